@@ -0,0 +1,177 @@
+// hsm-provider-server hosts the HSM crypto provider out of process and serves it over gRPC, so the
+// core does not need to be built with the toolchain and dependencies of the plugin.
+package main
+
+import (
+	"crypto/tls"
+	"errors"
+	"io"
+	"log"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/eclipse-xfsc/crypto-provider-luna-cloud-hsm-plugin/hsm"
+	"github.com/eclipse-xfsc/crypto-provider-luna-cloud-hsm-plugin/remote"
+	"github.com/eclipse-xfsc/crypto-provider-luna-cloud-hsm-plugin/rest"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/spf13/viper"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+)
+
+func main() {
+	viper.AutomaticEnv()
+	viper.SetDefault("HSM_KEY_EXPORT_FORMAT", string(hsm.DefaultKeyFormat))
+	viper.SetDefault("PROVIDER_LISTEN_ADDRESS", "unix:///run/hsm-provider/provider.sock")
+
+	listener, serverOptions, err := listen(viper.GetString("PROVIDER_LISTEN_ADDRESS"))
+	if err != nil {
+		log.Fatal(err)
+	}
+	faults, err := hsm.ParseFaults(viper.GetString("HSM_FAULTS"))
+	if err != nil {
+		log.Fatal(err)
+	}
+	logLevel, err := hsm.ParseLogLevel(viper.GetString("HSM_LOG_LEVEL"))
+	if err != nil {
+		log.Fatal(err)
+	}
+	slog.SetDefault(slog.New(slog.NewJSONHandler(os.Stderr, &slog.HandlerOptions{Level: logLevel})))
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+	var recording io.Writer
+	if path := viper.GetString("HSM_RECORD_FILE"); path != "" {
+		if recording, err = os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600); err != nil {
+			log.Fatal(err)
+		}
+	}
+	var metrics hsm.Metrics
+	metricsAddress := viper.GetString("METRICS_LISTEN_ADDRESS")
+	if metricsAddress != "" {
+		if metrics, err = hsm.NewPrometheusMetrics(prometheus.DefaultRegisterer, strings.Split(viper.GetString("HSM_METRICS_NAMESPACES"), ",")...); err != nil {
+			log.Fatal(err)
+		}
+	}
+	provider, err := hsm.New(hsm.Options{
+		Backend:    hsm.Backend(viper.GetString("HSM_BACKEND")),
+		Path:       viper.GetString("CRYPTO_EXECUTABLE_PATH"),
+		TokenLabel: viper.GetString("HSM_PARTITION_LABEL"),
+		Pin:        viper.GetString("HSM_PARTITION_PASSWORD"),
+		KeyFormat:  hsm.KeyFormat(viper.GetString("HSM_KEY_EXPORT_FORMAT")),
+		Faults:     faults,
+		Recording:  recording,
+		LogLevel:   logLevel,
+		Metrics:    metrics,
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if address := viper.GetString("KMIP_LISTEN_ADDRESS"); address != "" {
+		kmipListener, err := listenKMIP(address)
+		if err != nil {
+			log.Fatal(err)
+		}
+		log.Printf("serving KMIP on %s", kmipListener.Addr())
+		go func() {
+			log.Fatal(provider.NewKMIPServer(hsm.KMIPOptions{}).Serve(kmipListener))
+		}()
+	}
+
+	if address := viper.GetString("HTTP_LISTEN_ADDRESS"); address != "" {
+		httpServer, err := newHTTPServer(address, provider)
+		if err != nil {
+			log.Fatal(err)
+		}
+		log.Printf("serving HTTP on %s", address)
+		go func() {
+			log.Fatal(httpServer.ListenAndServeTLS("", ""))
+		}()
+	}
+
+	if metricsAddress != "" {
+		mux := http.NewServeMux()
+		mux.Handle("/metrics", promhttp.Handler())
+		metricsServer := &http.Server{Addr: metricsAddress, Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+		log.Printf("serving metrics on %s", metricsAddress)
+		go func() {
+			log.Fatal(metricsServer.ListenAndServe())
+		}()
+	}
+
+	server := grpc.NewServer(append(serverOptions, grpc.ChainUnaryInterceptor(remote.RecoveryInterceptor, remote.TraceContextInterceptor))...)
+	remote.NewServer(provider).Register(server)
+	go func() {
+		signals := make(chan os.Signal, 1)
+		signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+		<-signals
+		server.GracefulStop()
+	}()
+	log.Printf("serving crypto provider on %s", listener.Addr())
+	if err := server.Serve(listener); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// listenKMIP opens a TLS listener requiring client certificates, whose common names are the namespaces of the clients.
+func listenKMIP(address string) (net.Listener, error) {
+	config, err := remote.ServerTLSConfig(viper.GetString("PROVIDER_TLS_CERT"), viper.GetString("PROVIDER_TLS_KEY"), viper.GetString("PROVIDER_TLS_CLIENT_CA"))
+	if err != nil {
+		return nil, err
+	}
+	return tls.Listen("tcp", address, config)
+}
+
+// newHTTPServer serves the REST API with mTLS, clients may only use the namespaces configured for
+// their certificate in HTTP_ALLOWED_NAMESPACES.
+func newHTTPServer(address string, provider hsm.HSMCryptoProvider) (*http.Server, error) {
+	config, err := remote.ServerTLSConfig(viper.GetString("PROVIDER_TLS_CERT"), viper.GetString("PROVIDER_TLS_KEY"), viper.GetString("PROVIDER_TLS_CLIENT_CA"))
+	if err != nil {
+		return nil, err
+	}
+	allowed, err := rest.ParseAllowedNamespaces(viper.GetString("HTTP_ALLOWED_NAMESPACES"))
+	if err != nil {
+		return nil, err
+	}
+	if len(allowed) == 0 {
+		// without namespaces every request would be forbidden
+		return nil, errors.New("HTTP_ALLOWED_NAMESPACES must be set when HTTP_LISTEN_ADDRESS is set")
+	}
+	handler := rest.NewServer(provider, rest.Options{AllowedNamespaces: allowed})
+	return &http.Server{Addr: address, Handler: handler, TLSConfig: config, ReadHeaderTimeout: 10 * time.Second}, nil
+}
+
+// listen opens a unix socket for "unix://" addresses and a TCP socket with mTLS otherwise.
+func listen(address string) (net.Listener, []grpc.ServerOption, error) {
+	if path, ok := strings.CutPrefix(address, "unix://"); ok {
+		if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return nil, nil, err
+		}
+		listener, err := net.Listen("unix", path)
+		if err != nil {
+			return nil, nil, err
+		}
+		return listener, nil, os.Chmod(path, 0600)
+	}
+	certFile, keyFile, clientCAFile := viper.GetString("PROVIDER_TLS_CERT"), viper.GetString("PROVIDER_TLS_KEY"), viper.GetString("PROVIDER_TLS_CLIENT_CA")
+	if certFile == "" || keyFile == "" || clientCAFile == "" {
+		return nil, nil, errors.New("TCP listeners require PROVIDER_TLS_CERT, PROVIDER_TLS_KEY and PROVIDER_TLS_CLIENT_CA")
+	}
+	config, err := remote.ServerTLSConfig(certFile, keyFile, clientCAFile)
+	if err != nil {
+		return nil, nil, err
+	}
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		return nil, nil, err
+	}
+	return listener, []grpc.ServerOption{grpc.Creds(credentials.NewTLS(config))}, nil
+}
@@ -0,0 +1,279 @@
+// Package conformance tests implementations of types.CryptoProvider against the contract of the
+// interface, so every backend (mocks, the in-memory dev backend, SoftHSM2, Luna) is held to the same
+// behaviour.
+package conformance
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/sha512"
+	"errors"
+	"fmt"
+	"hash"
+	"io/fs"
+	"regexp"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/eclipse-xfsc/crypto-provider-core/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/sha3"
+)
+
+// Options adjusts the kit to a provider.
+type Options struct {
+	// Context is created before and destroyed after the tests, namespace "conformance" if empty.
+	Context types.CryptoContext
+	// KeyPrefix is prepended to the ids of all generated keys, "conformance-" if empty.
+	KeyPrefix string
+	// KnownFailures maps names of subtests, e.g. "SignVerify/rsa-2048", to the reason why the provider
+	// is known to fail them. These subtests are skipped with the reason.
+	KnownFailures map[string]string
+}
+
+var hashes = map[types.HashAlgorithm]func() hash.Hash{
+	types.Sha2224: sha256.New224,
+	types.Sha2256: sha256.New,
+	types.Sha2384: sha512.New384,
+	types.Sha2512: sha512.New,
+	types.Sha3224: sha3.New224,
+	types.Sha3256: sha3.New256,
+	types.Sha3384: sha3.New384,
+	types.Sha3512: sha3.New512,
+}
+
+// keyTypes are the key types of crypto-provider-core.
+var keyTypes = []types.KeyType{types.Aes256GCM, types.Ed25519, types.Ecdsap256, types.Ecdsap384, types.Ecdsap512, types.Rsa2048, types.Rsa3072, types.Rsa4096, types.KeyValue}
+
+type suite struct {
+	provider types.CryptoProvider
+	options  Options
+	root     string
+}
+
+// Run runs the conformance tests as subtests of t. Keys are generated for every advertised key type and
+// deleted again.
+func Run(t *testing.T, provider types.CryptoProvider, options Options) {
+	if options.Context.Namespace == "" {
+		options.Context.Namespace = "conformance"
+	}
+	if options.KeyPrefix == "" {
+		options.KeyPrefix = "conformance-"
+	}
+	s := suite{provider: provider, options: options, root: t.Name() + "/"}
+
+	s.run(t, "Context", s.testContext)
+	s.run(t, "Random", s.testRandom)
+	s.run(t, "Hash", s.testHash)
+	for _, keyType := range provider.GetSupportedKeysAlgs() {
+		s.run(t, "GenerateKey/"+string(keyType), func(t *testing.T) { s.testGenerateKey(t, keyType) })
+		if keyType == types.Aes256GCM {
+			s.run(t, "EncryptDecrypt/"+string(keyType), func(t *testing.T) { s.testEncryptDecrypt(t, keyType) })
+		} else {
+			s.run(t, "SignVerify/"+string(keyType), func(t *testing.T) { s.testSignVerify(t, keyType) })
+		}
+		s.run(t, "Rotate/"+string(keyType), func(t *testing.T) { s.testRotate(t, keyType) })
+		s.run(t, "Delete/"+string(keyType), func(t *testing.T) { s.testDelete(t, keyType) })
+	}
+	s.run(t, "GetKeys", s.testGetKeys)
+	s.run(t, "UnknownKey", s.testUnknownKey)
+	s.run(t, "UnsupportedKeyTypes", s.testUnsupportedKeyTypes)
+}
+
+// run runs a subtest unless it is a known failure and turns panics of the provider into failures.
+func (s suite) run(t *testing.T, name string, test func(t *testing.T)) {
+	t.Run(name, func(t *testing.T) {
+		if reason, ok := s.options.KnownFailures[strings.TrimPrefix(t.Name(), s.root)]; ok {
+			t.Skip("known failure: " + reason)
+		}
+		defer func() {
+			if r := recover(); r != nil {
+				t.Fatalf("provider panicked: %v", r)
+			}
+		}()
+		test(t)
+	})
+}
+
+func (s suite) identifier(name string) types.CryptoIdentifier {
+	return types.CryptoIdentifier{KeyId: s.options.KeyPrefix + name, CryptoContext: s.options.Context}
+}
+
+// generate creates a key of the type, which is deleted at the end of the test.
+func (s suite) generate(t *testing.T, name string, keyType types.KeyType) types.CryptoIdentifier {
+	identifier := s.identifier(name)
+	require.Nil(t, s.provider.GenerateKey(types.CryptoKeyParameter{Identifier: identifier, KeyType: keyType}), "GenerateKey %s", keyType)
+	t.Cleanup(func() {
+		defer func() { _ = recover() }()
+		_ = s.provider.DeleteKey(identifier)
+	})
+	return identifier
+}
+
+func (s suite) testContext(t *testing.T) {
+	require.Nil(t, s.provider.CreateCryptoContext(s.options.Context))
+	t.Cleanup(func() { assert.Nil(t, s.provider.DestroyCryptoContext(s.options.Context)) })
+	existing, err := s.provider.IsCryptoContextExisting(s.options.Context)
+	assert.Nil(t, err)
+	assert.True(t, existing, "context does not exist after CreateCryptoContext")
+	namespaces, err := s.provider.GetNamespaces(s.options.Context)
+	assert.Nil(t, err)
+	assert.NotEmpty(t, namespaces)
+}
+
+func (s suite) testRandom(t *testing.T) {
+	for _, n := range []int{1, 32, 1024} {
+		random, err := s.provider.GenerateRandom(s.options.Context, n)
+		require.Nil(t, err)
+		assert.Len(t, random, n)
+	}
+	first, _ := s.provider.GenerateRandom(s.options.Context, 32)
+	second, _ := s.provider.GenerateRandom(s.options.Context, 32)
+	assert.NotEqual(t, first, second, "two random values are equal")
+}
+
+func (s suite) testHash(t *testing.T) {
+	supported := s.provider.GetSupportedHashAlgs()
+	assert.NotEmpty(t, supported)
+	message := []byte("conformance")
+	for algorithm, newHash := range hashes {
+		parameter := types.CryptoHashParameter{Identifier: types.CryptoIdentifier{CryptoContext: s.options.Context}, HashAlgorithm: algorithm}
+		actual, err := s.provider.Hash(parameter, message)
+		if !slices.Contains(supported, algorithm) {
+			assert.ErrorIs(t, err, errors.ErrUnsupported, "unsupported hash algorithm %s", algorithm)
+			continue
+		}
+		expected := newHash()
+		expected.Write(message)
+		assert.Nil(t, err, algorithm)
+		assert.Equal(t, expected.Sum(nil), actual, algorithm)
+	}
+}
+
+func (s suite) testGenerateKey(t *testing.T, keyType types.KeyType) {
+	identifier := s.generate(t, "generate-"+string(keyType), keyType)
+	existing, err := s.provider.IsKeyExisting(identifier)
+	assert.Nil(t, err)
+	assert.True(t, existing, "generated key does not exist")
+	if keyType == types.Aes256GCM {
+		return
+	}
+	key, err := s.provider.GetKey(identifier)
+	require.Nil(t, err)
+	assert.Equal(t, keyType, key.KeyType)
+	assert.NotEmpty(t, key.Key)
+}
+
+func (s suite) testSignVerify(t *testing.T, keyType types.KeyType) {
+	identifier := s.generate(t, "sign-"+string(keyType), keyType)
+	data := []byte("conformance data which is longer than any digest of the supported hash algorithms")
+	signature, err := s.provider.Sign(identifier, data)
+	require.Nil(t, err)
+	assert.NotEmpty(t, signature)
+	valid, err := s.provider.Verify(identifier, data, signature)
+	assert.Nil(t, err)
+	assert.True(t, valid, "signature does not verify")
+	valid, err = s.provider.Verify(identifier, append([]byte("x"), data...), signature)
+	assert.Nil(t, err, "an invalid signature is no error")
+	assert.False(t, valid, "signature verifies other data")
+}
+
+func (s suite) testEncryptDecrypt(t *testing.T, keyType types.KeyType) {
+	identifier := s.generate(t, "encrypt-"+string(keyType), keyType)
+	for _, plaintext := range [][]byte{[]byte("conformance"), bytes.Repeat([]byte{0x42}, 1000)} {
+		ciphertext, err := s.provider.Encrypt(identifier, plaintext)
+		require.Nil(t, err)
+		require.NotEmpty(t, ciphertext, "Encrypt returned no ciphertext")
+		assert.False(t, bytes.Contains(ciphertext, plaintext), "ciphertext contains the plaintext")
+		decrypted, err := s.provider.Decrypt(identifier, ciphertext)
+		require.Nil(t, err)
+		assert.Equal(t, plaintext, decrypted)
+	}
+}
+
+func (s suite) testRotate(t *testing.T, keyType types.KeyType) {
+	identifier := s.generate(t, "rotate-"+string(keyType), keyType)
+	err := s.provider.RotateKey(identifier)
+	if errors.Is(err, errors.ErrUnsupported) {
+		return
+	}
+	require.Nil(t, err, "RotateKey must succeed or return errors.ErrUnsupported")
+	existing, err := s.provider.IsKeyExisting(identifier)
+	assert.Nil(t, err)
+	assert.True(t, existing, "rotated key does not exist")
+}
+
+func (s suite) testDelete(t *testing.T, keyType types.KeyType) {
+	identifier := s.generate(t, "delete-"+string(keyType), keyType)
+	require.Nil(t, s.provider.DeleteKey(identifier))
+	existing, err := s.provider.IsKeyExisting(identifier)
+	assert.Nil(t, err)
+	assert.False(t, existing, "deleted key still exists")
+	_, err = s.provider.GetKey(identifier)
+	assert.ErrorIs(t, err, fs.ErrNotExist, "GetKey of deleted key")
+	assert.ErrorIs(t, s.provider.DeleteKey(identifier), fs.ErrNotExist, "deleting a deleted key")
+}
+
+func (s suite) testGetKeys(t *testing.T) {
+	var expected []string
+	for _, keyType := range s.provider.GetSupportedKeysAlgs() {
+		if keyType == types.Aes256GCM {
+			continue
+		}
+		// failing key types are reported by GenerateKey
+		identifier := s.identifier(fmt.Sprintf("list-%s", keyType))
+		if s.provider.GenerateKey(types.CryptoKeyParameter{Identifier: identifier, KeyType: keyType}) == nil {
+			t.Cleanup(func() { _ = s.provider.DeleteKey(identifier) })
+			expected = append(expected, identifier.KeyId)
+		}
+	}
+	filter := regexp.MustCompile("^" + regexp.QuoteMeta(s.options.KeyPrefix+"list-"))
+	keys, err := s.provider.GetKeys(types.CryptoFilter{Filter: *filter, CryptoContext: s.options.Context})
+	require.Nil(t, err)
+	var actual []string
+	for _, key := range keys.Keys {
+		actual = append(actual, key.Identifier.KeyId)
+	}
+	assert.ElementsMatch(t, expected, actual)
+
+	filter = regexp.MustCompile("^" + regexp.QuoteMeta(s.options.KeyPrefix+"none-"))
+	keys, err = s.provider.GetKeys(types.CryptoFilter{Filter: *filter, CryptoContext: s.options.Context})
+	require.Nil(t, err)
+	assert.Empty(t, keys.Keys)
+}
+
+func (s suite) testUnknownKey(t *testing.T) {
+	identifier := s.identifier("unknown")
+	existing, err := s.provider.IsKeyExisting(identifier)
+	assert.Nil(t, err)
+	assert.False(t, existing, "unknown key exists")
+	_, err = s.provider.GetKey(identifier)
+	assert.ErrorIs(t, err, fs.ErrNotExist, "GetKey")
+	_, err = s.provider.Sign(identifier, []byte("data"))
+	assert.ErrorIs(t, err, fs.ErrNotExist, "Sign")
+	_, err = s.provider.Verify(identifier, []byte("data"), []byte("signature"))
+	assert.ErrorIs(t, err, fs.ErrNotExist, "Verify")
+	_, err = s.provider.Encrypt(identifier, []byte("data"))
+	assert.ErrorIs(t, err, fs.ErrNotExist, "Encrypt")
+	_, err = s.provider.Decrypt(identifier, []byte("data"))
+	assert.ErrorIs(t, err, fs.ErrNotExist, "Decrypt")
+	assert.ErrorIs(t, s.provider.DeleteKey(identifier), fs.ErrNotExist, "DeleteKey")
+}
+
+// testUnsupportedKeyTypes generates keys of the types which are not advertised.
+func (s suite) testUnsupportedKeyTypes(t *testing.T) {
+	supported := s.provider.GetSupportedKeysAlgs()
+	for _, keyType := range keyTypes {
+		if slices.Contains(supported, keyType) {
+			continue
+		}
+		identifier := s.identifier("unsupported-" + string(keyType))
+		err := s.provider.GenerateKey(types.CryptoKeyParameter{Identifier: identifier, KeyType: keyType})
+		if err == nil {
+			_ = s.provider.DeleteKey(identifier)
+		}
+		assert.ErrorIs(t, err, errors.ErrUnsupported, "GenerateKey %s", keyType)
+	}
+}
@@ -0,0 +1,177 @@
+package hsm
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"fmt"
+	"math/big"
+	"math/bits"
+	"net"
+	"net/url"
+	"time"
+
+	"github.com/eclipse-xfsc/crypto-provider-core/types"
+)
+
+// CertificateRequestOptions describes the subject and extensions of a certificate signing
+// request or self-signed certificate.
+type CertificateRequestOptions struct {
+	Subject        pkix.Name
+	DNSNames       []string
+	EmailAddresses []string
+	IPAddresses    []net.IP
+	URIs           []*url.URL
+	KeyUsage       x509.KeyUsage
+	ExtKeyUsage    []x509.ExtKeyUsage
+	// IsCA marks a self-signed certificate as CA certificate.
+	IsCA bool
+	// Validity of a self-signed certificate, one year if not set.
+	Validity time.Duration
+}
+
+func signatureAlgorithmFor(pub crypto.PublicKey) (x509.SignatureAlgorithm, error) {
+	switch pubKey := pub.(type) {
+	case *ecdsa.PublicKey:
+		switch pubKey.Curve.Params().BitSize {
+		case 384:
+			return x509.ECDSAWithSHA384, nil
+		case 521:
+			return x509.ECDSAWithSHA512, nil
+		}
+		return x509.ECDSAWithSHA256, nil
+	case *rsa.PublicKey:
+		return x509.SHA256WithRSAPSS, nil
+	default:
+		return x509.UnknownSignatureAlgorithm, fmt.Errorf("keys of type %T can not sign certificates", pub)
+	}
+}
+
+// CreateCertificateRequest builds a PKCS#10 certificate signing request signed with the HSM key,
+// which proves the possession of the private key.
+func (p HSMCryptoProvider) CreateCertificateRequest(parameter types.CryptoIdentifier, options CertificateRequestOptions) (_ []byte, err error) {
+	p, op := p.observe("CreateCertificateRequest", parameter.CryptoContext, parameter.KeyId)
+	defer op.end(&err)
+	signer, err := p.getSigner(parameter)
+	if err != nil {
+		return nil, err
+	}
+	if signer == nil {
+		return nil, keyNotFound(parameter.KeyId)
+	}
+	alg, err := signatureAlgorithmFor(signer.Public())
+	if err != nil {
+		return nil, err
+	}
+	extensions, err := options.extensions()
+	if err != nil {
+		return nil, err
+	}
+	template := &x509.CertificateRequest{
+		Subject:            options.Subject,
+		DNSNames:           options.DNSNames,
+		EmailAddresses:     options.EmailAddresses,
+		IPAddresses:        options.IPAddresses,
+		URIs:               options.URIs,
+		SignatureAlgorithm: alg,
+		ExtraExtensions:    extensions,
+	}
+	return x509.CreateCertificateRequest(p.controller.rand, template, signer)
+}
+
+// CreateSelfSignedCertificate issues a self-signed certificate for the HSM key.
+func (p HSMCryptoProvider) CreateSelfSignedCertificate(parameter types.CryptoIdentifier, options CertificateRequestOptions) (_ []byte, err error) {
+	p, op := p.observe("CreateSelfSignedCertificate", parameter.CryptoContext, parameter.KeyId)
+	defer op.end(&err)
+	signer, err := p.getSigner(parameter)
+	if err != nil {
+		return nil, err
+	}
+	if signer == nil {
+		return nil, keyNotFound(parameter.KeyId)
+	}
+	alg, err := signatureAlgorithmFor(signer.Public())
+	if err != nil {
+		return nil, err
+	}
+	serial, err := rand.Int(p.controller.rand, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return nil, err
+	}
+	validity := options.Validity
+	if validity == 0 {
+		validity = 365 * 24 * time.Hour
+	}
+	now := time.Now()
+	template := &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               options.Subject,
+		NotBefore:             now.Add(-5 * time.Minute),
+		NotAfter:              now.Add(validity),
+		DNSNames:              options.DNSNames,
+		EmailAddresses:        options.EmailAddresses,
+		IPAddresses:           options.IPAddresses,
+		URIs:                  options.URIs,
+		KeyUsage:              options.KeyUsage,
+		ExtKeyUsage:           options.ExtKeyUsage,
+		IsCA:                  options.IsCA,
+		BasicConstraintsValid: true,
+		SignatureAlgorithm:    alg,
+	}
+	if options.IsCA {
+		template.KeyUsage |= x509.KeyUsageCertSign | x509.KeyUsageCRLSign
+	}
+	return x509.CreateCertificate(p.controller.rand, template, template, signer.Public(), signer)
+}
+
+var (
+	oidExtensionKeyUsage    = asn1.ObjectIdentifier{2, 5, 29, 15}
+	oidExtensionExtKeyUsage = asn1.ObjectIdentifier{2, 5, 29, 37}
+
+	extKeyUsageOIDs = map[x509.ExtKeyUsage]asn1.ObjectIdentifier{
+		x509.ExtKeyUsageAny:             {2, 5, 29, 37, 0},
+		x509.ExtKeyUsageServerAuth:      {1, 3, 6, 1, 5, 5, 7, 3, 1},
+		x509.ExtKeyUsageClientAuth:      {1, 3, 6, 1, 5, 5, 7, 3, 2},
+		x509.ExtKeyUsageCodeSigning:     {1, 3, 6, 1, 5, 5, 7, 3, 3},
+		x509.ExtKeyUsageEmailProtection: {1, 3, 6, 1, 5, 5, 7, 3, 4},
+		x509.ExtKeyUsageTimeStamping:    {1, 3, 6, 1, 5, 5, 7, 3, 8},
+		x509.ExtKeyUsageOCSPSigning:     {1, 3, 6, 1, 5, 5, 7, 3, 9},
+	}
+)
+
+// extensions encodes the key usages as requested extensions, x509.CertificateRequest has no fields for them.
+func (o CertificateRequestOptions) extensions() ([]pkix.Extension, error) {
+	var extensions []pkix.Extension
+	if o.KeyUsage != 0 {
+		usage := []byte{bits.Reverse8(byte(o.KeyUsage)), bits.Reverse8(byte(o.KeyUsage >> 8))}
+		if usage[1] == 0 {
+			usage = usage[:1]
+		}
+		last := usage[len(usage)-1]
+		value, err := asn1.Marshal(asn1.BitString{Bytes: usage, BitLength: len(usage)*8 - bits.TrailingZeros8(last)})
+		if err != nil {
+			return nil, err
+		}
+		extensions = append(extensions, pkix.Extension{Id: oidExtensionKeyUsage, Critical: true, Value: value})
+	}
+	if len(o.ExtKeyUsage) > 0 {
+		oids := make([]asn1.ObjectIdentifier, 0, len(o.ExtKeyUsage))
+		for _, usage := range o.ExtKeyUsage {
+			oid, ok := extKeyUsageOIDs[usage]
+			if !ok {
+				return nil, fmt.Errorf("unsupported extended key usage %d", usage)
+			}
+			oids = append(oids, oid)
+		}
+		value, err := asn1.Marshal(oids)
+		if err != nil {
+			return nil, err
+		}
+		extensions = append(extensions, pkix.Extension{Id: oidExtensionExtKeyUsage, Value: value})
+	}
+	return extensions, nil
+}
@@ -0,0 +1,335 @@
+package hsm
+
+import (
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/ThalesIgnite/crypto11"
+	"github.com/eclipse-xfsc/crypto-provider-core/types"
+)
+
+// CertificateProfile describes the certificates a CA issues for a use case.
+type CertificateProfile struct {
+	// Validity of the certificates, it must be positive and is cut at the expiry of the CA certificate.
+	Validity    time.Duration
+	KeyUsage    x509.KeyUsage
+	ExtKeyUsage []x509.ExtKeyUsage
+	// IsCA issues intermediate CA certificates, MaxPathLen -1 leaves the path length unconstrained.
+	IsCA       bool
+	MaxPathLen int
+	// Name constraints of intermediate CA certificates.
+	PermittedDNSDomains []string
+	ExcludedDNSDomains  []string
+	PermittedIPRanges   []*net.IPNet
+	ExcludedIPRanges    []*net.IPNet
+}
+
+// CertificateAuthorityOptions configures an embedded CA.
+type CertificateAuthorityOptions struct {
+	// Profiles by name, used by IssueCertificate.
+	Profiles map[string]CertificateProfile
+	// IssuanceLog is the file the issued certificates are recorded in. The log is kept in memory only if empty.
+	IssuanceLog string
+	// CRLValidity is the time until the next update of a CRL, one week if not set.
+	CRLValidity time.Duration
+}
+
+// IssuedCertificate is an entry of the issuance log.
+type IssuedCertificate struct {
+	SerialNumber string     `json:"serialNumber"`
+	Subject      string     `json:"subject"`
+	Profile      string     `json:"profile"`
+	NotBefore    time.Time  `json:"notBefore"`
+	NotAfter     time.Time  `json:"notAfter"`
+	RevokedAt    *time.Time `json:"revokedAt,omitempty"`
+	ReasonCode   int        `json:"reasonCode,omitempty"`
+}
+
+type issuanceLog struct {
+	path         string
+	Certificates []IssuedCertificate `json:"certificates"`
+	CRLNumber    int64               `json:"crlNumber"`
+}
+
+// CertificateAuthority issues certificates and CRLs signed with a CA key on the HSM. The CA
+// certificate is the leaf of the certificate chain stored with the key.
+type CertificateAuthority struct {
+	provider    HSMCryptoProvider
+	key         types.CryptoIdentifier
+	signer      crypto11.Signer
+	certificate *x509.Certificate
+	profiles    map[string]CertificateProfile
+	crlValidity time.Duration
+
+	mu  sync.Mutex
+	log *issuanceLog
+}
+
+// NewCertificateAuthority designates the HSM key as CA signing key.
+func (p HSMCryptoProvider) NewCertificateAuthority(key types.CryptoIdentifier, options CertificateAuthorityOptions) (_ *CertificateAuthority, err error) {
+	_, op := p.observe("NewCertificateAuthority", key.CryptoContext, key.KeyId)
+	defer op.end(&err)
+	for name, profile := range options.Profiles {
+		if profile.Validity <= 0 {
+			return nil, fmt.Errorf("certificate profile %s has no validity", name)
+		}
+	}
+	signer, err := p.getSigner(key)
+	if err != nil {
+		return nil, err
+	}
+	if signer == nil {
+		return nil, keyNotFound(key.KeyId)
+	}
+	chain, err := p.certificateChain(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(chain) == 0 {
+		return nil, fmt.Errorf("no CA certificate stored for key %s", key.KeyId)
+	}
+	if !chain[0].IsCA {
+		return nil, fmt.Errorf("certificate of key %s is no CA certificate", key.KeyId)
+	}
+	log, err := loadIssuanceLog(options.IssuanceLog)
+	if err != nil {
+		return nil, err
+	}
+	crlValidity := options.CRLValidity
+	if crlValidity == 0 {
+		crlValidity = 7 * 24 * time.Hour
+	}
+	return &CertificateAuthority{
+		provider:    p,
+		key:         key,
+		signer:      signer,
+		certificate: chain[0],
+		profiles:    options.Profiles,
+		crlValidity: crlValidity,
+		log:         log,
+	}, nil
+}
+
+// Certificate returns the CA certificate.
+func (ca *CertificateAuthority) Certificate() *x509.Certificate {
+	return ca.certificate
+}
+
+// IssueCertificate issues a certificate for the PKCS#10 request with the named profile.
+// Subject and subject alternative names are taken from the request.
+func (ca *CertificateAuthority) IssueCertificate(csr []byte, profileName string) (_ *x509.Certificate, err error) {
+	_, op := ca.provider.observe("CertificateAuthority.IssueCertificate", ca.key.CryptoContext, ca.key.KeyId)
+	defer op.end(&err)
+	profile, ok := ca.profiles[profileName]
+	if !ok {
+		return nil, fmt.Errorf("unknown certificate profile %s", profileName)
+	}
+	request, err := x509.ParseCertificateRequest(csr)
+	if err != nil {
+		return nil, err
+	}
+	if err := request.CheckSignature(); err != nil {
+		return nil, fmt.Errorf("invalid proof of possession: %w", err)
+	}
+
+	ca.mu.Lock()
+	defer ca.mu.Unlock()
+	serial, err := ca.newSerialNumber()
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	notAfter := now.Add(profile.Validity)
+	if notAfter.After(ca.certificate.NotAfter) {
+		notAfter = ca.certificate.NotAfter
+	}
+	template := &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               request.Subject,
+		NotBefore:             now.Add(-5 * time.Minute),
+		NotAfter:              notAfter,
+		DNSNames:              request.DNSNames,
+		EmailAddresses:        request.EmailAddresses,
+		IPAddresses:           request.IPAddresses,
+		URIs:                  request.URIs,
+		KeyUsage:              profile.KeyUsage,
+		ExtKeyUsage:           profile.ExtKeyUsage,
+		BasicConstraintsValid: true,
+		IsCA:                  profile.IsCA,
+	}
+	if profile.IsCA {
+		template.KeyUsage |= x509.KeyUsageCertSign | x509.KeyUsageCRLSign
+		template.MaxPathLen = profile.MaxPathLen
+		template.MaxPathLenZero = profile.MaxPathLen == 0
+		template.PermittedDNSDomains = profile.PermittedDNSDomains
+		template.ExcludedDNSDomains = profile.ExcludedDNSDomains
+		template.PermittedIPRanges = profile.PermittedIPRanges
+		template.ExcludedIPRanges = profile.ExcludedIPRanges
+	}
+	if template.SignatureAlgorithm, err = signatureAlgorithmFor(ca.signer.Public()); err != nil {
+		return nil, err
+	}
+	der, err := x509.CreateCertificate(ca.provider.controller.rand, template, ca.certificate, request.PublicKey, ca.signer)
+	if err != nil {
+		return nil, err
+	}
+	certificate, err := x509.ParseCertificate(der)
+	if err != nil {
+		return nil, err
+	}
+	ca.log.Certificates = append(ca.log.Certificates, IssuedCertificate{
+		SerialNumber: serial.Text(16),
+		Subject:      certificate.Subject.String(),
+		Profile:      profileName,
+		NotBefore:    certificate.NotBefore,
+		NotAfter:     certificate.NotAfter,
+	})
+	if err := ca.log.save(); err != nil {
+		// the certificate is not handed out, so it is not kept in the log either
+		ca.log.Certificates = ca.log.Certificates[:len(ca.log.Certificates)-1]
+		return nil, err
+	}
+	return certificate, nil
+}
+
+// Revoke marks the certificate with the serial number as revoked, it is listed in the next CRL.
+func (ca *CertificateAuthority) Revoke(serial *big.Int, reasonCode int) error {
+	ca.mu.Lock()
+	defer ca.mu.Unlock()
+	for i, issued := range ca.log.Certificates {
+		if issued.SerialNumber == serial.Text(16) {
+			if issued.RevokedAt != nil {
+				return fmt.Errorf("certificate %s is already revoked", issued.SerialNumber)
+			}
+			now := time.Now()
+			ca.log.Certificates[i].RevokedAt = &now
+			ca.log.Certificates[i].ReasonCode = reasonCode
+			return ca.log.save()
+		}
+	}
+	return fmt.Errorf("certificate %s was not issued by this CA", serial.Text(16))
+}
+
+// IssuedCertificates returns the issuance log.
+func (ca *CertificateAuthority) IssuedCertificates() []IssuedCertificate {
+	ca.mu.Lock()
+	defer ca.mu.Unlock()
+	return append([]IssuedCertificate(nil), ca.log.Certificates...)
+}
+
+// CreateCRL creates a DER encoded CRL of all revoked certificates which are not expired yet.
+func (ca *CertificateAuthority) CreateCRL() (_ []byte, err error) {
+	_, op := ca.provider.observe("CertificateAuthority.CreateCRL", ca.key.CryptoContext, ca.key.KeyId)
+	defer op.end(&err)
+	ca.mu.Lock()
+	defer ca.mu.Unlock()
+	now := time.Now()
+	var revoked []x509.RevocationListEntry
+	for _, issued := range ca.log.Certificates {
+		if issued.RevokedAt == nil || issued.NotAfter.Before(now) {
+			continue
+		}
+		serial, ok := new(big.Int).SetString(issued.SerialNumber, 16)
+		if !ok {
+			return nil, fmt.Errorf("invalid serial number %s in issuance log", issued.SerialNumber)
+		}
+		revoked = append(revoked, x509.RevocationListEntry{
+			SerialNumber:   serial,
+			RevocationTime: *issued.RevokedAt,
+			ReasonCode:     issued.ReasonCode,
+		})
+	}
+	ca.log.CRLNumber++
+	template := &x509.RevocationList{
+		Number:                    big.NewInt(ca.log.CRLNumber),
+		ThisUpdate:                now,
+		NextUpdate:                now.Add(ca.crlValidity),
+		RevokedCertificateEntries: revoked,
+	}
+	if template.SignatureAlgorithm, err = signatureAlgorithmFor(ca.signer.Public()); err != nil {
+		return nil, err
+	}
+	crl, err := x509.CreateRevocationList(ca.provider.controller.rand, template, ca.certificate, ca.signer)
+	if err != nil {
+		return nil, err
+	}
+	if err := ca.log.save(); err != nil {
+		return nil, err
+	}
+	return crl, nil
+}
+
+// newSerialNumber returns a random serial number which was not issued before.
+func (ca *CertificateAuthority) newSerialNumber() (*big.Int, error) {
+	limit := new(big.Int).Lsh(big.NewInt(1), 159)
+	for {
+		serial, err := rand.Int(ca.provider.controller.rand, limit)
+		if err != nil {
+			return nil, err
+		}
+		if serial.Sign() == 0 || ca.log.contains(serial.Text(16)) || serial.Cmp(ca.certificate.SerialNumber) == 0 {
+			continue
+		}
+		return serial, nil
+	}
+}
+
+func loadIssuanceLog(path string) (*issuanceLog, error) {
+	log := &issuanceLog{path: path}
+	if path == "" {
+		return log, nil
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return log, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, log); err != nil {
+		return nil, fmt.Errorf("invalid issuance log %s: %w", path, err)
+	}
+	return log, nil
+}
+
+func (l *issuanceLog) contains(serial string) bool {
+	for _, issued := range l.Certificates {
+		if issued.SerialNumber == serial {
+			return true
+		}
+	}
+	return false
+}
+
+// save writes the log atomically, so a crash never leaves a partial log behind.
+func (l *issuanceLog) save() error {
+	if l.path == "" {
+		return nil
+	}
+	data, err := json.MarshalIndent(l, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(l.path), filepath.Base(l.path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), l.path)
+}
@@ -0,0 +1,106 @@
+package hsm
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/eclipse-xfsc/crypto-provider-core/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func getTestCertificateAuthority(t *testing.T, logPath string) (*CertificateAuthority, *ContextTypeMock) {
+	key, _ := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	var mockApi = new(ContextTypeMock)
+	mockApi.On("FindKeyPair", []byte(testId), []byte(nil)).Return(&SoftSignerMock{key}, nil)
+	provider := getTestHSMCryptoProvider(mockApi)
+	der, err := provider.CreateSelfSignedCertificate(types.CryptoIdentifier{KeyId: testId}, CertificateRequestOptions{Subject: pkix.Name{CommonName: "root"}, IsCA: true})
+	assert.Nil(t, err)
+	root, _ := x509.ParseCertificate(der)
+	mockApi.On("FindCertificate", []byte(testId), certificateLabel(testId, 0), (*big.Int)(nil)).Return(root, nil)
+	mockApi.WithoutCertificates()
+
+	ca, err := provider.NewCertificateAuthority(types.CryptoIdentifier{KeyId: testId}, CertificateAuthorityOptions{
+		Profiles: map[string]CertificateProfile{
+			"tls":          {Validity: 24 * time.Hour, KeyUsage: x509.KeyUsageDigitalSignature, ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth}},
+			"intermediate": {Validity: 48 * time.Hour, IsCA: true, MaxPathLen: 0, PermittedDNSDomains: []string{"example.com"}},
+		},
+		IssuanceLog: logPath,
+	})
+	assert.Nil(t, err)
+	return ca, mockApi
+}
+
+func testRequest(t *testing.T, dnsName string) []byte {
+	key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	csr, err := x509.CreateCertificateRequest(rand.Reader, &x509.CertificateRequest{Subject: pkix.Name{CommonName: dnsName}, DNSNames: []string{dnsName}}, key)
+	assert.Nil(t, err)
+	return csr
+}
+
+func TestCertificateAuthority_IssueCertificate(t *testing.T) {
+	logPath := filepath.Join(t.TempDir(), "issued.json")
+	ca, _ := getTestCertificateAuthority(t, logPath)
+
+	intermediate, err := ca.IssueCertificate(testRequest(t, "ca.example.com"), "intermediate")
+	assert.Nil(t, err)
+	assert.True(t, intermediate.IsCA)
+	assert.True(t, intermediate.MaxPathLenZero)
+	assert.Equal(t, []string{"example.com"}, intermediate.PermittedDNSDomains)
+
+	leaf, err := ca.IssueCertificate(testRequest(t, "www.example.com"), "tls")
+	assert.Nil(t, err)
+	roots := x509.NewCertPool()
+	roots.AddCert(ca.Certificate())
+	_, err = leaf.Verify(x509.VerifyOptions{Roots: roots, DNSName: "www.example.com"})
+	assert.Nil(t, err)
+
+	_, err = ca.IssueCertificate(testRequest(t, "www.example.com"), "unknown")
+	assert.NotNil(t, err)
+
+	reloaded, err := loadIssuanceLog(logPath)
+	assert.Nil(t, err)
+	assert.Len(t, reloaded.Certificates, 2)
+	assert.Equal(t, leaf.SerialNumber.Text(16), reloaded.Certificates[1].SerialNumber)
+}
+
+func TestCertificateAuthority_IssueCertificate_SaveFailed(t *testing.T) {
+	ca, _ := getTestCertificateAuthority(t, filepath.Join(t.TempDir(), "missing", "issued.json"))
+
+	certificate, err := ca.IssueCertificate(testRequest(t, "www.example.com"), "tls")
+	assert.NotNil(t, err)
+	assert.Nil(t, certificate)
+	assert.Empty(t, ca.IssuedCertificates())
+}
+
+func TestNewCertificateAuthority_NoValidity(t *testing.T) {
+	ca, _ := getTestCertificateAuthority(t, "")
+
+	_, err := ca.provider.NewCertificateAuthority(types.CryptoIdentifier{KeyId: testId}, CertificateAuthorityOptions{
+		Profiles: map[string]CertificateProfile{"tls": {KeyUsage: x509.KeyUsageDigitalSignature}},
+	})
+	assert.EqualError(t, err, "certificate profile tls has no validity")
+}
+
+func TestCertificateAuthority_CreateCRL(t *testing.T) {
+	ca, _ := getTestCertificateAuthority(t, "")
+	leaf, err := ca.IssueCertificate(testRequest(t, "www.example.com"), "tls")
+	assert.Nil(t, err)
+	assert.Nil(t, ca.Revoke(leaf.SerialNumber, 1))
+	assert.NotNil(t, ca.Revoke(leaf.SerialNumber, 1))
+
+	der, err := ca.CreateCRL()
+	assert.Nil(t, err)
+	crl, err := x509.ParseRevocationList(der)
+	assert.Nil(t, err)
+	assert.Nil(t, crl.CheckSignatureFrom(ca.Certificate()))
+	assert.Equal(t, big.NewInt(1), crl.Number)
+	assert.Len(t, crl.RevokedCertificateEntries, 1)
+	assert.Equal(t, leaf.SerialNumber, crl.RevokedCertificateEntries[0].SerialNumber)
+}
@@ -0,0 +1,116 @@
+package hsm
+
+import (
+	"crypto"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+
+	"github.com/eclipse-xfsc/crypto-provider-core/types"
+	"github.com/lestrrat-go/jwx/v2/cert"
+	"github.com/lestrrat-go/jwx/v2/jwk"
+)
+
+// certificateLabel is the CKA_LABEL of the certificate at position index of the chain of a key.
+// All certificates of the chain share the CKA_ID of the key.
+func certificateLabel(keyId string, index int) []byte {
+	return []byte(fmt.Sprintf("%s/certificate/%d", keyId, index))
+}
+
+// ImportCertificateChain stores the certificate chain, leaf first, next to the key pair on the partition.
+// An existing chain of the key is replaced.
+func (p HSMCryptoProvider) ImportCertificateChain(parameter types.CryptoIdentifier, chain []*x509.Certificate) (err error) {
+	p, op := p.observe("ImportCertificateChain", parameter.CryptoContext, parameter.KeyId)
+	defer op.end(&err)
+	if len(chain) == 0 {
+		return errors.New("certificate chain is empty")
+	}
+	pub, err := p.publicKey(parameter)
+	if err != nil {
+		return err
+	}
+	leaf, ok := chain[0].PublicKey.(interface{ Equal(crypto.PublicKey) bool })
+	if !ok || !leaf.Equal(pub) {
+		return fmt.Errorf("certificate does not belong to key %s", parameter.KeyId)
+	}
+	if err := p.deleteCertificateChain(parameter); err != nil {
+		return err
+	}
+	id := []byte(parameter.KeyId)
+	for i, certificate := range chain {
+		if err := p.controller.api.ImportCertificateWithLabel(id, certificateLabel(parameter.KeyId, i), certificate); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetCertificateChain returns the certificate chain stored for the key, leaf first, or nil if there is none.
+func (p HSMCryptoProvider) GetCertificateChain(parameter types.CryptoIdentifier) (chain []*x509.Certificate, err error) {
+	p, op := p.observe("GetCertificateChain", parameter.CryptoContext, parameter.KeyId)
+	defer op.end(&err)
+	return p.certificateChain(parameter)
+}
+
+func (p HSMCryptoProvider) certificateChain(parameter types.CryptoIdentifier) ([]*x509.Certificate, error) {
+	id := []byte(parameter.KeyId)
+	var chain []*x509.Certificate
+	for i := 0; ; i++ {
+		certificate, err := p.controller.api.FindCertificate(id, certificateLabel(parameter.KeyId, i), nil)
+		if err != nil {
+			return nil, err
+		}
+		if certificate == nil {
+			return chain, nil
+		}
+		chain = append(chain, certificate)
+	}
+}
+
+func (p HSMCryptoProvider) deleteCertificateChain(parameter types.CryptoIdentifier) error {
+	chain, err := p.certificateChain(parameter)
+	if err != nil {
+		return err
+	}
+	id := []byte(parameter.KeyId)
+	for i := range chain {
+		if err := p.controller.api.DeleteCertificate(id, certificateLabel(parameter.KeyId, i), nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// attachCertificateChain adds the chain to an encoded public key. PEM keys are prefixed with the
+// certificates, leaf first, as CryptoKey.GetJwk of crypto-provider-core expects. JWKs get a x5c member,
+// other formats stay unchanged.
+func attachCertificateChain(key []byte, chain []*x509.Certificate, format KeyFormat) ([]byte, error) {
+	switch format {
+	case PEM, "":
+		var certificates []byte
+		for _, certificate := range chain {
+			certificates = append(certificates, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: certificate.Raw})...)
+		}
+		return append(certificates, key...), nil
+	case JWK:
+		parsed, err := jwk.ParseKey(key)
+		if err != nil {
+			return nil, err
+		}
+		x5c := &cert.Chain{}
+		for _, certificate := range chain {
+			if err := x5c.AddString(base64.StdEncoding.EncodeToString(certificate.Raw)); err != nil {
+				return nil, err
+			}
+		}
+		if err := parsed.Set(jwk.X509CertChainKey, x5c); err != nil {
+			return nil, err
+		}
+		return json.Marshal(parsed)
+	default:
+		return key, nil
+	}
+}
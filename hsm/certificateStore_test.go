@@ -0,0 +1,80 @@
+package hsm
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"testing"
+
+	"github.com/eclipse-xfsc/crypto-provider-core/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func testCertificate(t *testing.T, provider HSMCryptoProvider) *x509.Certificate {
+	der, err := provider.CreateSelfSignedCertificate(types.CryptoIdentifier{KeyId: testId}, CertificateRequestOptions{Subject: pkix.Name{CommonName: "leaf"}})
+	assert.Nil(t, err)
+	certificate, err := x509.ParseCertificate(der)
+	assert.Nil(t, err)
+	return certificate
+}
+
+func TestHSMCryptoProvider_ImportCertificateChain(t *testing.T) {
+	key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	var mockApi = new(ContextTypeMock)
+	mockApi.On("FindKeyPair", []byte(testId), []byte(nil)).Return(&SoftSignerMock{key}, nil)
+	provider := getTestHSMCryptoProvider(mockApi)
+	certificate := testCertificate(t, provider)
+
+	mockApi.WithoutCertificates()
+	mockApi.On("ImportCertificateWithLabel", []byte(testId), certificateLabel(testId, 0), certificate).Return(nil)
+	err := provider.ImportCertificateChain(types.CryptoIdentifier{KeyId: testId}, []*x509.Certificate{certificate})
+	assert.Nil(t, err)
+	mockApi.AssertExpectations(t)
+
+	other, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	template := &x509.Certificate{SerialNumber: big.NewInt(1)}
+	der, _ := x509.CreateCertificate(rand.Reader, template, template, other.Public(), other)
+	foreign, _ := x509.ParseCertificate(der)
+	err = provider.ImportCertificateChain(types.CryptoIdentifier{KeyId: testId}, []*x509.Certificate{foreign})
+	assert.NotNil(t, err)
+}
+
+func TestHSMCryptoProvider_GetKey_WithCertificateChain(t *testing.T) {
+	key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	var mockApi = new(ContextTypeMock)
+	mockApi.On("FindKeyPair", []byte(testId), []byte(nil)).Return(&SoftSignerMock{key}, nil)
+	provider := getTestHSMCryptoProvider(mockApi)
+	certificate := testCertificate(t, provider)
+	mockApi.On("FindCertificate", []byte(testId), certificateLabel(testId, 0), (*big.Int)(nil)).Return(certificate, nil)
+	mockApi.WithoutCertificates()
+
+	cryptoKey, err := provider.GetKey(types.CryptoIdentifier{KeyId: testId})
+	assert.Nil(t, err)
+	block, err := cryptoKey.GetPem()
+	assert.Nil(t, err)
+	assert.Equal(t, "CERTIFICATE", block.Type)
+	assert.Equal(t, certificate.Raw, block.Bytes)
+
+	jwkKey, err := cryptoKey.GetJwk()
+	assert.Nil(t, err)
+	assert.Positive(t, jwkKey.X509CertChain().Len())
+}
+
+func TestHSMCryptoProvider_DeleteKey(t *testing.T) {
+	key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	var mockApi = new(ContextTypeMock)
+	mockApi.On("FindKeyPair", []byte(testId), []byte(nil)).Return(&SoftSignerMock{key}, nil)
+	provider := getTestHSMCryptoProvider(mockApi)
+	certificate := testCertificate(t, provider)
+	mockApi.On("FindCertificate", []byte(testId), certificateLabel(testId, 0), (*big.Int)(nil)).Return(certificate, nil)
+	mockApi.WithoutCertificates()
+	mockApi.On("DeleteCertificate", []byte(testId), certificateLabel(testId, 0), (*big.Int)(nil)).Return(nil)
+
+	err := provider.DeleteKey(types.CryptoIdentifier{KeyId: testId})
+	assert.Nil(t, err)
+	mockApi.AssertCalled(t, "DeleteCertificate", []byte(testId), certificateLabel(testId, 0), (*big.Int)(nil))
+	mockApi.AssertNumberOfCalls(t, "DeleteCertificate", 1)
+}
@@ -0,0 +1,64 @@
+package hsm
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"testing"
+
+	"github.com/eclipse-xfsc/crypto-provider-core/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHSMCryptoProvider_CreateCertificateRequest(t *testing.T) {
+	key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	var mockApi = new(ContextTypeMock)
+	mockApi.On("FindKeyPair", []byte(testId), []byte(nil)).Return(&SoftSignerMock{key}, nil)
+	provider := getTestHSMCryptoProvider(mockApi)
+
+	der, err := provider.CreateCertificateRequest(types.CryptoIdentifier{KeyId: testId}, CertificateRequestOptions{
+		Subject:     pkix.Name{CommonName: "issuer"},
+		DNSNames:    []string{"issuer.example.com"},
+		KeyUsage:    x509.KeyUsageDigitalSignature | x509.KeyUsageKeyAgreement,
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	})
+	assert.Nil(t, err)
+	csr, err := x509.ParseCertificateRequest(der)
+	assert.Nil(t, err)
+	assert.Nil(t, csr.CheckSignature())
+	assert.Equal(t, "issuer", csr.Subject.CommonName)
+	assert.Equal(t, []string{"issuer.example.com"}, csr.DNSNames)
+	assert.Len(t, csr.Extensions, 3)
+
+	// the extensions are honoured when a certificate is issued from the request
+	template := &x509.Certificate{SerialNumber: big.NewInt(1), ExtraExtensions: csr.Extensions}
+	certDer, err := x509.CreateCertificate(rand.Reader, template, template, csr.PublicKey, key)
+	assert.Nil(t, err)
+	cert, _ := x509.ParseCertificate(certDer)
+	assert.Equal(t, x509.KeyUsageDigitalSignature|x509.KeyUsageKeyAgreement, cert.KeyUsage)
+	assert.Equal(t, []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth}, cert.ExtKeyUsage)
+}
+
+func TestHSMCryptoProvider_CreateSelfSignedCertificate(t *testing.T) {
+	key, _ := rsa.GenerateKey(rand.Reader, 2048)
+	var mockApi = new(ContextTypeMock)
+	mockApi.On("FindKeyPair", []byte(testId), []byte(nil)).Return(&SoftSignerMock{key}, nil)
+	provider := getTestHSMCryptoProvider(mockApi)
+
+	der, err := provider.CreateSelfSignedCertificate(types.CryptoIdentifier{KeyId: testId}, CertificateRequestOptions{
+		Subject:     pkix.Name{CommonName: "ca"},
+		KeyUsage:    x509.KeyUsageDigitalSignature,
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+		IsCA:        true,
+	})
+	assert.Nil(t, err)
+	cert, err := x509.ParseCertificate(der)
+	assert.Nil(t, err)
+	assert.Nil(t, cert.CheckSignatureFrom(cert))
+	assert.True(t, cert.IsCA)
+	assert.Equal(t, x509.KeyUsageDigitalSignature|x509.KeyUsageCertSign|x509.KeyUsageCRLSign, cert.KeyUsage)
+}
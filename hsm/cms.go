@@ -0,0 +1,128 @@
+package hsm
+
+import (
+	"crypto"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/digitorus/pkcs7"
+	"github.com/eclipse-xfsc/crypto-provider-core/types"
+)
+
+// CMSOptions controls how a CMS SignedData structure is produced with an HSM key.
+type CMSOptions struct {
+	// Detached leaves the content out of the SignedData structure.
+	Detached bool
+	// Hash is the message digest algorithm, SHA-256 if not set.
+	Hash crypto.Hash
+	// OmitCertificates leaves the certificate chain of the key out of the SignedData structure.
+	OmitCertificates bool
+}
+
+// CMSVerifyOptions controls how a CMS SignedData structure is verified.
+type CMSVerifyOptions struct {
+	// Content is the signed content of a detached signature.
+	Content []byte
+	// Certificates are used to find the signer certificate in addition to the embedded ones.
+	Certificates []*x509.Certificate
+	// Roots enables the verification of the signer certificate chain.
+	Roots *x509.CertPool
+	// CurrentTime overrides the signing time attribute as point in time for the chain verification.
+	CurrentTime time.Time
+}
+
+var cmsDigestAlgorithmOID = timestampDigestAlgorithmOID
+
+// SignCMS creates a DER encoded CMS SignedData structure of the content. The signed attributes carry
+// content type, message digest and signing time. The signer certificate is the leaf of the
+// certificate chain stored with the key.
+func (p HSMCryptoProvider) SignCMS(parameter types.CryptoIdentifier, content []byte, options CMSOptions) (_ []byte, err error) {
+	p, op := p.observe("SignCMS", parameter.CryptoContext, parameter.KeyId)
+	defer op.end(&err)
+	hash := options.Hash
+	if hash == 0 {
+		hash = crypto.SHA256
+	}
+	digestAlgorithm, ok := cmsDigestAlgorithmOID[hash]
+	if !ok {
+		return nil, fmt.Errorf("unsupported hash %s", hash)
+	}
+	signer, err := p.getSigner(parameter)
+	if err != nil {
+		return nil, err
+	}
+	if signer == nil {
+		return nil, keyNotFound(parameter.KeyId)
+	}
+	chain, err := p.certificateChain(parameter)
+	if err != nil {
+		return nil, err
+	}
+	if len(chain) == 0 {
+		return nil, fmt.Errorf("no certificate stored for key %s", parameter.KeyId)
+	}
+
+	signedData, err := pkcs7.NewSignedData(content)
+	if err != nil {
+		return nil, err
+	}
+	signedData.SetDigestAlgorithm(digestAlgorithm)
+	config := pkcs7.SignerInfoConfig{SkipCertificates: options.OmitCertificates}
+	if err = signedData.AddSignerChain(chain[0], signer, chain[1:], config); err != nil {
+		return nil, err
+	}
+	if options.Detached {
+		signedData.Detach()
+	}
+	return signedData.Finish()
+}
+
+// VerifyCMS verifies all signatures of a DER encoded CMS SignedData structure and returns the signed
+// content. If the identifier names a key, the signature must have been made by the certificate stored
+// with it, otherwise signer certificates are looked up in the structure and the options.
+func (p HSMCryptoProvider) VerifyCMS(parameter types.CryptoIdentifier, signature []byte, options CMSVerifyOptions) (_ []byte, err error) {
+	p, op := p.observe("VerifyCMS", parameter.CryptoContext, parameter.KeyId)
+	defer op.end(&err)
+	p7, err := pkcs7.Parse(signature)
+	if err != nil {
+		return nil, err
+	}
+	if len(p7.Content) == 0 {
+		if len(options.Content) == 0 {
+			return nil, errors.New("missing content of detached signature")
+		}
+		p7.Content = options.Content
+	} else if len(options.Content) > 0 {
+		return nil, errors.New("content given for enveloping signature")
+	}
+
+	intermediates := append(append([]*x509.Certificate(nil), p7.Certificates...), options.Certificates...)
+	if parameter.KeyId != "" {
+		chain, err := p.certificateChain(parameter)
+		if err != nil {
+			return nil, err
+		}
+		if len(chain) == 0 {
+			return nil, fmt.Errorf("no certificate stored for key %s", parameter.KeyId)
+		}
+		// only the stored certificate can match the signer
+		p7.Certificates = chain[:1]
+		intermediates = append(intermediates, chain[1:]...)
+	} else {
+		p7.Certificates = intermediates
+	}
+
+	verifyOptions := x509.VerifyOptions{Roots: options.Roots, CurrentTime: options.CurrentTime}
+	if options.Roots != nil {
+		verifyOptions.Intermediates = x509.NewCertPool()
+		for _, certificate := range intermediates {
+			verifyOptions.Intermediates.AddCert(certificate)
+		}
+	}
+	if err = p7.VerifyWithOpts(verifyOptions); err != nil {
+		return nil, err
+	}
+	return p7.Content, nil
+}
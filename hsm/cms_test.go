@@ -0,0 +1,75 @@
+package hsm
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"testing"
+
+	"github.com/eclipse-xfsc/crypto-provider-core/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func getTestCMSProvider(t *testing.T) (HSMCryptoProvider, *x509.Certificate) {
+	key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	var mockApi = new(ContextTypeMock)
+	mockApi.On("FindKeyPair", []byte(testId), []byte(nil)).Return(&SoftSignerMock{key}, nil)
+	provider := getTestHSMCryptoProvider(mockApi)
+	der, err := provider.CreateSelfSignedCertificate(types.CryptoIdentifier{KeyId: testId}, CertificateRequestOptions{Subject: pkix.Name{CommonName: "signer"}})
+	assert.Nil(t, err)
+	certificate, _ := x509.ParseCertificate(der)
+	mockApi.On("FindCertificate", []byte(testId), certificateLabel(testId, 0), (*big.Int)(nil)).Return(certificate, nil)
+	mockApi.WithoutCertificates()
+	return provider, certificate
+}
+
+func TestSignCMS_Enveloping(t *testing.T) {
+	provider, certificate := getTestCMSProvider(t)
+	identifier := types.CryptoIdentifier{KeyId: testId}
+
+	signature, err := provider.SignCMS(identifier, []byte("document"), CMSOptions{})
+	assert.Nil(t, err)
+
+	roots := x509.NewCertPool()
+	roots.AddCert(certificate)
+	content, err := provider.VerifyCMS(types.CryptoIdentifier{}, signature, CMSVerifyOptions{Roots: roots})
+	assert.Nil(t, err)
+	assert.Equal(t, []byte("document"), content)
+
+	content, err = provider.VerifyCMS(identifier, signature, CMSVerifyOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, []byte("document"), content)
+}
+
+func TestSignCMS_Detached(t *testing.T) {
+	provider, certificate := getTestCMSProvider(t)
+	identifier := types.CryptoIdentifier{KeyId: testId}
+
+	signature, err := provider.SignCMS(identifier, []byte("document"), CMSOptions{Detached: true, OmitCertificates: true})
+	assert.Nil(t, err)
+
+	_, err = provider.VerifyCMS(types.CryptoIdentifier{}, signature, CMSVerifyOptions{})
+	assert.NotNil(t, err)
+	_, err = provider.VerifyCMS(types.CryptoIdentifier{}, signature, CMSVerifyOptions{Content: []byte("document")})
+	assert.NotNil(t, err)
+
+	content, err := provider.VerifyCMS(types.CryptoIdentifier{}, signature, CMSVerifyOptions{Content: []byte("document"), Certificates: []*x509.Certificate{certificate}})
+	assert.Nil(t, err)
+	assert.Equal(t, []byte("document"), content)
+
+	_, err = provider.VerifyCMS(identifier, signature, CMSVerifyOptions{Content: []byte("tampered")})
+	assert.NotNil(t, err)
+}
+
+func TestVerifyCMS_OtherKey(t *testing.T) {
+	provider, _ := getTestCMSProvider(t)
+	other, _ := getTestCMSProvider(t)
+
+	signature, err := other.SignCMS(types.CryptoIdentifier{KeyId: testId}, []byte("document"), CMSOptions{})
+	assert.Nil(t, err)
+	_, err = provider.VerifyCMS(types.CryptoIdentifier{KeyId: testId}, signature, CMSVerifyOptions{})
+	assert.NotNil(t, err)
+}
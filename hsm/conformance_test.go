@@ -0,0 +1,17 @@
+package hsm
+
+import (
+	"testing"
+
+	"github.com/eclipse-xfsc/crypto-provider-luna-cloud-hsm-plugin/conformance"
+)
+
+func TestConformance_DevBackend(t *testing.T) {
+	provider, _ := New(Options{Backend: DevBackend})
+	conformance.Run(t, provider, conformance.Options{})
+}
+
+func TestConformance_ContextTypeMock(t *testing.T) {
+	provider := getTestHSMCryptoProvider(new(ContextTypeMock).BackedBy(NewMemoryContext()))
+	conformance.Run(t, provider, conformance.Options{})
+}
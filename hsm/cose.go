@@ -0,0 +1,358 @@
+package hsm
+
+import (
+	"crypto"
+	"crypto/cipher"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"encoding/asn1"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+
+	"github.com/ThalesIgnite/crypto11"
+	"github.com/eclipse-xfsc/crypto-provider-core/types"
+	"github.com/fxamacker/cbor/v2"
+)
+
+// COSE header labels and algorithm identifiers, RFC 9052 and RFC 9053.
+const (
+	coseHeaderAlgorithm = 1
+	coseHeaderKeyId     = 4
+	coseHeaderIV        = 5
+
+	coseAlgES256   = -7
+	coseAlgEdDSA   = -8
+	coseAlgES384   = -35
+	coseAlgES512   = -36
+	coseAlgPS256   = -37
+	coseAlgA256GCM = 3
+
+	coseSign1Tag    = 18
+	coseEncrypt0Tag = 16
+)
+
+// COSEOptions controls how COSE_Sign1 and COSE_Encrypt0 messages are produced and processed.
+type COSEOptions struct {
+	// ExternalAAD is bound to the signature or ciphertext without being part of the message.
+	ExternalAAD []byte
+	// Detached leaves the payload out of a COSE_Sign1 message.
+	Detached bool
+}
+
+type coseMessage struct {
+	_           struct{} `cbor:",toarray"`
+	Protected   []byte
+	Unprotected map[interface{}]interface{}
+	Payload     []byte
+	Signature   []byte
+}
+
+type coseEncrypt0Message struct {
+	_           struct{} `cbor:",toarray"`
+	Protected   []byte
+	Unprotected map[interface{}]interface{}
+	Ciphertext  []byte
+}
+
+var coseEncMode, _ = cbor.CoreDetEncOptions().EncMode()
+
+// coseAlgorithm maps the key type reported by GetKey and ExportKey to the COSE algorithm and its hash.
+func coseAlgorithm(keyType types.KeyType) (int64, crypto.Hash, error) {
+	switch keyType {
+	case types.Ecdsap256:
+		return coseAlgES256, crypto.SHA256, nil
+	case types.Ecdsap384:
+		return coseAlgES384, crypto.SHA384, nil
+	case types.Ecdsap512:
+		return coseAlgES512, crypto.SHA512, nil
+	case types.Ed25519:
+		return coseAlgEdDSA, 0, nil
+	}
+	if mkt, _, err := splitKeyTypeAndParams(keyType); err == nil && mkt == RSA {
+		return coseAlgPS256, crypto.SHA256, nil
+	}
+	return 0, 0, fmt.Errorf("key type %s can not be used for COSE", keyType)
+}
+
+func coseHeader(headers map[interface{}]interface{}, label int64) (interface{}, bool) {
+	for k, v := range headers {
+		switch key := k.(type) {
+		case int64:
+			if key == label {
+				return v, true
+			}
+		case uint64:
+			if label >= 0 && key == uint64(label) {
+				return v, true
+			}
+		}
+	}
+	return nil, false
+}
+
+func coseProtectedAlgorithm(protected []byte) (int64, error) {
+	var headers map[interface{}]interface{}
+	if err := cbor.Unmarshal(protected, &headers); err != nil {
+		return 0, err
+	}
+	value, ok := coseHeader(headers, coseHeaderAlgorithm)
+	if !ok {
+		return 0, errors.New("missing algorithm in protected header")
+	}
+	switch alg := value.(type) {
+	case int64:
+		return alg, nil
+	case uint64:
+		return int64(alg), nil
+	}
+	return 0, fmt.Errorf("unsupported algorithm %v", value)
+}
+
+func (p HSMCryptoProvider) coseSigner(parameter types.CryptoIdentifier) (crypto11.Signer, int64, crypto.Hash, error) {
+	key, err := p.exportKey(parameter, p.controller.keyFormat)
+	if err != nil {
+		return nil, 0, 0, err
+	}
+	alg, hash, err := coseAlgorithm(key.KeyType)
+	if err != nil {
+		return nil, 0, 0, err
+	}
+	signer, err := p.getSigner(parameter)
+	if err != nil {
+		return nil, 0, 0, err
+	}
+	return signer, alg, hash, nil
+}
+
+// SignCOSE produces a tagged COSE_Sign1 message signed with the HSM key.
+func (p HSMCryptoProvider) SignCOSE(parameter types.CryptoIdentifier, payload []byte, options COSEOptions) (_ []byte, err error) {
+	p, op := p.observe("SignCOSE", parameter.CryptoContext, parameter.KeyId)
+	defer op.end(&err)
+	signer, alg, hash, err := p.coseSigner(parameter)
+	if err != nil {
+		return nil, err
+	}
+	protected, err := coseEncMode.Marshal(map[int64]interface{}{coseHeaderAlgorithm: alg})
+	if err != nil {
+		return nil, err
+	}
+	toBeSigned, err := coseEncMode.Marshal([]interface{}{"Signature1", protected, nonNil(options.ExternalAAD), nonNil(payload)})
+	if err != nil {
+		return nil, err
+	}
+	signature, err := coseSign(signer, p.controller.rand, toBeSigned, hash)
+	if err != nil {
+		return nil, err
+	}
+	msg := coseMessage{
+		Protected:   protected,
+		Unprotected: map[interface{}]interface{}{int64(coseHeaderKeyId): []byte(parameter.KeyId)},
+		Payload:     nonNil(payload),
+		Signature:   signature,
+	}
+	if options.Detached {
+		msg.Payload = nil
+	}
+	return coseEncMode.Marshal(cbor.Tag{Number: coseSign1Tag, Content: msg})
+}
+
+// VerifyCOSE verifies a COSE_Sign1 message against the HSM key and returns the payload.
+// For detached messages the payload has to be supplied in detachedPayload.
+func (p HSMCryptoProvider) VerifyCOSE(parameter types.CryptoIdentifier, message []byte, detachedPayload []byte, options COSEOptions) (_ []byte, err error) {
+	p, op := p.observe("VerifyCOSE", parameter.CryptoContext, parameter.KeyId)
+	defer op.end(&err)
+	signer, alg, hash, err := p.coseSigner(parameter)
+	if err != nil {
+		return nil, err
+	}
+	var msg coseMessage
+	if err := unmarshalCOSE(message, coseSign1Tag, &msg); err != nil {
+		return nil, err
+	}
+	msgAlg, err := coseProtectedAlgorithm(msg.Protected)
+	if err != nil {
+		return nil, err
+	}
+	if msgAlg != alg {
+		return nil, fmt.Errorf("algorithm %d does not match key algorithm %d", msgAlg, alg)
+	}
+	payload := msg.Payload
+	if payload == nil {
+		payload = detachedPayload
+	}
+	if payload == nil {
+		return nil, errors.New("missing payload")
+	}
+	toBeSigned, err := coseEncMode.Marshal([]interface{}{"Signature1", msg.Protected, nonNil(options.ExternalAAD), payload})
+	if err != nil {
+		return nil, err
+	}
+	if err := coseVerify(signer.Public(), toBeSigned, msg.Signature, hash); err != nil {
+		return nil, err
+	}
+	return payload, nil
+}
+
+// EncryptCOSE produces a tagged COSE_Encrypt0 message encrypted with A256GCM on the HSM.
+func (p HSMCryptoProvider) EncryptCOSE(parameter types.CryptoIdentifier, plaintext []byte, options COSEOptions) (_ []byte, err error) {
+	p, op := p.observe("EncryptCOSE", parameter.CryptoContext, parameter.KeyId)
+	op.keyType = types.Aes256GCM
+	defer op.end(&err)
+	aead, err := p.gcm(parameter)
+	if err != nil {
+		return nil, err
+	}
+	return coseEncrypt0(aead, p.controller.rand, []byte(parameter.KeyId), plaintext, options.ExternalAAD)
+}
+
+// DecryptCOSE decrypts a COSE_Encrypt0 message with A256GCM on the HSM.
+func (p HSMCryptoProvider) DecryptCOSE(parameter types.CryptoIdentifier, message []byte, options COSEOptions) (_ []byte, err error) {
+	p, op := p.observe("DecryptCOSE", parameter.CryptoContext, parameter.KeyId)
+	op.keyType = types.Aes256GCM
+	defer op.end(&err)
+	aead, err := p.gcm(parameter)
+	if err != nil {
+		return nil, err
+	}
+	return coseDecrypt0(aead, message, options.ExternalAAD)
+}
+
+func (p HSMCryptoProvider) gcm(parameter types.CryptoIdentifier) (cipher.AEAD, error) {
+	key, err := p.controller.api.FindKey([]byte(parameter.KeyId), nil)
+	if err != nil {
+		return nil, err
+	}
+	if key == nil {
+		return nil, keyNotFound(parameter.KeyId)
+	}
+	return key.NewGCM()
+}
+
+func coseEncrypt0(aead cipher.AEAD, rand io.Reader, kid []byte, plaintext []byte, externalAAD []byte) ([]byte, error) {
+	protected, err := coseEncMode.Marshal(map[int64]interface{}{coseHeaderAlgorithm: coseAlgA256GCM})
+	if err != nil {
+		return nil, err
+	}
+	aad, err := coseEncMode.Marshal([]interface{}{"Encrypt0", protected, nonNil(externalAAD)})
+	if err != nil {
+		return nil, err
+	}
+	iv := make([]byte, aead.NonceSize())
+	if _, err := io.ReadFull(rand, iv); err != nil {
+		return nil, err
+	}
+	ciphertext, err := seal(aead, iv, plaintext, aad)
+	if err != nil {
+		return nil, err
+	}
+	msg := coseEncrypt0Message{
+		Protected:   protected,
+		Unprotected: map[interface{}]interface{}{int64(coseHeaderIV): iv, int64(coseHeaderKeyId): kid},
+		Ciphertext:  ciphertext,
+	}
+	return coseEncMode.Marshal(cbor.Tag{Number: coseEncrypt0Tag, Content: msg})
+}
+
+func coseDecrypt0(aead cipher.AEAD, message []byte, externalAAD []byte) ([]byte, error) {
+	var msg coseEncrypt0Message
+	if err := unmarshalCOSE(message, coseEncrypt0Tag, &msg); err != nil {
+		return nil, err
+	}
+	alg, err := coseProtectedAlgorithm(msg.Protected)
+	if err != nil {
+		return nil, err
+	}
+	if alg != coseAlgA256GCM {
+		return nil, fmt.Errorf("unsupported algorithm %d", alg)
+	}
+	value, _ := coseHeader(msg.Unprotected, coseHeaderIV)
+	iv, ok := value.([]byte)
+	if !ok || len(iv) != aead.NonceSize() {
+		return nil, errors.New("missing or invalid iv")
+	}
+	aad, err := coseEncMode.Marshal([]interface{}{"Encrypt0", msg.Protected, nonNil(externalAAD)})
+	if err != nil {
+		return nil, err
+	}
+	return open(aead, iv, msg.Ciphertext, aad)
+}
+
+// unmarshalCOSE decodes a COSE message which may or may not carry its CBOR tag.
+func unmarshalCOSE(data []byte, tag uint64, v interface{}) error {
+	var raw cbor.RawTag
+	if err := cbor.Unmarshal(data, &raw); err == nil {
+		if raw.Number != tag {
+			return fmt.Errorf("expected COSE tag %d, got %d", tag, raw.Number)
+		}
+		data = raw.Content
+	}
+	return cbor.Unmarshal(data, v)
+}
+
+func coseSign(signer crypto.Signer, rand io.Reader, toBeSigned []byte, hash crypto.Hash) ([]byte, error) {
+	if _, ok := signer.Public().(ed25519.PublicKey); ok {
+		return signer.Sign(rand, toBeSigned, crypto.Hash(0))
+	}
+	h := hash.New()
+	h.Write(toBeSigned)
+	digest := h.Sum(nil)
+	switch pub := signer.Public().(type) {
+	case *ecdsa.PublicKey:
+		der, err := signer.Sign(rand, digest, hash)
+		if err != nil {
+			return nil, err
+		}
+		var sig struct{ R, S *big.Int }
+		if _, err := asn1.Unmarshal(der, &sig); err != nil {
+			return nil, err
+		}
+		size := (pub.Curve.Params().BitSize + 7) / 8
+		raw := make([]byte, 2*size)
+		sig.R.FillBytes(raw[:size])
+		sig.S.FillBytes(raw[size:])
+		return raw, nil
+	case *rsa.PublicKey:
+		return signer.Sign(rand, digest, &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash, Hash: hash})
+	default:
+		return nil, fmt.Errorf("keys of type %T can not be used for COSE", pub)
+	}
+}
+
+func coseVerify(public crypto.PublicKey, toBeSigned []byte, signature []byte, hash crypto.Hash) error {
+	if pub, ok := public.(ed25519.PublicKey); ok {
+		if !ed25519.Verify(pub, toBeSigned, signature) {
+			return errors.New("invalid signature")
+		}
+		return nil
+	}
+	h := hash.New()
+	h.Write(toBeSigned)
+	digest := h.Sum(nil)
+	switch pub := public.(type) {
+	case *ecdsa.PublicKey:
+		size := (pub.Curve.Params().BitSize + 7) / 8
+		if len(signature) != 2*size {
+			return errors.New("invalid signature length")
+		}
+		r := new(big.Int).SetBytes(signature[:size])
+		s := new(big.Int).SetBytes(signature[size:])
+		if !ecdsa.Verify(pub, digest, r, s) {
+			return errors.New("invalid signature")
+		}
+		return nil
+	case *rsa.PublicKey:
+		return rsa.VerifyPSS(pub, hash, digest, signature, &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash})
+	default:
+		return fmt.Errorf("keys of type %T can not be used for COSE", pub)
+	}
+}
+
+func nonNil(b []byte) []byte {
+	if b == nil {
+		return []byte{}
+	}
+	return b
+}
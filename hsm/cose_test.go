@@ -0,0 +1,90 @@
+package hsm
+
+import (
+	"crypto"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"testing"
+
+	"github.com/eclipse-xfsc/crypto-provider-core/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHSMCryptoProvider_SignCOSE(t *testing.T) {
+	p256, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	p384, _ := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	_, ed, _ := ed25519.GenerateKey(rand.Reader)
+	algorithms := []int64{coseAlgES256, coseAlgES384, coseAlgEdDSA}
+	for i, key := range []crypto.Signer{p256, p384, ed} {
+		var mockApi = new(ContextTypeMock)
+		mockApi.On("FindKeyPair", []byte(testId), []byte(nil)).Return(&SoftSignerMock{key}, nil)
+		provider := getTestHSMCryptoProvider(mockApi)
+		identifier := types.CryptoIdentifier{KeyId: testId}
+
+		signed, err := provider.SignCOSE(identifier, []byte("mdoc"), COSEOptions{ExternalAAD: []byte("aad")})
+		assert.Nil(t, err)
+		var msg coseMessage
+		assert.Nil(t, unmarshalCOSE(signed, coseSign1Tag, &msg))
+		alg, err := coseProtectedAlgorithm(msg.Protected)
+		assert.Nil(t, err)
+		assert.Equal(t, algorithms[i], alg)
+		payload, err := provider.VerifyCOSE(identifier, signed, nil, COSEOptions{ExternalAAD: []byte("aad")})
+		assert.Nil(t, err)
+		assert.Equal(t, []byte("mdoc"), payload)
+		_, err = provider.VerifyCOSE(identifier, signed, nil, COSEOptions{})
+		assert.NotNil(t, err)
+	}
+}
+
+func TestHSMCryptoProvider_SignCOSE_Detached(t *testing.T) {
+	key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	var mockApi = new(ContextTypeMock)
+	mockApi.On("FindKeyPair", []byte(testId), []byte(nil)).Return(&SoftSignerMock{key}, nil)
+	provider := getTestHSMCryptoProvider(mockApi)
+	identifier := types.CryptoIdentifier{KeyId: testId}
+
+	signed, err := provider.SignCOSE(identifier, []byte("mdoc"), COSEOptions{Detached: true})
+	assert.Nil(t, err)
+	_, err = provider.VerifyCOSE(identifier, signed, nil, COSEOptions{})
+	assert.NotNil(t, err)
+	payload, err := provider.VerifyCOSE(identifier, signed, []byte("mdoc"), COSEOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, []byte("mdoc"), payload)
+}
+
+func TestCoseEncrypt0(t *testing.T) {
+	key := make([]byte, 32)
+	_, _ = rand.Read(key)
+	block, _ := aes.NewCipher(key)
+	aead, _ := cipher.NewGCM(block)
+
+	encrypted, err := coseEncrypt0(aead, rand.Reader, []byte(testId), []byte("secret"), []byte("aad"))
+	assert.Nil(t, err)
+	decrypted, err := coseDecrypt0(aead, encrypted, []byte("aad"))
+	assert.Nil(t, err)
+	assert.Equal(t, []byte("secret"), decrypted)
+	_, err = coseDecrypt0(aead, encrypted, nil)
+	assert.NotNil(t, err)
+
+	_, err = coseEncrypt0(panickingAEAD{aead}, rand.Reader, []byte(testId), []byte("secret"), nil)
+	assert.EqualError(t, err, "encryption failed: CKR_DEVICE_ERROR")
+	_, err = coseDecrypt0(panickingAEAD{aead}, encrypted, []byte("aad"))
+	assert.EqualError(t, err, "decryption failed: CKR_DEVICE_ERROR")
+}
+
+// panickingAEAD fails like the AEAD of crypto11 when the HSM rejects the operation.
+type panickingAEAD struct {
+	cipher.AEAD
+}
+
+func (a panickingAEAD) Seal(dst, nonce, plaintext, additionalData []byte) []byte {
+	panic("CKR_DEVICE_ERROR")
+}
+
+func (a panickingAEAD) Open(dst, nonce, ciphertext, additionalData []byte) ([]byte, error) {
+	panic("CKR_DEVICE_ERROR")
+}
@@ -1,9 +1,11 @@
-package main
+package hsm
 
 import (
 	"crypto/elliptic"
+	"crypto/x509"
 	"github.com/ThalesIgnite/crypto11"
 	"io"
+	"math/big"
 )
 
 // methods used from crypto11.Context
@@ -58,17 +60,17 @@ type ContextType interface {
 	// FindKey retrieves a previously created symmetric key, or nil if it cannot be found.
 	//
 	// Either (but not both) of id and label may be nil, in which case they are ignored.
-	FindKey(id []byte, label []byte) (*crypto11.SecretKey, error)
+	FindKey(id []byte, label []byte) (SecretKey, error)
 	// FindKeys retrieves all matching symmetric keys, or a nil slice if none can be found.
 	//
 	// At least one of id and label must be specified.
-	FindKeys(id []byte, label []byte) (key []*crypto11.SecretKey, err error)
+	FindKeys(id []byte, label []byte) (key []SecretKey, err error)
 	// FindKeyWithAttributes retrieves a previously created symmetric key, or nil if it cannot be found.
-	FindKeyWithAttributes(attributes crypto11.AttributeSet) (*crypto11.SecretKey, error)
+	FindKeyWithAttributes(attributes crypto11.AttributeSet) (SecretKey, error)
 	// FindKeysWithAttributes retrieves previously created symmetric keys, or a nil slice if none can be found.
-	FindKeysWithAttributes(attributes crypto11.AttributeSet) ([]*crypto11.SecretKey, error)
+	FindKeysWithAttributes(attributes crypto11.AttributeSet) ([]SecretKey, error)
 	// FindAllKeyPairs retrieves all existing symmetric keys, or a nil slice if none can be found.
-	FindAllKeys() ([]*crypto11.SecretKey, error)
+	FindAllKeys() ([]SecretKey, error)
 	// GetAttributes gets the values of the specified attributes on the given key or keypair.
 	// If the key is asymmetric, then the attributes are retrieved from the private half.
 	//
@@ -100,14 +102,24 @@ type ContextType interface {
 	// a default value.
 	GenerateECDSAKeyPairWithAttributes(public, private crypto11.AttributeSet, curve elliptic.Curve) (crypto11.Signer, error)
 
-	GenerateSecretKey(id []byte, bits int, cipher *crypto11.SymmetricCipher) (*crypto11.SecretKey, error)
+	GenerateSecretKey(id []byte, bits int, cipher *crypto11.SymmetricCipher) (SecretKey, error)
 	// GenerateSecretKey creates an secret key of given length and type. The id and label parameters are used to
 	// set CKA_ID and CKA_LABEL respectively and must be non-nil.
-	GenerateSecretKeyWithLabel(id, label []byte, bits int, cipher *crypto11.SymmetricCipher) (*crypto11.SecretKey, error)
+	GenerateSecretKeyWithLabel(id, label []byte, bits int, cipher *crypto11.SymmetricCipher) (SecretKey, error)
 	// GenerateSecretKeyWithAttributes creates an secret key of given length and type. After this function returns, template
 	// will contain the attributes applied to the key. If required attributes are missing, they will be set to a default
 	// value.
-	GenerateSecretKeyWithAttributes(template crypto11.AttributeSet, bits int, cipher *crypto11.SymmetricCipher) (k *crypto11.SecretKey, err error)
+	GenerateSecretKeyWithAttributes(template crypto11.AttributeSet, bits int, cipher *crypto11.SymmetricCipher) (k SecretKey, err error)
 	// NewRandomReader returns a reader for the random number generator on the token.
 	NewRandomReader() (io.Reader, error)
+	// ImportCertificateWithLabel imports a certificate onto the token.  The id and label parameters are used to
+	// set CKA_ID and CKA_LABEL respectively and must be non-nil.
+	ImportCertificateWithLabel(id []byte, label []byte, certificate *x509.Certificate) error
+	// FindCertificate retrieves a previously imported certificate. Any combination of id, label
+	// and serial can be provided. An error is return if all are nil.
+	FindCertificate(id []byte, label []byte, serial *big.Int) (*x509.Certificate, error)
+	// DeleteCertificate destroys a previously imported certificate. it will return
+	// nil if succeeds or if the certificate does not exist. Any combination of id,
+	// label and serial can be provided. An error is return if all are nil.
+	DeleteCertificate(id []byte, label []byte, serial *big.Int) error
 }
@@ -1,4 +1,4 @@
-package main
+package hsm
 
 import (
 	"crypto/elliptic"
@@ -39,10 +39,20 @@ func (p HSMCryptoProvider) generateECDSA(params types.CryptoKeyParameter) (crypt
 			curve = elliptic.P256()
 		case "p384":
 			curve = elliptic.P384()
-		case "p521":
+		// types.Ecdsap512 is the name of the core for P-521
+		case "p512", "p521":
 			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("unsupported curve %s", param)
 		}
-		return p.controller.api.GenerateECDSAKeyPair([]byte(params.Identifier.KeyId), curve)
+		public, err := crypto11.NewAttributeSetWithID([]byte(params.Identifier.KeyId))
+		if err != nil {
+			return nil, err
+		}
+		// ECDH-ES of DecryptJWE derives with the private key, crypto11 only permits signing by default
+		private := public.Copy()
+		_ = private.Set(crypto11.CkaDerive, true)
+		return p.controller.api.GenerateECDSAKeyPairWithAttributes(public, private, curve)
 	}
 	return nil, fmt.Errorf("expected type %v - got %v", ECDSA, mkt)
 }
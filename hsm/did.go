@@ -0,0 +1,199 @@
+package hsm
+
+import (
+	"crypto"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/eclipse-xfsc/crypto-provider-core/types"
+	"github.com/lestrrat-go/jwx/v2/jwk"
+)
+
+// VerificationMethodType is the type of a DID verification method.
+type VerificationMethodType string
+
+const (
+	JsonWebKey2020 VerificationMethodType = "JsonWebKey2020"
+	Multikey       VerificationMethodType = "Multikey"
+)
+
+const (
+	didContext            = "https://www.w3.org/ns/did/v1"
+	jsonWebKey2020Context = "https://w3id.org/security/suites/jws-2020/v1"
+	multikeyContext       = "https://w3id.org/security/multikey/v1"
+)
+
+type VerificationMethod struct {
+	Id                 string          `json:"id"`
+	Type               string          `json:"type"`
+	Controller         string          `json:"controller"`
+	PublicKeyJwk       json.RawMessage `json:"publicKeyJwk,omitempty"`
+	PublicKeyMultibase string          `json:"publicKeyMultibase,omitempty"`
+}
+
+type DIDDocument struct {
+	Context            []string             `json:"@context"`
+	Id                 string               `json:"id"`
+	VerificationMethod []VerificationMethod `json:"verificationMethod"`
+	Authentication     []string             `json:"authentication,omitempty"`
+	AssertionMethod    []string             `json:"assertionMethod,omitempty"`
+}
+
+// DIDKey returns the did:key identifier of the public key.
+func (p HSMCryptoProvider) DIDKey(parameter types.CryptoIdentifier) (_ string, err error) {
+	p, op := p.observe("DIDKey", parameter.CryptoContext, parameter.KeyId)
+	defer op.end(&err)
+	return p.didKey(parameter)
+}
+
+func (p HSMCryptoProvider) didKey(parameter types.CryptoIdentifier) (string, error) {
+	pub, err := p.publicKey(parameter)
+	if err != nil {
+		return "", err
+	}
+	multibase, err := multibaseKey(pub)
+	if err != nil {
+		return "", err
+	}
+	return "did:key:" + string(multibase), nil
+}
+
+// DIDJWK returns the did:jwk identifier of the public key.
+func (p HSMCryptoProvider) DIDJWK(parameter types.CryptoIdentifier) (_ string, err error) {
+	p, op := p.observe("DIDJWK", parameter.CryptoContext, parameter.KeyId)
+	defer op.end(&err)
+	return p.didJWK(parameter)
+}
+
+func (p HSMCryptoProvider) didJWK(parameter types.CryptoIdentifier) (string, error) {
+	pub, err := p.publicKey(parameter)
+	if err != nil {
+		return "", err
+	}
+	key, err := jwk.FromRaw(pub)
+	if err != nil {
+		return "", err
+	}
+	encoded, err := json.Marshal(key)
+	if err != nil {
+		return "", err
+	}
+	return "did:jwk:" + base64.RawURLEncoding.EncodeToString(encoded), nil
+}
+
+// DIDKeyDocument resolves the did:key of the public key to its DID document.
+func (p HSMCryptoProvider) DIDKeyDocument(parameter types.CryptoIdentifier, typ VerificationMethodType) (_ *DIDDocument, err error) {
+	p, op := p.observe("DIDKeyDocument", parameter.CryptoContext, parameter.KeyId)
+	defer op.end(&err)
+	did, err := p.didKey(parameter)
+	if err != nil {
+		return nil, err
+	}
+	return p.singleKeyDocument(parameter, did, did+"#"+strings.TrimPrefix(did, "did:key:"), typ)
+}
+
+// DIDJWKDocument resolves the did:jwk of the public key to its DID document.
+func (p HSMCryptoProvider) DIDJWKDocument(parameter types.CryptoIdentifier, typ VerificationMethodType) (_ *DIDDocument, err error) {
+	p, op := p.observe("DIDJWKDocument", parameter.CryptoContext, parameter.KeyId)
+	defer op.end(&err)
+	did, err := p.didJWK(parameter)
+	if err != nil {
+		return nil, err
+	}
+	return p.singleKeyDocument(parameter, did, did+"#0", typ)
+}
+
+func (p HSMCryptoProvider) singleKeyDocument(parameter types.CryptoIdentifier, did string, id string, typ VerificationMethodType) (*DIDDocument, error) {
+	pub, err := p.publicKey(parameter)
+	if err != nil {
+		return nil, err
+	}
+	method, err := verificationMethod(pub, id, did, typ)
+	if err != nil {
+		return nil, err
+	}
+	doc := newDIDDocument(did, typ)
+	doc.addVerificationMethod(*method)
+	return doc, nil
+}
+
+// VerificationMethod returns the verification method entry of the public key with the given id and controller.
+func (p HSMCryptoProvider) VerificationMethod(parameter types.CryptoIdentifier, id string, controller string, typ VerificationMethodType) (_ *VerificationMethod, err error) {
+	p, op := p.observe("VerificationMethod", parameter.CryptoContext, parameter.KeyId)
+	defer op.end(&err)
+	pub, err := p.publicKey(parameter)
+	if err != nil {
+		return nil, err
+	}
+	return verificationMethod(pub, id, controller, typ)
+}
+
+// DIDWebDocument builds the did:web document listing all keys of the filter returned by GetKeys.
+// The verification methods are named did#keyId with the key id escaped.
+func (p HSMCryptoProvider) DIDWebDocument(filter types.CryptoFilter, did string, typ VerificationMethodType) (_ *DIDDocument, err error) {
+	p, op := p.observe("DIDWebDocument", filter.CryptoContext, filter.Id)
+	defer op.end(&err)
+	if !strings.HasPrefix(did, "did:web:") {
+		return nil, fmt.Errorf("%s is not a did:web", did)
+	}
+	keys, err := p.getKeys(filter)
+	if err != nil {
+		return nil, err
+	}
+	doc := newDIDDocument(did, typ)
+	for _, key := range keys.Keys {
+		pub, err := decodePublicKey(key.Key, p.controller.keyFormat)
+		if err != nil {
+			return nil, err
+		}
+		method, err := verificationMethod(pub, did+"#"+url.PathEscape(key.Identifier.KeyId), did, typ)
+		if err != nil {
+			return nil, err
+		}
+		doc.addVerificationMethod(*method)
+	}
+	return doc, nil
+}
+
+func newDIDDocument(did string, typ VerificationMethodType) *DIDDocument {
+	doc := &DIDDocument{Context: []string{didContext}, Id: did, VerificationMethod: []VerificationMethod{}}
+	switch typ {
+	case JsonWebKey2020:
+		doc.Context = append(doc.Context, jsonWebKey2020Context)
+	case Multikey:
+		doc.Context = append(doc.Context, multikeyContext)
+	}
+	return doc
+}
+
+func (d *DIDDocument) addVerificationMethod(method VerificationMethod) {
+	d.VerificationMethod = append(d.VerificationMethod, method)
+	d.Authentication = append(d.Authentication, method.Id)
+	d.AssertionMethod = append(d.AssertionMethod, method.Id)
+}
+
+func verificationMethod(pub crypto.PublicKey, id string, controller string, typ VerificationMethodType) (*VerificationMethod, error) {
+	method := &VerificationMethod{Id: id, Type: string(typ), Controller: controller}
+	switch typ {
+	case JsonWebKey2020:
+		key, err := jwk.FromRaw(pub)
+		if err != nil {
+			return nil, err
+		}
+		if method.PublicKeyJwk, err = json.Marshal(key); err != nil {
+			return nil, err
+		}
+	case Multikey:
+		multibase, err := multibaseKey(pub)
+		if err != nil {
+			return nil, err
+		}
+		method.PublicKeyMultibase = string(multibase)
+	default:
+		return nil, fmt.Errorf("unsupported verification method type %s", typ)
+	}
+	return method, nil
+}
@@ -0,0 +1,61 @@
+package hsm
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/base64"
+	"strings"
+	"testing"
+
+	"github.com/ThalesIgnite/crypto11"
+	"github.com/eclipse-xfsc/crypto-provider-core/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHSMCryptoProvider_DIDKey(t *testing.T) {
+	key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	var mockApi = new(ContextTypeMock)
+	mockApi.On("FindKeyPair", []byte(testId), []byte(nil)).Return(&SoftSignerMock{key}, nil)
+	provider := getTestHSMCryptoProvider(mockApi)
+	identifier := types.CryptoIdentifier{KeyId: testId}
+
+	did, err := provider.DIDKey(identifier)
+	assert.Nil(t, err)
+	assert.True(t, strings.HasPrefix(did, "did:key:zDn"))
+
+	doc, err := provider.DIDKeyDocument(identifier, Multikey)
+	assert.Nil(t, err)
+	assert.Equal(t, did, doc.Id)
+	assert.Equal(t, strings.TrimPrefix(did, "did:key:"), doc.VerificationMethod[0].PublicKeyMultibase)
+
+	didJwk, err := provider.DIDJWK(identifier)
+	assert.Nil(t, err)
+	decoded, err := base64.RawURLEncoding.DecodeString(strings.TrimPrefix(didJwk, "did:jwk:"))
+	assert.Nil(t, err)
+	assert.Contains(t, string(decoded), `"crv":"P-256"`)
+}
+
+func TestHSMCryptoProvider_DIDWebDocument(t *testing.T) {
+	first, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	second, _ := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	firstSigner, secondSigner := &SoftSignerMock{first}, &SoftSignerMock{second}
+	var mockApi = new(ContextTypeMock).WithoutCertificates()
+	mockApi.On("FindAllKeyPairs").Return([]crypto11.Signer{firstSigner, secondSigner}, nil)
+	mockApi.On("GetAttribute", firstSigner, crypto11.CkaId).Return(&crypto11.Attribute{Value: []byte("first")}, nil)
+	mockApi.On("GetAttribute", secondSigner, crypto11.CkaId).Return(&crypto11.Attribute{Value: []byte("second key")}, nil)
+	mockApi.On("FindKeyPair", []byte("first"), []byte(nil)).Return(firstSigner, nil)
+	mockApi.On("FindKeyPair", []byte("second key"), []byte(nil)).Return(secondSigner, nil)
+	provider := getTestHSMCryptoProvider(mockApi)
+
+	doc, err := provider.DIDWebDocument(types.CryptoFilter{}, "did:web:example.com", JsonWebKey2020)
+	assert.Nil(t, err)
+	assert.Len(t, doc.VerificationMethod, 2)
+	assert.Equal(t, "did:web:example.com#first", doc.VerificationMethod[0].Id)
+	assert.Equal(t, "did:web:example.com#second%20key", doc.AssertionMethod[1])
+	assert.Contains(t, string(doc.VerificationMethod[1].PublicKeyJwk), `"crv":"P-384"`)
+	mockApi.AssertNumberOfCalls(t, "FindKeyPair", 2)
+
+	_, err = provider.DIDWebDocument(types.CryptoFilter{}, "did:key:abc", JsonWebKey2020)
+	assert.NotNil(t, err)
+}
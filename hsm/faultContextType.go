@@ -0,0 +1,190 @@
+package hsm
+
+import (
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/miekg/pkcs11"
+)
+
+// Fault is a failure FaultContext injects into calls.
+type Fault struct {
+	// Methods the fault applies to, see Call. All if empty.
+	Methods []string
+	// Probability of the fault per call between 0 and 1, always if 0.
+	Probability float64
+	// Times limits how often the fault is injected, unlimited if 0.
+	Times int
+	// Latency delays the call.
+	Latency time.Duration
+	// Hang blocks the call until FaultContext.Clear is called.
+	Hang bool
+	// Error is returned by the call, e.g. pkcs11.Error(pkcs11.CKR_DEVICE_ERROR).
+	Error error
+	// AfterCall returns Error after the call reached the context, like a lost response.
+	AfterCall bool
+}
+
+// FaultContext is a ContextType decorator which injects latency, errors and hangs, for resilience tests.
+type FaultContext struct {
+	*InterceptorContext
+	mutex    sync.Mutex
+	faults   []*faultState
+	random   *rand.Rand
+	release  chan struct{}
+	injected int
+}
+
+type faultState struct {
+	Fault
+	injected int
+}
+
+var pkcs11ErrorNames = map[string]uint{
+	"CKR_GENERAL_ERROR":          pkcs11.CKR_GENERAL_ERROR,
+	"CKR_FUNCTION_FAILED":        pkcs11.CKR_FUNCTION_FAILED,
+	"CKR_DEVICE_ERROR":           pkcs11.CKR_DEVICE_ERROR,
+	"CKR_DEVICE_MEMORY":          pkcs11.CKR_DEVICE_MEMORY,
+	"CKR_DEVICE_REMOVED":         pkcs11.CKR_DEVICE_REMOVED,
+	"CKR_SESSION_CLOSED":         pkcs11.CKR_SESSION_CLOSED,
+	"CKR_SESSION_HANDLE_INVALID": pkcs11.CKR_SESSION_HANDLE_INVALID,
+	"CKR_OBJECT_HANDLE_INVALID":  pkcs11.CKR_OBJECT_HANDLE_INVALID,
+	"CKR_KEY_HANDLE_INVALID":     pkcs11.CKR_KEY_HANDLE_INVALID,
+	"CKR_PIN_EXPIRED":            pkcs11.CKR_PIN_EXPIRED,
+	"CKR_TOKEN_NOT_PRESENT":      pkcs11.CKR_TOKEN_NOT_PRESENT,
+	"CKR_USER_NOT_LOGGED_IN":     pkcs11.CKR_USER_NOT_LOGGED_IN,
+}
+
+// NewFaultContext decorates the context with the faults.
+func NewFaultContext(api ContextType, faults ...Fault) *FaultContext {
+	f := &FaultContext{random: rand.New(rand.NewPCG(rand.Uint64(), rand.Uint64())), release: make(chan struct{})}
+	f.InterceptorContext = NewInterceptorContext(api, f.intercept)
+	for _, fault := range faults {
+		f.Inject(fault)
+	}
+	return f
+}
+
+// Seed makes the probabilities of the faults reproducible.
+func (f *FaultContext) Seed(seed uint64) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	f.random = rand.New(rand.NewPCG(seed, seed))
+}
+
+// Inject adds a fault, the first matching fault of a call applies.
+func (f *FaultContext) Inject(fault Fault) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	f.faults = append(f.faults, &faultState{Fault: fault})
+}
+
+// Clear removes all faults and releases hanging calls.
+func (f *FaultContext) Clear() {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	f.faults = nil
+	close(f.release)
+	f.release = make(chan struct{})
+}
+
+// Injected returns the number of calls a fault was injected into.
+func (f *FaultContext) Injected() int {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	return f.injected
+}
+
+// fault selects the fault of a call, waits for its latency or hang and returns it, nil if none applies.
+func (f *FaultContext) fault(method string) *Fault {
+	f.mutex.Lock()
+	var selected *Fault
+	for _, fault := range f.faults {
+		if len(fault.Methods) > 0 && !slices.Contains(fault.Methods, method) {
+			continue
+		}
+		if fault.Times > 0 && fault.injected >= fault.Times {
+			continue
+		}
+		if fault.Probability > 0 && f.random.Float64() >= fault.Probability {
+			continue
+		}
+		fault.injected++
+		f.injected++
+		selected = &fault.Fault
+		break
+	}
+	release := f.release
+	f.mutex.Unlock()
+	if selected == nil {
+		return nil
+	}
+	time.Sleep(selected.Latency)
+	if selected.Hang {
+		<-release
+	}
+	return selected
+}
+
+// intercept injects the fault of the call before or after it.
+func (f *FaultContext) intercept(call *Call, invoke func() error) error {
+	fault := f.fault(call.Method)
+	if fault == nil || fault.Error == nil {
+		return invoke()
+	}
+	if !fault.AfterCall {
+		return fault.Error
+	}
+	_ = invoke()
+	return fault.Error
+}
+
+// ParseFaults parses faults like "methods=FindKeyPair|Sign,probability=0.1,error=CKR_DEVICE_ERROR;latency=2s",
+// entries are separated by semicolons. Further keys are times and after. Hangs can only be injected by
+// tests, nothing would release them.
+func ParseFaults(value string) ([]Fault, error) {
+	var faults []Fault
+	for _, entry := range strings.Split(value, ";") {
+		if strings.TrimSpace(entry) == "" {
+			continue
+		}
+		var fault Fault
+		for _, field := range strings.Split(entry, ",") {
+			key, v, _ := strings.Cut(strings.TrimSpace(field), "=")
+			var err error
+			switch key {
+			case "methods":
+				fault.Methods = strings.Split(v, "|")
+			case "probability":
+				fault.Probability, err = strconv.ParseFloat(v, 64)
+			case "times":
+				fault.Times, err = strconv.Atoi(v)
+			case "latency":
+				fault.Latency, err = time.ParseDuration(v)
+			case "hang":
+				return nil, errors.New("hang faults can only be injected with FaultContext.Inject")
+			case "after":
+				fault.AfterCall = true
+			case "error":
+				code, ok := pkcs11ErrorNames[v]
+				if !ok {
+					return nil, fmt.Errorf("unknown PKCS#11 error %q", v)
+				}
+				fault.Error = pkcs11.Error(code)
+			default:
+				return nil, fmt.Errorf("unknown fault field %q", key)
+			}
+			if err != nil {
+				return nil, fmt.Errorf("invalid fault field %q: %w", field, err)
+			}
+		}
+		faults = append(faults, fault)
+	}
+	return faults, nil
+}
@@ -0,0 +1,114 @@
+package hsm
+
+import (
+	"crypto/elliptic"
+	"crypto/rand"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/eclipse-xfsc/crypto-provider-core/types"
+	"github.com/miekg/pkcs11"
+	"github.com/stretchr/testify/assert"
+)
+
+func getFaultTestProvider(faults ...Fault) (HSMCryptoProvider, *FaultContext) {
+	memory := NewMemoryContext()
+	faultContext := NewFaultContext(memory, faults...)
+	return HSMCryptoProvider{controller: &hsmController{api: faultContext, rand: rand.Reader, derive: memory}}, faultContext
+}
+
+func TestParseFaults(t *testing.T) {
+	faults, err := ParseFaults("methods=FindKeyPair|Sign,probability=0.1,error=CKR_DEVICE_ERROR,times=3; latency=2s,after")
+	assert.Nil(t, err)
+	assert.Equal(t, []Fault{
+		{Methods: []string{"FindKeyPair", "Sign"}, Probability: 0.1, Error: pkcs11.Error(pkcs11.CKR_DEVICE_ERROR), Times: 3},
+		{Latency: 2 * time.Second, AfterCall: true},
+	}, faults)
+
+	faults, err = ParseFaults("")
+	assert.Nil(t, err)
+	assert.Empty(t, faults)
+	_, err = ParseFaults("error=CKR_UNKNOWN")
+	assert.EqualError(t, err, "unknown PKCS#11 error \"CKR_UNKNOWN\"")
+	_, err = ParseFaults("latency=soon")
+	assert.NotNil(t, err)
+	_, err = ParseFaults("methods=Sign,hang")
+	assert.EqualError(t, err, "hang faults can only be injected with FaultContext.Inject")
+}
+
+func TestFaultContext_Errors(t *testing.T) {
+	provider, faults := getFaultTestProvider()
+	id := types.CryptoIdentifier{KeyId: testId}
+	assert.Nil(t, provider.GenerateKey(types.CryptoKeyParameter{Identifier: id, KeyType: types.Ecdsap256}))
+
+	faults.Inject(Fault{Methods: []string{"Sign"}, Error: pkcs11.Error(pkcs11.CKR_SESSION_HANDLE_INVALID), Times: 1})
+	_, err := provider.Sign(id, make([]byte, 32))
+	assert.Equal(t, pkcs11.Error(pkcs11.CKR_SESSION_HANDLE_INVALID), err)
+	_, err = provider.Sign(id, make([]byte, 32))
+	assert.Nil(t, err)
+	assert.Equal(t, 1, faults.Injected())
+
+	faults.Inject(Fault{Methods: []string{"FindKeyPair"}, Error: pkcs11.Error(pkcs11.CKR_PIN_EXPIRED)})
+	_, err = provider.GetKey(id)
+	assert.Equal(t, pkcs11.Error(pkcs11.CKR_PIN_EXPIRED), err)
+	faults.Clear()
+	// attributes of wrapped key pairs are read from the decorated context
+	keys, err := provider.GetKeys(types.CryptoFilter{Filter: *regexp.MustCompile("")})
+	assert.Nil(t, err)
+	assert.Len(t, keys.Keys, 1)
+
+	aes := types.CryptoIdentifier{KeyId: "aes"}
+	assert.Nil(t, provider.GenerateKey(types.CryptoKeyParameter{Identifier: aes, KeyType: types.Aes256GCM}))
+	faults.Inject(Fault{Methods: []string{"NewGCM"}, Error: pkcs11.Error(pkcs11.CKR_DEVICE_ERROR)})
+	_, err = provider.AEAD(aes)
+	assert.Equal(t, pkcs11.Error(pkcs11.CKR_DEVICE_ERROR), err)
+}
+
+func TestFaultContext_AfterCall(t *testing.T) {
+	provider, faults := getFaultTestProvider(Fault{Methods: []string{"GenerateECDSAKeyPair"}, Error: pkcs11.Error(pkcs11.CKR_DEVICE_ERROR), AfterCall: true})
+	_, err := faults.GenerateECDSAKeyPair([]byte(testId), elliptic.P256())
+	assert.Equal(t, pkcs11.Error(pkcs11.CKR_DEVICE_ERROR), err)
+	existing, err := provider.getSigner(types.CryptoIdentifier{KeyId: testId})
+	assert.Nil(t, err)
+	assert.NotNil(t, existing)
+}
+
+func TestFaultContext_Probability(t *testing.T) {
+	_, faults := getFaultTestProvider(Fault{Methods: []string{"NewRandomReader"}, Probability: 0.5, Error: pkcs11.Error(pkcs11.CKR_DEVICE_ERROR)})
+	faults.Seed(1)
+	failures := 0
+	for i := 0; i < 100; i++ {
+		if _, err := faults.NewRandomReader(); err != nil {
+			failures++
+		}
+	}
+	assert.Equal(t, faults.Injected(), failures)
+	assert.InDelta(t, 50, failures, 20)
+}
+
+func TestFaultContext_LatencyAndHang(t *testing.T) {
+	_, faults := getFaultTestProvider(Fault{Methods: []string{"FindAllKeys"}, Latency: 20 * time.Millisecond})
+	start := time.Now()
+	_, err := faults.FindAllKeys()
+	assert.Nil(t, err)
+	assert.GreaterOrEqual(t, time.Since(start), 20*time.Millisecond)
+
+	faults.Inject(Fault{Methods: []string{"FindAllKeyPairs"}, Hang: true})
+	done := make(chan struct{})
+	go func() {
+		_, _ = faults.FindAllKeyPairs()
+		close(done)
+	}()
+	select {
+	case <-done:
+		t.Fatal("call did not hang")
+	case <-time.After(20 * time.Millisecond):
+	}
+	faults.Clear()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Clear did not release the call")
+	}
+}
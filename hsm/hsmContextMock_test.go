@@ -1,18 +1,75 @@
-package main
+package hsm
 
 import (
 	"crypto"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdsa"
 	"crypto/elliptic"
 	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
 	"github.com/ThalesIgnite/crypto11"
 	"github.com/stretchr/testify/mock"
 	"io"
+	"math/big"
 )
 
 type ContextTypeMock struct {
 	mock.Mock
 }
 
+// WithoutCertificates lets FindCertificate report that no certificates are stored.
+func (t *ContextTypeMock) WithoutCertificates() *ContextTypeMock {
+	t.On("FindCertificate", mock.Anything, mock.Anything, mock.Anything).Return((*x509.Certificate)(nil), nil)
+	return t
+}
+
+// BackedBy answers the calls of the provider operations with the keys of the MemoryContext, so generic
+// tests such as the conformance kit run on the mock.
+func (t *ContextTypeMock) BackedBy(memory *MemoryContext) *ContextTypeMock {
+	answer := func(method string, arguments int, call func(args mock.Arguments) mock.Arguments) {
+		matchers := make([]interface{}, arguments)
+		for i := range matchers {
+			matchers[i] = mock.Anything
+		}
+		expectation := t.On(method, matchers...)
+		expectation.Run(func(args mock.Arguments) { expectation.ReturnArguments = call(args) })
+	}
+	answer("GenerateRSAKeyPair", 2, func(args mock.Arguments) mock.Arguments {
+		return returns(memory.GenerateRSAKeyPair(args.Get(0).([]byte), args.Int(1)))
+	})
+	answer("GenerateECDSAKeyPairWithAttributes", 3, func(args mock.Arguments) mock.Arguments {
+		return returns(memory.GenerateECDSAKeyPairWithAttributes(args.Get(0).(crypto11.AttributeSet), args.Get(1).(crypto11.AttributeSet), args.Get(2).(elliptic.Curve)))
+	})
+	answer("GenerateSecretKey", 3, func(args mock.Arguments) mock.Arguments {
+		return returns(memory.GenerateSecretKey(args.Get(0).([]byte), args.Int(1), args.Get(2).(*crypto11.SymmetricCipher)))
+	})
+	answer("FindKeyPair", 2, func(args mock.Arguments) mock.Arguments {
+		return returns(memory.FindKeyPair(args.Get(0).([]byte), args.Get(1).([]byte)))
+	})
+	answer("FindAllKeyPairs", 0, func(mock.Arguments) mock.Arguments {
+		return returns(memory.FindAllKeyPairs())
+	})
+	answer("FindKey", 2, func(args mock.Arguments) mock.Arguments {
+		return returns(memory.FindKey(args.Get(0).([]byte), args.Get(1).([]byte)))
+	})
+	answer("GetAttribute", 2, func(args mock.Arguments) mock.Arguments {
+		return returns(memory.GetAttribute(args.Get(0), args.Get(1).(crypto11.AttributeType)))
+	})
+	answer("FindCertificate", 3, func(args mock.Arguments) mock.Arguments {
+		return returns(memory.FindCertificate(args.Get(0).([]byte), args.Get(1).([]byte), args.Get(2).(*big.Int)))
+	})
+	answer("DeleteCertificate", 3, func(args mock.Arguments) mock.Arguments {
+		return returns(memory.DeleteCertificate(args.Get(0).([]byte), args.Get(1).([]byte), args.Get(2).(*big.Int)))
+	})
+	return t
+}
+
+func returns(values ...interface{}) mock.Arguments {
+	return values
+}
+
 type SignerMock struct {
 	public crypto.PublicKey
 }
@@ -27,6 +84,80 @@ func (s *SignerMock) Public() crypto.PublicKey {
 	return s.public
 }
 
+// SecretKeyMock is a SecretKey whose operations are mocked.
+type SecretKeyMock struct {
+	mock.Mock
+}
+
+func (k *SecretKeyMock) BlockSize() int {
+	return aes.BlockSize
+}
+
+func (k *SecretKeyMock) Encrypt(dst, src []byte) {
+	k.Called(dst, src)
+}
+
+func (k *SecretKeyMock) Decrypt(dst, src []byte) {
+	k.Called(dst, src)
+}
+
+func (k *SecretKeyMock) NewGCM() (cipher.AEAD, error) {
+	args := k.Called()
+	aead, _ := args.Get(0).(cipher.AEAD)
+	return aead, args.Error(1)
+}
+
+func (k *SecretKeyMock) NewCBCEncrypter(iv []byte) (cipher.BlockMode, error) {
+	args := k.Called(iv)
+	mode, _ := args.Get(0).(cipher.BlockMode)
+	return mode, args.Error(1)
+}
+
+func (k *SecretKeyMock) NewCBCDecrypter(iv []byte) (cipher.BlockMode, error) {
+	args := k.Called(iv)
+	mode, _ := args.Get(0).(cipher.BlockMode)
+	return mode, args.Error(1)
+}
+
+func (k *SecretKeyMock) Delete() error {
+	return k.Called().Error(0)
+}
+
+// SoftSignerMock signs with a software key, for tests which need real signatures.
+type SoftSignerMock struct {
+	crypto.Signer
+}
+
+func (s *SoftSignerMock) Delete() error {
+	return nil
+}
+
+// SoftDecrypterMock signs and decrypts with a software rsa key.
+type SoftDecrypterMock struct {
+	*rsa.PrivateKey
+}
+
+func (s *SoftDecrypterMock) Delete() error {
+	return nil
+}
+
+// SoftDeriverMock derives ECDH secrets with software keys indexed by CKA_ID.
+type SoftDeriverMock struct {
+	keys map[string]*ecdsa.PrivateKey
+}
+
+func (d *SoftDeriverMock) DeriveECDH(id []byte, peer *ecdsa.PublicKey) ([]byte, error) {
+	priv, err := d.keys[string(id)].ECDH()
+	if err != nil {
+		return nil, err
+	}
+	pub, err := peer.ECDH()
+	if err != nil {
+		return nil, err
+	}
+	return priv.ECDH(pub)
+}
+
 // GenerateRSAKeyPair creates an RSA key pair on the token. The id parameter is used to
 // set CKA_ID and must be non-nil. RSA private keys are generated with both sign and decrypt
 // permissions, and a public exponent of 65537.
@@ -68,7 +199,8 @@ func (t *ContextTypeMock) FindKeyPair(id []byte, label []byte) (crypto11.Signer,
 
 	args := t.Called(id, label)
 
-	return args.Get(0).(crypto11.Signer), args.Error(1)
+	signer, _ := args.Get(0).(crypto11.Signer)
+	return signer, args.Error(1)
 }
 
 // FindKeyPairs retrieves all matching asymmetric key pairs, or a nil slice if none can be found.
@@ -126,45 +258,50 @@ func (t *ContextTypeMock) FindAllKeyPairs() ([]crypto11.Signer, error) {
 // FindKey retrieves a previously created symmetric key, or nil if it cannot be found.
 //
 // Either (but not both) of id and label may be nil, in which case they are ignored.
-func (t *ContextTypeMock) FindKey(id []byte, label []byte) (*crypto11.SecretKey, error) {
+func (t *ContextTypeMock) FindKey(id []byte, label []byte) (SecretKey, error) {
 
 	args := t.Called(id, label)
 
-	return args.Get(0).(*crypto11.SecretKey), args.Error(1)
+	key, _ := args.Get(0).(SecretKey)
+	return key, args.Error(1)
 }
 
 // FindKeys retrieves all matching symmetric keys, or a nil slice if none can be found.
 //
 // At least one of id and label must be specified.
-func (t *ContextTypeMock) FindKeys(id []byte, label []byte) (key []*crypto11.SecretKey, err error) {
+func (t *ContextTypeMock) FindKeys(id []byte, label []byte) (key []SecretKey, err error) {
 
 	args := t.Called(id, label)
 
-	return args.Get(0).([]*crypto11.SecretKey), args.Error(1)
+	keys, _ := args.Get(0).([]SecretKey)
+	return keys, args.Error(1)
 }
 
 // FindKeyWithAttributes retrieves a previously created symmetric key, or nil if it cannot be found.
-func (t *ContextTypeMock) FindKeyWithAttributes(attributes crypto11.AttributeSet) (*crypto11.SecretKey, error) {
+func (t *ContextTypeMock) FindKeyWithAttributes(attributes crypto11.AttributeSet) (SecretKey, error) {
 
 	args := t.Called(attributes)
 
-	return args.Get(0).(*crypto11.SecretKey), args.Error(1)
+	key, _ := args.Get(0).(SecretKey)
+	return key, args.Error(1)
 }
 
 // FindKeysWithAttributes retrieves previously created symmetric keys, or a nil slice if none can be found.
-func (t *ContextTypeMock) FindKeysWithAttributes(attributes crypto11.AttributeSet) ([]*crypto11.SecretKey, error) {
+func (t *ContextTypeMock) FindKeysWithAttributes(attributes crypto11.AttributeSet) ([]SecretKey, error) {
 
 	args := t.Called(attributes)
 
-	return args.Get(0).([]*crypto11.SecretKey), args.Error(1)
+	keys, _ := args.Get(0).([]SecretKey)
+	return keys, args.Error(1)
 }
 
 // FindAllKeyPairs retrieves all existing symmetric keys, or a nil slice if none can be found.
-func (t *ContextTypeMock) FindAllKeys() ([]*crypto11.SecretKey, error) {
+func (t *ContextTypeMock) FindAllKeys() ([]SecretKey, error) {
 
 	args := t.Called()
 
-	return args.Get(0).([]*crypto11.SecretKey), args.Error(1)
+	keys, _ := args.Get(0).([]SecretKey)
+	return keys, args.Error(1)
 }
 
 // GetAttributes gets the values of the specified attributes on the given key or keypair.
@@ -180,7 +317,10 @@ func (t *ContextTypeMock) GetAttributes(key interface{}, attributes []crypto11.A
 //
 // If the object is not a crypto11 key or keypair then an error is returned.
 func (t *ContextTypeMock) GetAttribute(key interface{}, attribute crypto11.AttributeType) (a *crypto11.Attribute, err error) {
-	return nil, nil
+
+	args := t.Called(key, attribute)
+
+	return args.Get(0).(*crypto11.Attribute), args.Error(1)
 }
 
 // GetPubAttributes gets the values of the specified attributes on the public half of the given keypair.
@@ -221,26 +361,31 @@ func (t *ContextTypeMock) GenerateECDSAKeyPairWithLabel(id, label []byte, curve
 // private will contain the attributes applied to the key pair. If required attributes are missing, they will be set to
 // a default value.
 func (t *ContextTypeMock) GenerateECDSAKeyPairWithAttributes(public, private crypto11.AttributeSet, curve elliptic.Curve) (crypto11.Signer, error) {
-	return nil, nil
+
+	args := t.Called(public, private, curve)
+
+	signer, _ := args.Get(0).(crypto11.Signer)
+	return signer, args.Error(1)
 }
 
-func (t *ContextTypeMock) GenerateSecretKey(id []byte, bits int, cipher *crypto11.SymmetricCipher) (*crypto11.SecretKey, error) {
+func (t *ContextTypeMock) GenerateSecretKey(id []byte, bits int, cipher *crypto11.SymmetricCipher) (SecretKey, error) {
 
 	args := t.Called(id, bits, cipher)
 
-	return args.Get(0).(*crypto11.SecretKey), args.Error(1)
+	key, _ := args.Get(0).(SecretKey)
+	return key, args.Error(1)
 }
 
 // GenerateSecretKey creates an secret key of given length and type. The id and label parameters are used to
 // set CKA_ID and CKA_LABEL respectively and must be non-nil.
-func (t *ContextTypeMock) GenerateSecretKeyWithLabel(id, label []byte, bits int, cipher *crypto11.SymmetricCipher) (*crypto11.SecretKey, error) {
+func (t *ContextTypeMock) GenerateSecretKeyWithLabel(id, label []byte, bits int, cipher *crypto11.SymmetricCipher) (SecretKey, error) {
 	return nil, nil
 }
 
 // GenerateSecretKeyWithAttributes creates an secret key of given length and type. After this function returns, template
 // will contain the attributes applied to the key. If required attributes are missing, they will be set to a default
 // value.
-func (t *ContextTypeMock) GenerateSecretKeyWithAttributes(template crypto11.AttributeSet, bits int, cipher *crypto11.SymmetricCipher) (k *crypto11.SecretKey, err error) {
+func (t *ContextTypeMock) GenerateSecretKeyWithAttributes(template crypto11.AttributeSet, bits int, cipher *crypto11.SymmetricCipher) (k SecretKey, err error) {
 	return nil, nil
 }
 
@@ -248,3 +393,31 @@ func (t *ContextTypeMock) GenerateSecretKeyWithAttributes(template crypto11.Attr
 func (t *ContextTypeMock) NewRandomReader() (io.Reader, error) {
 	return rand.Reader, nil
 }
+
+// ImportCertificateWithLabel imports a certificate onto the token.  The id and label parameters are used to
+// set CKA_ID and CKA_LABEL respectively and must be non-nil.
+func (t *ContextTypeMock) ImportCertificateWithLabel(id []byte, label []byte, certificate *x509.Certificate) error {
+
+	args := t.Called(id, label, certificate)
+
+	return args.Error(0)
+}
+
+// FindCertificate retrieves a previously imported certificate. Any combination of id, label
+// and serial can be provided. An error is return if all are nil.
+func (t *ContextTypeMock) FindCertificate(id []byte, label []byte, serial *big.Int) (*x509.Certificate, error) {
+
+	args := t.Called(id, label, serial)
+
+	return args.Get(0).(*x509.Certificate), args.Error(1)
+}
+
+// DeleteCertificate destroys a previously imported certificate. it will return
+// nil if succeeds or if the certificate does not exist. Any combination of id,
+// label and serial can be provided. An error is return if all are nil.
+func (t *ContextTypeMock) DeleteCertificate(id []byte, label []byte, serial *big.Int) error {
+
+	args := t.Called(id, label, serial)
+
+	return args.Error(0)
+}
@@ -0,0 +1,124 @@
+package hsm
+
+import (
+	"crypto"
+	"crypto/rand"
+	"fmt"
+	"io"
+	"log/slog"
+
+	"github.com/ThalesIgnite/crypto11"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Backend selects the implementation of the partition.
+type Backend string
+
+const (
+	// PKCS11Backend uses the partition of the PKCS#11 library, this is the default.
+	PKCS11Backend Backend = "pkcs11"
+	// DevBackend keeps software keys in memory, see MemoryContext. Keys are lost on restart.
+	DevBackend Backend = "dev"
+)
+
+// Options configures the connection to the HSM partition.
+type Options struct {
+	// Backend of the partition, PKCS11Backend if empty.
+	Backend Backend
+	// Path of the PKCS#11 library.
+	Path string
+	// TokenLabel is the label of the partition.
+	TokenLabel string
+	// Pin is the crypto officer PIN of the partition.
+	Pin string
+	// KeyFormat is the encoding of public keys returned by GetKey, DefaultKeyFormat if empty.
+	KeyFormat KeyFormat
+	// SignerOptions select hash and padding of Sign and Verify, SHA-256 and, for RSA keys, PSS if nil.
+	// Without a hash the data is signed as is.
+	SignerOptions crypto.SignerOpts
+	// Faults are injected into the calls to the partition, see FaultContext. Only for staging.
+	Faults []Fault
+	// Recording receives the calls to the partition if set, see RecordingContext. Only for staging.
+	Recording io.Writer
+	// Logger receives the logs of the provider, slog.Default() if nil. PINs and key material are redacted.
+	Logger *slog.Logger
+	// LogLevel is the minimum level logged, the level of Logger if nil. The handler of Logger may filter
+	// further.
+	LogLevel slog.Leveler
+	// Metrics receives measurements of the operations and of the calls to the partition if set, see
+	// NewPrometheusMetrics.
+	Metrics Metrics
+	// TracerProvider creates the spans of the operations and of the calls to the partition,
+	// otel.GetTracerProvider() if nil.
+	TracerProvider trace.TracerProvider
+}
+
+// New connects to the HSM partition and returns a provider for its keys.
+func New(options Options) (HSMCryptoProvider, error) {
+	keyFormat := options.KeyFormat
+	if keyFormat == "" {
+		keyFormat = DefaultKeyFormat
+	}
+	if !validKeyFormat(keyFormat) {
+		return HSMCryptoProvider{}, fmt.Errorf("unsupported key format %q", keyFormat)
+	}
+	def := hsmController{
+		config: &crypto11.Config{
+			Path:       options.Path,
+			TokenLabel: options.TokenLabel,
+			Pin:        options.Pin,
+		},
+		signerOptions: options.SignerOptions,
+		keyFormat:     keyFormat,
+		logger:        newLogger(options.Logger, options.LogLevel),
+		metrics:       options.Metrics,
+		tracer:        newTracer(options.TracerProvider),
+	}
+	controller := &def
+	if options.Backend == "" {
+		options.Backend = PKCS11Backend
+	}
+	switch options.Backend {
+	case PKCS11Backend:
+		var err error
+		if controller, err = def.withApiAndRandomReader(); err != nil {
+			return HSMCryptoProvider{}, err
+		}
+	case DevBackend:
+		memory := NewMemoryContext()
+		controller.api, controller.rand, controller.derive = memory, rand.Reader, memory
+	default:
+		return HSMCryptoProvider{}, fmt.Errorf("unsupported backend %q", options.Backend)
+	}
+	controller.log().Info("connected to partition", slog.String("backend", string(options.Backend)), slog.String("token_label", options.TokenLabel))
+	if len(options.Faults) > 0 {
+		controller.api = NewFaultContext(controller.api, options.Faults...)
+	}
+	if options.Recording != nil {
+		controller.api = NewRecordingContext(controller.api, options.Recording)
+	}
+	if options.Metrics != nil {
+		controller.api = NewMetricsContext(controller.api, options.Metrics)
+	}
+	return HSMCryptoProvider{controller: controller}, nil
+}
+
+func (c hsmController) withApiAndRandomReader() (*hsmController, error) {
+	ctx, err := crypto11.Configure(c.config)
+	if err != nil {
+		c.log().Error("failed configuring PKCS#11 library", slog.String("path", c.config.Path), slog.String("token_label", c.config.TokenLabel), slog.String("error_class", errorClass(err)), slog.String("error", err.Error()))
+		return nil, err
+	}
+	c.meter().Connected()
+	c.meter().MaxSessions(c.config.MaxSessions)
+	randReader, err := ctx.NewRandomReader()
+	if err != nil {
+		return nil, err
+	}
+	deriver, err := newPkcs11Deriver(c.config, c.meter())
+	if err != nil {
+		return nil, err
+	}
+	controller := &hsmController{api: crypto11Context{ctx}, config: c.config, signerOptions: c.signerOptions, rand: randReader, derive: deriver, keyFormat: c.keyFormat, logger: c.logger, metrics: c.metrics, tracer: c.tracer}
+	return controller, nil
+}
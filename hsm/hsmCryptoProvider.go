@@ -0,0 +1,392 @@
+package hsm
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"crypto/sha256"
+	b64 "encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log/slog"
+	"math/rand"
+	"slices"
+
+	"github.com/ThalesIgnite/crypto11"
+	"github.com/eclipse-xfsc/crypto-provider-core/types"
+)
+
+const (
+	HsmNamespace = "luna-cloud-hsm"
+)
+
+func (p HSMCryptoProvider) CreateCryptoContext(context types.CryptoContext) error {
+	return nil
+}
+
+func (p HSMCryptoProvider) DestroyCryptoContext(context types.CryptoContext) error {
+	return nil
+}
+
+func (p HSMCryptoProvider) DeleteKey(parameter types.CryptoIdentifier) (err error) {
+	p, op := p.observe("DeleteKey", parameter.CryptoContext, parameter.KeyId)
+	defer op.end(&err)
+	id := []byte(parameter.KeyId)
+	signer, err := p.getSigner(parameter)
+	if err != nil {
+		return err
+	}
+	if signer != nil {
+		op.keyType = keyPairType(signer)
+		if err := p.deleteCertificateChain(parameter); err != nil {
+			return err
+		}
+		return signer.Delete()
+	}
+	key, err := p.controller.api.FindKey(id, nil)
+	if err != nil {
+		return err
+	}
+	if key == nil {
+		return keyNotFound(parameter.KeyId)
+	}
+	op.keyType = types.Aes256GCM
+	return key.Delete()
+}
+
+// keyPairType returns the key type of the key pair for metrics, empty if it is unknown.
+func keyPairType(signer crypto11.Signer) types.KeyType {
+	if signer == nil {
+		return ""
+	}
+	keyType, _ := publicKeyType(signer.Public())
+	return keyType
+}
+
+func (p HSMCryptoProvider) getSigner(parameter types.CryptoIdentifier) (crypto11.Signer, error) {
+	id := []byte(parameter.KeyId)
+	return p.controller.api.FindKeyPair(id, nil)
+}
+
+// ErrKeyNotFound is matched by the errors of operations on keys which do not exist. It is
+// fs.ErrNotExist, so clients such as the remote package need not depend on this package.
+var ErrKeyNotFound = fs.ErrNotExist
+
+type keyNotFoundError string
+
+func keyNotFound(keyId string) error {
+	return keyNotFoundError(keyId)
+}
+
+func (e keyNotFoundError) Error() string {
+	return fmt.Sprintf("key %s not found", string(e))
+}
+
+func (e keyNotFoundError) Is(target error) bool {
+	return target == ErrKeyNotFound
+}
+
+func (p HSMCryptoProvider) GetNamespaces(context types.CryptoContext) ([]string, error) {
+	return []string{HsmNamespace}, nil
+}
+func (p HSMCryptoProvider) GenerateRandom(context types.CryptoContext, number int) (random []byte, err error) {
+	p, op := p.observe("GenerateRandom", context, "")
+	defer op.end(&err)
+	key := make([]byte, number)
+	reader, err := p.controller.api.NewRandomReader()
+	if err != nil {
+		return nil, err
+	}
+	_, err = reader.Read(key)
+	if err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+func (p HSMCryptoProvider) Hash(parameter types.CryptoHashParameter, msg []byte) (digest []byte, err error) {
+	_, op := p.observe("Hash", parameter.Identifier.CryptoContext, parameter.Identifier.KeyId)
+	defer op.end(&err)
+	// todo use hsm?
+	if parameter.HashAlgorithm == types.Sha2256 {
+		msgHash := sha256.New()
+		_, err := msgHash.Write(msg)
+		if err != nil {
+			return nil, err
+		}
+		msgHashSum := msgHash.Sum(nil)
+		return msgHashSum, nil
+	} else {
+		return nil, errors.ErrUnsupported
+	}
+
+}
+
+// Encrypt encrypts with AES-GCM of the secret key, the random nonce is prepended to the ciphertext.
+func (p HSMCryptoProvider) Encrypt(parameter types.CryptoIdentifier, data []byte) (ciphertext []byte, err error) {
+	p, op := p.observe("Encrypt", parameter.CryptoContext, parameter.KeyId)
+	op.keyType = types.Aes256GCM
+	defer op.end(&err)
+	aead, err := p.gcm(parameter)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := io.ReadFull(p.controller.rand, nonce); err != nil {
+		return nil, err
+	}
+	sealed, err := seal(aead, nonce, data, nil)
+	if err != nil {
+		return nil, err
+	}
+	return append(nonce, sealed...), nil
+}
+
+// Decrypt decrypts the output of Encrypt.
+func (p HSMCryptoProvider) Decrypt(parameter types.CryptoIdentifier, data []byte) (plaintext []byte, err error) {
+	p, op := p.observe("Decrypt", parameter.CryptoContext, parameter.KeyId)
+	op.keyType = types.Aes256GCM
+	defer op.end(&err)
+	aead, err := p.gcm(parameter)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < aead.NonceSize()+aead.Overhead() {
+		return nil, errors.New("ciphertext too short")
+	}
+	return open(aead, data[:aead.NonceSize()], data[aead.NonceSize():], nil)
+}
+func (p HSMCryptoProvider) Sign(parameter types.CryptoIdentifier, data []byte) (signature []byte, err error) {
+	p, op := p.observe("Sign", parameter.CryptoContext, parameter.KeyId)
+	defer op.end(&err)
+	signer, err := p.getSigner(parameter)
+	if err != nil {
+		return nil, err
+	}
+	if signer == nil {
+		return nil, keyNotFound(parameter.KeyId)
+	}
+	op.keyType = keyPairType(signer)
+	opts := p.signatureOptions(signer.Public())
+	digest, err := hashForSignature(data, opts)
+	if err != nil {
+		return nil, err
+	}
+	return signer.Sign(p.controller.rand, digest, opts)
+}
+
+// signatureOptions returns the options of Sign and Verify: Options.SignerOptions if set, otherwise
+// SHA-256 and, for RSA keys, PSS.
+func (p HSMCryptoProvider) signatureOptions(pub crypto.PublicKey) crypto.SignerOpts {
+	if p.controller.signerOptions != nil {
+		return p.controller.signerOptions
+	}
+	if _, ok := pub.(*rsa.PublicKey); ok {
+		return &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash, Hash: crypto.SHA256}
+	}
+	return crypto.SHA256
+}
+
+// hashForSignature hashes the data with the hash of the options, the data is signed as is without one.
+func hashForSignature(data []byte, opts crypto.SignerOpts) ([]byte, error) {
+	hash := opts.HashFunc()
+	if hash == 0 {
+		return data, nil
+	}
+	if !hash.Available() {
+		return nil, fmt.Errorf("hash %s is not available", hash)
+	}
+	h := hash.New()
+	h.Write(data)
+	return h.Sum(nil), nil
+}
+func (p HSMCryptoProvider) GetKeys(parameter types.CryptoFilter) (result *types.CryptoKeySet, err error) {
+	p, op := p.observe("GetKeys", parameter.CryptoContext, parameter.Id)
+	defer op.end(&err)
+	return p.getKeys(parameter)
+}
+
+func (p HSMCryptoProvider) getKeys(parameter types.CryptoFilter) (*types.CryptoKeySet, error) {
+	if parameter.Id != "" {
+		identifier := types.CryptoIdentifier{KeyId: parameter.Id, CryptoContext: parameter.CryptoContext}
+		key, err := p.getKey(identifier)
+		if err != nil {
+			return nil, err
+		}
+		keySet := &types.CryptoKeySet{Keys: []types.CryptoKey{*key}}
+		return keySet, nil
+	}
+	ids, err := p.keyPairIds()
+	if err != nil {
+		return nil, err
+	}
+	keySet := &types.CryptoKeySet{Keys: []types.CryptoKey{}}
+	for _, id := range ids {
+		if parameter.Filter.String() != "" && !parameter.Filter.MatchString(id) {
+			continue
+		}
+		key, err := p.getKey(types.CryptoIdentifier{KeyId: id, CryptoContext: parameter.CryptoContext})
+		if err != nil {
+			return nil, err
+		}
+		keySet.Keys = append(keySet.Keys, *key)
+	}
+	return keySet, nil
+}
+
+// keyPairIds returns the CKA_ID of all key pairs on the partition.
+func (p HSMCryptoProvider) keyPairIds() ([]string, error) {
+	signers, err := p.controller.api.FindAllKeyPairs()
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]string, 0, len(signers))
+	for _, signer := range signers {
+		attribute, err := p.controller.api.GetAttribute(signer, crypto11.CkaId)
+		if err != nil {
+			return nil, err
+		}
+		if attribute != nil {
+			ids = append(ids, string(attribute.Value))
+		}
+	}
+	return ids, nil
+}
+func (p HSMCryptoProvider) GetKey(parameter types.CryptoIdentifier) (key *types.CryptoKey, err error) {
+	p, op := p.observe("GetKey", parameter.CryptoContext, parameter.KeyId)
+	defer op.end(&err)
+	if key, err = p.getKey(parameter); err != nil {
+		return nil, err
+	}
+	op.keyType = key.KeyType
+	return key, nil
+}
+
+func (p HSMCryptoProvider) getKey(parameter types.CryptoIdentifier) (*types.CryptoKey, error) {
+	key, err := p.exportKey(parameter, p.controller.keyFormat)
+	if err != nil {
+		return nil, err
+	}
+	chain, err := p.certificateChain(parameter)
+	if err != nil {
+		return nil, err
+	}
+	if len(chain) > 0 {
+		key.Key, err = attachCertificateChain(key.Key, chain, p.controller.keyFormat)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return key, nil
+}
+func (p HSMCryptoProvider) Verify(parameter types.CryptoIdentifier, data []byte, signature []byte) (valid bool, err error) {
+	p, op := p.observe("Verify", parameter.CryptoContext, parameter.KeyId)
+	defer op.end(&err)
+	signer, err := p.getSigner(parameter)
+	if err != nil {
+		return false, err
+	}
+	if signer == nil {
+		return false, keyNotFound(parameter.KeyId)
+	}
+	op.keyType = keyPairType(signer)
+	pubKeyObj := signer.Public()
+	opts := p.signatureOptions(pubKeyObj)
+	hashed, err := hashForSignature(data, opts)
+	if err != nil {
+		return false, err
+	}
+	if pubKey, ok := pubKeyObj.(*ecdsa.PublicKey); ok {
+		result := ecdsa.VerifyASN1(pubKey, hashed, signature)
+		return result, nil
+	} else if pubKey, ok := pubKeyObj.(*rsa.PublicKey); ok {
+		if pss, ok := opts.(*rsa.PSSOptions); ok {
+			err = rsa.VerifyPSS(pubKey, opts.HashFunc(), hashed, signature, pss)
+		} else {
+			err = rsa.VerifyPKCS1v15(pubKey, opts.HashFunc(), hashed, signature)
+		}
+		if errors.Is(err, rsa.ErrVerification) {
+			// an invalid signature is no error, like for ECDSA
+			return false, nil
+		}
+		return err == nil, err
+	} else if pubKey, ok := pubKeyObj.(SecretKey); ok {
+		return false, fmt.Errorf("keys of type %T are not retrievable", pubKey)
+	} else {
+		return false, fmt.Errorf("key %s has unsupported key format", parameter.KeyId)
+	}
+}
+func (p HSMCryptoProvider) GenerateKey(parameter types.CryptoKeyParameter) (err error) {
+	p, op := p.observe("GenerateKey", parameter.Identifier.CryptoContext, parameter.Identifier.KeyId)
+	defer op.end(&err)
+	if slices.Contains(p.GetSupportedKeysAlgs(), parameter.KeyType) {
+		op.keyType = parameter.KeyType
+	}
+	switch parameter.KeyType {
+	case types.Rsa2048, types.Rsa3072, types.Rsa4096:
+		_, err := p.generateRSA(parameter)
+		return err
+	case types.Ecdsap256, types.Ecdsap384, types.Ecdsap512:
+		_, err := p.generateECDSA(parameter)
+		return err
+	case types.Aes256GCM:
+		_, err := p.generateAES(parameter)
+		return err
+	case types.Ed25519:
+		_, err := p.generateEDDSA(parameter)
+		return err
+	default:
+		return fmt.Errorf("unsupported key type %v: %w", parameter.KeyType, errors.ErrUnsupported)
+	}
+}
+func (p HSMCryptoProvider) GetSeed(context context.Context) string {
+	n := rand.Int()
+	random, err := p.GenerateRandom(types.CryptoContext{Context: context}, n)
+	if err != nil {
+		p.controller.log().ErrorContext(context, "failed generating seed", slog.String("error_class", errorClass(err)), slog.String("error", err.Error()))
+		return ""
+	}
+	return b64.StdEncoding.EncodeToString(random)
+}
+
+func (p HSMCryptoProvider) GetSupportedKeysAlgs() []types.KeyType {
+	// Ed25519 is missing, crypto11 does not implement EdDSA keys
+	return []types.KeyType{types.Ecdsap256, types.Ecdsap384, types.Ecdsap512, types.Aes256GCM, types.Rsa2048, types.Rsa3072, types.Rsa4096}
+}
+
+func (p HSMCryptoProvider) GetSupportedHashAlgs() []types.HashAlgorithm {
+	return []types.HashAlgorithm{types.Sha2256}
+}
+
+func (p HSMCryptoProvider) IsCryptoContextExisting(context types.CryptoContext) (bool, error) {
+	return true, nil
+}
+
+func (p HSMCryptoProvider) IsKeyExisting(parameter types.CryptoIdentifier) (existing bool, err error) {
+	p, op := p.observe("IsKeyExisting", parameter.CryptoContext, parameter.KeyId)
+	defer op.end(&err)
+	signer, err := p.getSigner(parameter)
+	if err != nil {
+		return false, err
+	}
+	if signer != nil {
+		op.keyType = keyPairType(signer)
+		return true, nil
+	}
+	key, err := p.controller.api.FindKey([]byte(parameter.KeyId), nil)
+	if err != nil {
+		return false, err
+	}
+	if key != nil {
+		op.keyType = types.Aes256GCM
+	}
+	return key != nil, nil
+}
+
+func (p HSMCryptoProvider) RotateKey(parameter types.CryptoIdentifier) (err error) {
+	_, op := p.observe("RotateKey", parameter.CryptoContext, parameter.KeyId)
+	defer op.end(&err)
+	return errors.ErrUnsupported
+}
@@ -0,0 +1,116 @@
+package hsm
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"testing"
+
+	"github.com/ThalesIgnite/crypto11"
+	"github.com/eclipse-xfsc/crypto-provider-core/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+var testId = "test id"
+
+func getTestHSMCryptoProvider(mock *ContextTypeMock) HSMCryptoProvider {
+	return HSMCryptoProvider{
+		controller: &hsmController{
+			config: &crypto11.Config{},
+			rand:   rand.Reader,
+			api:    mock,
+		},
+	}
+}
+
+func TestHSMCryptoProvider_GenerateKey(t *testing.T) {
+	var mockApi = new(ContextTypeMock)
+	provider := getTestHSMCryptoProvider(mockApi)
+	param := types.CryptoKeyParameter{KeyType: types.Ecdsap256, Identifier: types.CryptoIdentifier{KeyId: testId}}
+	derive := mock.MatchedBy(func(private crypto11.AttributeSet) bool {
+		return private[crypto11.CkaDerive] != nil && string(private[crypto11.CkaId].Value) == testId
+	})
+	mockApi.On("GenerateECDSAKeyPairWithAttributes", mock.Anything, derive, elliptic.P256()).Return(&SignerMock{}, nil)
+	_ = provider.GenerateKey(param)
+	mockApi.AssertExpectations(t)
+}
+
+func TestHSMCryptoProvider_GetKey(t *testing.T) {
+	key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	der, _ := x509.MarshalPKIXPublicKey(key.Public())
+	expected := pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})
+	var mockApi = new(ContextTypeMock).WithoutCertificates()
+	mockApi.On("FindKeyPair", []byte(testId), []byte(nil)).Return(&SignerMock{public: key.Public()}, nil)
+	provider := getTestHSMCryptoProvider(mockApi)
+	actual, _ := provider.GetKey(types.CryptoIdentifier{KeyId: testId})
+	assert.Equal(t, expected, actual.Key)
+	assert.Equal(t, types.Ecdsap256, actual.CryptoKeyParameter.KeyType)
+}
+
+func TestHSMCryptoProvider_SecretKeys(t *testing.T) {
+	block, _ := aes.NewCipher(make([]byte, 32))
+	gcm, _ := cipher.NewGCM(block)
+	key := new(SecretKeyMock)
+	key.On("NewGCM").Return(gcm, nil)
+	key.On("Delete").Return(nil).Once()
+	var mockApi = new(ContextTypeMock).WithoutCertificates()
+	mockApi.On("FindKeyPair", []byte(testId), []byte(nil)).Return(nil, nil)
+	mockApi.On("FindKey", []byte(testId), []byte(nil)).Return(key, nil)
+	provider := getTestHSMCryptoProvider(mockApi)
+	identifier := types.CryptoIdentifier{KeyId: testId}
+
+	ciphertext, err := provider.Encrypt(identifier, []byte("data"))
+	assert.Nil(t, err)
+	plaintext, err := provider.Decrypt(identifier, ciphertext)
+	assert.Nil(t, err)
+	assert.Equal(t, []byte("data"), plaintext)
+	aead, err := provider.AEAD(identifier)
+	assert.Nil(t, err)
+	nonce := make([]byte, aead.NonceSize())
+	opened, err := aead.Open(nil, nonce, aead.Seal(nil, nonce, []byte("secret"), nil), nil)
+	assert.Nil(t, err)
+	assert.Equal(t, []byte("secret"), opened)
+	assert.Nil(t, provider.DeleteKey(identifier))
+	key.AssertExpectations(t)
+
+	mockApi = new(ContextTypeMock).WithoutCertificates()
+	mockApi.On("FindKeyPair", []byte(testId), []byte(nil)).Return(nil, nil)
+	mockApi.On("FindKey", []byte(testId), []byte(nil)).Return(nil, nil)
+	provider = getTestHSMCryptoProvider(mockApi)
+	_, err = provider.AEAD(identifier)
+	assert.EqualError(t, err, "key test id not found")
+	assert.EqualError(t, provider.DeleteKey(identifier), "key test id not found")
+	_, err = provider.Encrypt(identifier, []byte("data"))
+	assert.ErrorIs(t, err, ErrKeyNotFound)
+	_, err = provider.Sign(identifier, []byte("data"))
+	assert.ErrorIs(t, err, ErrKeyNotFound)
+	_, err = provider.Verify(identifier, []byte("data"), []byte("signature"))
+	assert.ErrorIs(t, err, ErrKeyNotFound)
+}
+
+func TestHSMCryptoProvider_EncryptDecrypt(t *testing.T) {
+	provider, err := New(Options{Backend: DevBackend})
+	assert.Nil(t, err)
+	identifier := types.CryptoIdentifier{KeyId: testId}
+	assert.Nil(t, provider.GenerateKey(types.CryptoKeyParameter{Identifier: identifier, KeyType: types.Aes256GCM}))
+
+	first, err := provider.Encrypt(identifier, []byte("data"))
+	assert.Nil(t, err)
+	second, err := provider.Encrypt(identifier, []byte("data"))
+	assert.Nil(t, err)
+	assert.NotEqual(t, first, second)
+	plaintext, err := provider.Decrypt(identifier, first)
+	assert.Nil(t, err)
+	assert.Equal(t, []byte("data"), plaintext)
+
+	first[len(first)-1] ^= 1
+	_, err = provider.Decrypt(identifier, first)
+	assert.NotNil(t, err)
+	_, err = provider.Decrypt(identifier, first[:12])
+	assert.EqualError(t, err, "ciphertext too short")
+}
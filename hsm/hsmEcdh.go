@@ -0,0 +1,147 @@
+package hsm
+
+import (
+	"crypto/ecdsa"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/ThalesIgnite/crypto11"
+	"github.com/miekg/pkcs11"
+)
+
+// ecdhDeriver derives ECDH shared secrets with private keys which never leave the token.
+// crypto11 offers no key derivation, so this is done with plain PKCS#11 calls.
+type ecdhDeriver interface {
+	// DeriveECDH returns the raw shared secret Z of the private key with CKA_ID id and the peer public key.
+	DeriveECDH(id []byte, peer *ecdsa.PublicKey) ([]byte, error)
+}
+
+// pkcs11Deriver keeps one logged in session for the derivations. crypto11 neither exposes its
+// sessions nor its module handle, so the deriver opens its own on the same library; the login state is
+// shared with the crypto11 sessions of this process.
+type pkcs11Deriver struct {
+	ctx     *pkcs11.Ctx
+	slot    uint
+	pin     string
+	mutex   sync.Mutex
+	session pkcs11.SessionHandle
+	open    bool
+	// opened is set once the first session was opened, later ones are reconnects.
+	opened  bool
+	metrics Metrics
+}
+
+func newPkcs11Deriver(config *crypto11.Config, metrics Metrics) (*pkcs11Deriver, error) {
+	ctx := pkcs11.New(config.Path)
+	if ctx == nil {
+		return nil, fmt.Errorf("could not load PKCS#11 library %s", config.Path)
+	}
+	// crypto11 initialized the library already in this process
+	if err := ctx.Initialize(); err != nil && !isPkcs11Error(err, pkcs11.CKR_CRYPTOKI_ALREADY_INITIALIZED) {
+		return nil, err
+	}
+	slots, err := ctx.GetSlotList(true)
+	if err != nil {
+		return nil, err
+	}
+	for _, slot := range slots {
+		info, err := ctx.GetTokenInfo(slot)
+		if err != nil {
+			return nil, err
+		}
+		if info.Label == config.TokenLabel {
+			return &pkcs11Deriver{ctx: ctx, slot: slot, pin: config.Pin, metrics: metrics}, nil
+		}
+	}
+	return nil, fmt.Errorf("token %s not found", config.TokenLabel)
+}
+
+func isPkcs11Error(err error, code uint) bool {
+	var p11Err pkcs11.Error
+	return errors.As(err, &p11Err) && uint(p11Err) == code
+}
+
+// openSession returns the session of the deriver, it is opened and logged in on first use and after
+// it became invalid.
+func (d *pkcs11Deriver) openSession() (pkcs11.SessionHandle, error) {
+	if d.open {
+		return d.session, nil
+	}
+	session, err := d.ctx.OpenSession(d.slot, pkcs11.CKF_SERIAL_SESSION|pkcs11.CKF_RW_SESSION)
+	if err != nil {
+		return 0, err
+	}
+	if err := d.ctx.Login(session, pkcs11.CKU_USER, d.pin); err != nil && !isPkcs11Error(err, pkcs11.CKR_USER_ALREADY_LOGGED_IN) {
+		_ = d.ctx.CloseSession(session)
+		return 0, err
+	}
+	if d.opened {
+		d.metrics.Connected()
+	}
+	d.session, d.open, d.opened = session, true, true
+	return session, nil
+}
+
+func (d *pkcs11Deriver) DeriveECDH(id []byte, peer *ecdsa.PublicKey) ([]byte, error) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	session, err := d.openSession()
+	if err != nil {
+		return nil, err
+	}
+	z, err := d.derive(session, id, peer)
+	if isPkcs11Error(err, pkcs11.CKR_SESSION_HANDLE_INVALID) || isPkcs11Error(err, pkcs11.CKR_SESSION_CLOSED) || isPkcs11Error(err, pkcs11.CKR_USER_NOT_LOGGED_IN) {
+		_ = d.ctx.CloseSession(session)
+		d.open = false
+	}
+	return z, err
+}
+
+func (d *pkcs11Deriver) derive(session pkcs11.SessionHandle, id []byte, peer *ecdsa.PublicKey) ([]byte, error) {
+	template := []*pkcs11.Attribute{
+		pkcs11.NewAttribute(pkcs11.CKA_CLASS, pkcs11.CKO_PRIVATE_KEY),
+		pkcs11.NewAttribute(pkcs11.CKA_KEY_TYPE, pkcs11.CKK_EC),
+		pkcs11.NewAttribute(pkcs11.CKA_ID, id),
+	}
+	if err := d.ctx.FindObjectsInit(session, template); err != nil {
+		return nil, err
+	}
+	handles, _, err := d.ctx.FindObjects(session, 1)
+	finalErr := d.ctx.FindObjectsFinal(session)
+	if err != nil {
+		return nil, err
+	}
+	if finalErr != nil {
+		return nil, finalErr
+	}
+	if len(handles) == 0 {
+		return nil, fmt.Errorf("ec private key %s not found", id)
+	}
+
+	peerKey, err := peer.ECDH()
+	if err != nil {
+		return nil, err
+	}
+	params := pkcs11.NewECDH1DeriveParams(pkcs11.CKD_NULL, nil, peerKey.Bytes())
+	mechanism := []*pkcs11.Mechanism{pkcs11.NewMechanism(pkcs11.CKM_ECDH1_DERIVE, params)}
+	secretTemplate := []*pkcs11.Attribute{
+		pkcs11.NewAttribute(pkcs11.CKA_CLASS, pkcs11.CKO_SECRET_KEY),
+		pkcs11.NewAttribute(pkcs11.CKA_KEY_TYPE, pkcs11.CKK_GENERIC_SECRET),
+		pkcs11.NewAttribute(pkcs11.CKA_TOKEN, false),
+		pkcs11.NewAttribute(pkcs11.CKA_SENSITIVE, false),
+		pkcs11.NewAttribute(pkcs11.CKA_EXTRACTABLE, true),
+		pkcs11.NewAttribute(pkcs11.CKA_VALUE_LEN, (peer.Curve.Params().BitSize+7)/8),
+	}
+	secret, err := d.ctx.DeriveKey(session, mechanism, handles[0], secretTemplate)
+	if err != nil {
+		return nil, err
+	}
+	defer d.ctx.DestroyObject(session, secret)
+
+	value, err := d.ctx.GetAttributeValue(session, secret, []*pkcs11.Attribute{pkcs11.NewAttribute(pkcs11.CKA_VALUE, nil)})
+	if err != nil {
+		return nil, err
+	}
+	return value[0].Value, nil
+}
@@ -0,0 +1,534 @@
+package hsm
+
+import (
+	"crypto"
+	"crypto/cipher"
+	"crypto/elliptic"
+	"crypto/x509"
+	"io"
+	"math/big"
+	"sync"
+
+	"github.com/ThalesIgnite/crypto11"
+)
+
+// Call is a call to the partition passed to an Interceptor.
+type Call struct {
+	// Method of ContextType, Sign, Decrypt and Delete of key pairs, NewGCM, NewCBCEncrypter,
+	// NewCBCDecrypter and Delete of secret keys, Seal and Open of AES-GCM or GenerateRandom for reads of
+	// the random reader.
+	Method string
+	// Key is the key pair or secret key of the operation, nil for methods of ContextType.
+	Key interface{}
+	// Args are the arguments of the call without the key.
+	Args []interface{}
+	// Results are set by the call, keys are the ones of the InterceptorContext.
+	Results []interface{}
+}
+
+// Interceptor intercepts a call to the partition. It runs the call with invoke and returns its error, it
+// may as well fail the call without running it or replace its error.
+type Interceptor func(call *Call, invoke func() error) error
+
+// InterceptorContext is a ContextType decorator which passes every call to the partition through an
+// Interceptor, including the operations of the keys, AEADs and random readers it returns.
+type InterceptorContext struct {
+	api       ContextType
+	intercept Interceptor
+	mutex     sync.Mutex
+	handles   int
+}
+
+// interceptedKey is implemented by the keys of an InterceptorContext.
+type interceptedKey interface {
+	unwrap() interface{}
+	interceptor() *InterceptorContext
+}
+
+type interceptedKeyPair struct {
+	crypto11.Signer
+	context *InterceptorContext
+	handle  int
+}
+
+type interceptedRSAKeyPair struct {
+	interceptedKeyPair
+}
+
+type interceptedSecretKey struct {
+	SecretKey
+	context *InterceptorContext
+	handle  int
+}
+
+// interceptedAEAD intercepts Seal and Open, which are calls to the partition for keys of crypto11.
+type interceptedAEAD struct {
+	cipher.AEAD
+	key interceptedSecretKey
+}
+
+// interceptedBlockMode intercepts CryptBlocks of CBC as CBCEncrypt or CBCDecrypt.
+type interceptedBlockMode struct {
+	cipher.BlockMode
+	key    interceptedSecretKey
+	method string
+}
+
+// interceptedReader intercepts reads of random numbers, GenerateRandom of the partition for crypto11.
+type interceptedReader struct {
+	io.Reader
+	context *InterceptorContext
+}
+
+// NewInterceptorContext decorates the context with the interceptor.
+func NewInterceptorContext(api ContextType, intercept Interceptor) *InterceptorContext {
+	return &InterceptorContext{api: api, intercept: intercept}
+}
+
+// call passes a call of the method with the arguments through the interceptor, invoke runs it and returns
+// its results.
+func (c *InterceptorContext) call(method string, key interface{}, args []interface{}, invoke func() ([]interface{}, error)) ([]interface{}, error) {
+	call := &Call{Method: method, Key: key, Args: args}
+	err := c.intercept(call, func() error {
+		var err error
+		call.Results, err = invoke()
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return call.Results, nil
+}
+
+// handle numbers the keys of the context, see keyHandle.
+func (c *InterceptorContext) handle() int {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.handles++
+	return c.handles
+}
+
+func (c *InterceptorContext) wrap(signer crypto11.Signer) crypto11.Signer {
+	if signer == nil {
+		return nil
+	}
+	keyPair := interceptedKeyPair{Signer: signer, context: c, handle: c.handle()}
+	if _, ok := signer.(crypto.Decrypter); ok {
+		return interceptedRSAKeyPair{keyPair}
+	}
+	return keyPair
+}
+
+func (c *InterceptorContext) wrapAll(signers []crypto11.Signer) []crypto11.Signer {
+	for i, signer := range signers {
+		signers[i] = c.wrap(signer)
+	}
+	return signers
+}
+
+func (c *InterceptorContext) wrapKey(key SecretKey) SecretKey {
+	if key == nil {
+		return nil
+	}
+	return interceptedSecretKey{SecretKey: key, context: c, handle: c.handle()}
+}
+
+func (c *InterceptorContext) wrapKeys(keys []SecretKey) []SecretKey {
+	for i, key := range keys {
+		keys[i] = c.wrapKey(key)
+	}
+	return keys
+}
+
+// own returns the key of the context for a key of a decorator of it and the key of the decorated context.
+// Keys of other contexts are passed on without their decorators.
+func (c *InterceptorContext) own(key interface{}) (interface{}, interface{}) {
+	for wrapped := key; ; {
+		wrapper, ok := wrapped.(interceptedKey)
+		if !ok {
+			return key, unwrap(key)
+		}
+		if wrapper.interceptor() == c {
+			return wrapper, wrapper.unwrap()
+		}
+		wrapped = wrapper.unwrap()
+	}
+}
+
+// unwrap returns the key of the decorated context for keys of decorators, the context needs its own
+// keys for attributes.
+func unwrap(key interface{}) interface{} {
+	for {
+		wrapper, ok := key.(interface{ unwrap() interface{} })
+		if !ok {
+			return key
+		}
+		key = wrapper.unwrap()
+	}
+}
+
+// results converts the results of a call, which are nil if it failed.
+func results[T any](values []interface{}, err error) (T, error) {
+	var result T
+	if err != nil || len(values) == 0 || values[0] == nil {
+		return result, err
+	}
+	return values[0].(T), nil
+}
+
+func (k interceptedKeyPair) unwrap() interface{} {
+	return k.Signer
+}
+
+func (k interceptedKeyPair) interceptor() *InterceptorContext {
+	return k.context
+}
+
+func (k interceptedKeyPair) keyHandle() int {
+	return k.handle
+}
+
+func (k interceptedSecretKey) unwrap() interface{} {
+	return k.SecretKey
+}
+
+func (k interceptedSecretKey) interceptor() *InterceptorContext {
+	return k.context
+}
+
+func (k interceptedSecretKey) keyHandle() int {
+	return k.handle
+}
+
+func (k interceptedKeyPair) Sign(rand io.Reader, digest []byte, opts crypto.SignerOpts) ([]byte, error) {
+	values, err := k.context.call("Sign", k, []interface{}{digest, opts}, func() ([]interface{}, error) {
+		signature, err := k.Signer.Sign(rand, digest, opts)
+		return []interface{}{signature}, err
+	})
+	return results[[]byte](values, err)
+}
+
+func (k interceptedKeyPair) Delete() error {
+	_, err := k.context.call("Delete", k, nil, func() ([]interface{}, error) {
+		return nil, k.Signer.Delete()
+	})
+	return err
+}
+
+func (k interceptedRSAKeyPair) Decrypt(rand io.Reader, msg []byte, opts crypto.DecrypterOpts) ([]byte, error) {
+	values, err := k.context.call("Decrypt", k, []interface{}{msg, opts}, func() ([]interface{}, error) {
+		plaintext, err := k.Signer.(crypto.Decrypter).Decrypt(rand, msg, opts)
+		return []interface{}{plaintext}, err
+	})
+	return results[[]byte](values, err)
+}
+
+func (k interceptedSecretKey) NewGCM() (cipher.AEAD, error) {
+	values, err := k.context.call("NewGCM", k, nil, func() ([]interface{}, error) {
+		aead, err := k.SecretKey.NewGCM()
+		if err != nil {
+			return nil, err
+		}
+		return []interface{}{interceptedAEAD{AEAD: aead, key: k}}, nil
+	})
+	return results[cipher.AEAD](values, err)
+}
+
+func (k interceptedSecretKey) NewCBCEncrypter(iv []byte) (cipher.BlockMode, error) {
+	values, err := k.context.call("NewCBCEncrypter", k, []interface{}{iv}, func() ([]interface{}, error) {
+		mode, err := k.SecretKey.NewCBCEncrypter(iv)
+		if err != nil {
+			return nil, err
+		}
+		return []interface{}{interceptedBlockMode{BlockMode: mode, key: k, method: "CBCEncrypt"}}, nil
+	})
+	return results[cipher.BlockMode](values, err)
+}
+
+func (k interceptedSecretKey) NewCBCDecrypter(iv []byte) (cipher.BlockMode, error) {
+	values, err := k.context.call("NewCBCDecrypter", k, []interface{}{iv}, func() ([]interface{}, error) {
+		mode, err := k.SecretKey.NewCBCDecrypter(iv)
+		if err != nil {
+			return nil, err
+		}
+		return []interface{}{interceptedBlockMode{BlockMode: mode, key: k, method: "CBCDecrypt"}}, nil
+	})
+	return results[cipher.BlockMode](values, err)
+}
+
+func (k interceptedSecretKey) Delete() error {
+	_, err := k.context.call("Delete", k, nil, func() ([]interface{}, error) {
+		return nil, k.SecretKey.Delete()
+	})
+	return err
+}
+
+// Seal panics if the interceptor fails it, like Seal of crypto11 if the partition fails.
+func (a interceptedAEAD) Seal(dst, nonce, plaintext, additionalData []byte) []byte {
+	values, err := a.key.context.call("Seal", a.key, []interface{}{nonce, plaintext, additionalData}, func() ([]interface{}, error) {
+		return []interface{}{a.AEAD.Seal(dst, nonce, plaintext, additionalData)}, nil
+	})
+	if err != nil {
+		panic(err)
+	}
+	return values[0].([]byte)
+}
+
+func (a interceptedAEAD) Open(dst, nonce, ciphertext, additionalData []byte) ([]byte, error) {
+	values, err := a.key.context.call("Open", a.key, []interface{}{nonce, ciphertext, additionalData}, func() ([]interface{}, error) {
+		plaintext, err := a.AEAD.Open(dst, nonce, ciphertext, additionalData)
+		return []interface{}{plaintext}, err
+	})
+	return results[[]byte](values, err)
+}
+
+// CryptBlocks panics if the interceptor fails it, as cipher.BlockMode has no error result.
+func (m interceptedBlockMode) CryptBlocks(dst, src []byte) {
+	_, err := m.key.context.call(m.method, m.key, []interface{}{src}, func() ([]interface{}, error) {
+		m.BlockMode.CryptBlocks(dst, src)
+		return []interface{}{dst[:len(src)]}, nil
+	})
+	if err != nil {
+		panic(err)
+	}
+}
+
+func (r interceptedReader) Read(p []byte) (int, error) {
+	n := 0
+	_, err := r.context.call("GenerateRandom", nil, []interface{}{len(p)}, func() ([]interface{}, error) {
+		var err error
+		n, err = r.Reader.Read(p)
+		return []interface{}{n}, err
+	})
+	return n, err
+}
+
+func (c *InterceptorContext) GenerateRSAKeyPair(id []byte, bits int) (crypto11.SignerDecrypter, error) {
+	values, err := c.call("GenerateRSAKeyPair", nil, []interface{}{id, bits}, func() ([]interface{}, error) {
+		signer, err := c.api.GenerateRSAKeyPair(id, bits)
+		if err != nil {
+			return nil, err
+		}
+		return []interface{}{c.wrap(signer)}, nil
+	})
+	return results[crypto11.SignerDecrypter](values, err)
+}
+
+func (c *InterceptorContext) GenerateRSAKeyPairWithLabel(id, label []byte, bits int) (crypto11.SignerDecrypter, error) {
+	values, err := c.call("GenerateRSAKeyPairWithLabel", nil, []interface{}{id, label, bits}, func() ([]interface{}, error) {
+		signer, err := c.api.GenerateRSAKeyPairWithLabel(id, label, bits)
+		if err != nil {
+			return nil, err
+		}
+		return []interface{}{c.wrap(signer)}, nil
+	})
+	return results[crypto11.SignerDecrypter](values, err)
+}
+
+func (c *InterceptorContext) GenerateRSAKeyPairWithAttributes(public, private crypto11.AttributeSet, bits int) (crypto11.SignerDecrypter, error) {
+	values, err := c.call("GenerateRSAKeyPairWithAttributes", nil, []interface{}{public, private, bits}, func() ([]interface{}, error) {
+		signer, err := c.api.GenerateRSAKeyPairWithAttributes(public, private, bits)
+		if err != nil {
+			return nil, err
+		}
+		return []interface{}{c.wrap(signer)}, nil
+	})
+	return results[crypto11.SignerDecrypter](values, err)
+}
+
+func (c *InterceptorContext) FindKeyPair(id []byte, label []byte) (crypto11.Signer, error) {
+	values, err := c.call("FindKeyPair", nil, []interface{}{id, label}, func() ([]interface{}, error) {
+		signer, err := c.api.FindKeyPair(id, label)
+		return []interface{}{c.wrap(signer)}, err
+	})
+	return results[crypto11.Signer](values, err)
+}
+
+func (c *InterceptorContext) FindKeyPairs(id []byte, label []byte) ([]crypto11.Signer, error) {
+	values, err := c.call("FindKeyPairs", nil, []interface{}{id, label}, func() ([]interface{}, error) {
+		signers, err := c.api.FindKeyPairs(id, label)
+		return []interface{}{c.wrapAll(signers)}, err
+	})
+	return results[[]crypto11.Signer](values, err)
+}
+
+func (c *InterceptorContext) FindKeyPairWithAttributes(attributes crypto11.AttributeSet) (crypto11.Signer, error) {
+	values, err := c.call("FindKeyPairWithAttributes", nil, []interface{}{attributes}, func() ([]interface{}, error) {
+		signer, err := c.api.FindKeyPairWithAttributes(attributes)
+		return []interface{}{c.wrap(signer)}, err
+	})
+	return results[crypto11.Signer](values, err)
+}
+
+func (c *InterceptorContext) FindKeyPairsWithAttributes(attributes crypto11.AttributeSet) ([]crypto11.Signer, error) {
+	values, err := c.call("FindKeyPairsWithAttributes", nil, []interface{}{attributes}, func() ([]interface{}, error) {
+		signers, err := c.api.FindKeyPairsWithAttributes(attributes)
+		return []interface{}{c.wrapAll(signers)}, err
+	})
+	return results[[]crypto11.Signer](values, err)
+}
+
+func (c *InterceptorContext) FindAllKeyPairs() ([]crypto11.Signer, error) {
+	values, err := c.call("FindAllKeyPairs", nil, nil, func() ([]interface{}, error) {
+		signers, err := c.api.FindAllKeyPairs()
+		return []interface{}{c.wrapAll(signers)}, err
+	})
+	return results[[]crypto11.Signer](values, err)
+}
+
+func (c *InterceptorContext) FindKey(id []byte, label []byte) (SecretKey, error) {
+	values, err := c.call("FindKey", nil, []interface{}{id, label}, func() ([]interface{}, error) {
+		key, err := c.api.FindKey(id, label)
+		return []interface{}{c.wrapKey(key)}, err
+	})
+	return results[SecretKey](values, err)
+}
+
+func (c *InterceptorContext) FindKeys(id []byte, label []byte) ([]SecretKey, error) {
+	values, err := c.call("FindKeys", nil, []interface{}{id, label}, func() ([]interface{}, error) {
+		keys, err := c.api.FindKeys(id, label)
+		return []interface{}{c.wrapKeys(keys)}, err
+	})
+	return results[[]SecretKey](values, err)
+}
+
+func (c *InterceptorContext) FindKeyWithAttributes(attributes crypto11.AttributeSet) (SecretKey, error) {
+	values, err := c.call("FindKeyWithAttributes", nil, []interface{}{attributes}, func() ([]interface{}, error) {
+		key, err := c.api.FindKeyWithAttributes(attributes)
+		return []interface{}{c.wrapKey(key)}, err
+	})
+	return results[SecretKey](values, err)
+}
+
+func (c *InterceptorContext) FindKeysWithAttributes(attributes crypto11.AttributeSet) ([]SecretKey, error) {
+	values, err := c.call("FindKeysWithAttributes", nil, []interface{}{attributes}, func() ([]interface{}, error) {
+		keys, err := c.api.FindKeysWithAttributes(attributes)
+		return []interface{}{c.wrapKeys(keys)}, err
+	})
+	return results[[]SecretKey](values, err)
+}
+
+func (c *InterceptorContext) FindAllKeys() ([]SecretKey, error) {
+	values, err := c.call("FindAllKeys", nil, nil, func() ([]interface{}, error) {
+		keys, err := c.api.FindAllKeys()
+		return []interface{}{c.wrapKeys(keys)}, err
+	})
+	return results[[]SecretKey](values, err)
+}
+
+func (c *InterceptorContext) GetAttributes(key interface{}, attributes []crypto11.AttributeType) (crypto11.AttributeSet, error) {
+	key, inner := c.own(key)
+	values, err := c.call("GetAttributes", nil, []interface{}{key, attributes}, func() ([]interface{}, error) {
+		set, err := c.api.GetAttributes(inner, attributes)
+		return []interface{}{set}, err
+	})
+	return results[crypto11.AttributeSet](values, err)
+}
+
+func (c *InterceptorContext) GetAttribute(key interface{}, attribute crypto11.AttributeType) (*crypto11.Attribute, error) {
+	key, inner := c.own(key)
+	values, err := c.call("GetAttribute", nil, []interface{}{key, attribute}, func() ([]interface{}, error) {
+		value, err := c.api.GetAttribute(inner, attribute)
+		return []interface{}{value}, err
+	})
+	return results[*crypto11.Attribute](values, err)
+}
+
+func (c *InterceptorContext) GetPubAttributes(key interface{}, attributes []crypto11.AttributeType) (crypto11.AttributeSet, error) {
+	key, inner := c.own(key)
+	values, err := c.call("GetPubAttributes", nil, []interface{}{key, attributes}, func() ([]interface{}, error) {
+		set, err := c.api.GetPubAttributes(inner, attributes)
+		return []interface{}{set}, err
+	})
+	return results[crypto11.AttributeSet](values, err)
+}
+
+func (c *InterceptorContext) GetPubAttribute(key interface{}, attribute crypto11.AttributeType) (*crypto11.Attribute, error) {
+	key, inner := c.own(key)
+	values, err := c.call("GetPubAttribute", nil, []interface{}{key, attribute}, func() ([]interface{}, error) {
+		value, err := c.api.GetPubAttribute(inner, attribute)
+		return []interface{}{value}, err
+	})
+	return results[*crypto11.Attribute](values, err)
+}
+
+func (c *InterceptorContext) GenerateECDSAKeyPair(id []byte, curve elliptic.Curve) (crypto11.Signer, error) {
+	values, err := c.call("GenerateECDSAKeyPair", nil, []interface{}{id, curve}, func() ([]interface{}, error) {
+		signer, err := c.api.GenerateECDSAKeyPair(id, curve)
+		return []interface{}{c.wrap(signer)}, err
+	})
+	return results[crypto11.Signer](values, err)
+}
+
+func (c *InterceptorContext) GenerateECDSAKeyPairWithLabel(id, label []byte, curve elliptic.Curve) (crypto11.Signer, error) {
+	values, err := c.call("GenerateECDSAKeyPairWithLabel", nil, []interface{}{id, label, curve}, func() ([]interface{}, error) {
+		signer, err := c.api.GenerateECDSAKeyPairWithLabel(id, label, curve)
+		return []interface{}{c.wrap(signer)}, err
+	})
+	return results[crypto11.Signer](values, err)
+}
+
+func (c *InterceptorContext) GenerateECDSAKeyPairWithAttributes(public, private crypto11.AttributeSet, curve elliptic.Curve) (crypto11.Signer, error) {
+	values, err := c.call("GenerateECDSAKeyPairWithAttributes", nil, []interface{}{public, private, curve}, func() ([]interface{}, error) {
+		signer, err := c.api.GenerateECDSAKeyPairWithAttributes(public, private, curve)
+		return []interface{}{c.wrap(signer)}, err
+	})
+	return results[crypto11.Signer](values, err)
+}
+
+func (c *InterceptorContext) GenerateSecretKey(id []byte, bits int, cipher *crypto11.SymmetricCipher) (SecretKey, error) {
+	values, err := c.call("GenerateSecretKey", nil, []interface{}{id, bits, cipher}, func() ([]interface{}, error) {
+		key, err := c.api.GenerateSecretKey(id, bits, cipher)
+		return []interface{}{c.wrapKey(key)}, err
+	})
+	return results[SecretKey](values, err)
+}
+
+func (c *InterceptorContext) GenerateSecretKeyWithLabel(id, label []byte, bits int, cipher *crypto11.SymmetricCipher) (SecretKey, error) {
+	values, err := c.call("GenerateSecretKeyWithLabel", nil, []interface{}{id, label, bits, cipher}, func() ([]interface{}, error) {
+		key, err := c.api.GenerateSecretKeyWithLabel(id, label, bits, cipher)
+		return []interface{}{c.wrapKey(key)}, err
+	})
+	return results[SecretKey](values, err)
+}
+
+func (c *InterceptorContext) GenerateSecretKeyWithAttributes(template crypto11.AttributeSet, bits int, cipher *crypto11.SymmetricCipher) (SecretKey, error) {
+	values, err := c.call("GenerateSecretKeyWithAttributes", nil, []interface{}{template, bits, cipher}, func() ([]interface{}, error) {
+		key, err := c.api.GenerateSecretKeyWithAttributes(template, bits, cipher)
+		return []interface{}{c.wrapKey(key)}, err
+	})
+	return results[SecretKey](values, err)
+}
+
+func (c *InterceptorContext) NewRandomReader() (io.Reader, error) {
+	values, err := c.call("NewRandomReader", nil, nil, func() ([]interface{}, error) {
+		reader, err := c.api.NewRandomReader()
+		if err != nil {
+			return nil, err
+		}
+		return []interface{}{interceptedReader{Reader: reader, context: c}}, nil
+	})
+	return results[io.Reader](values, err)
+}
+
+func (c *InterceptorContext) ImportCertificateWithLabel(id []byte, label []byte, certificate *x509.Certificate) error {
+	_, err := c.call("ImportCertificateWithLabel", nil, []interface{}{id, label, certificate}, func() ([]interface{}, error) {
+		return nil, c.api.ImportCertificateWithLabel(id, label, certificate)
+	})
+	return err
+}
+
+func (c *InterceptorContext) FindCertificate(id []byte, label []byte, serial *big.Int) (*x509.Certificate, error) {
+	values, err := c.call("FindCertificate", nil, []interface{}{id, label, serial}, func() ([]interface{}, error) {
+		certificate, err := c.api.FindCertificate(id, label, serial)
+		return []interface{}{certificate}, err
+	})
+	return results[*x509.Certificate](values, err)
+}
+
+func (c *InterceptorContext) DeleteCertificate(id []byte, label []byte, serial *big.Int) error {
+	_, err := c.call("DeleteCertificate", nil, []interface{}{id, label, serial}, func() ([]interface{}, error) {
+		return nil, c.api.DeleteCertificate(id, label, serial)
+	})
+	return err
+}
@@ -0,0 +1,68 @@
+package hsm
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/elliptic"
+	"crypto/rand"
+	"errors"
+	"testing"
+
+	"github.com/ThalesIgnite/crypto11"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInterceptorContext(t *testing.T) {
+	var calls []*Call
+	interceptor := NewInterceptorContext(NewMemoryContext(), func(call *Call, invoke func() error) error {
+		calls = append(calls, call)
+		return invoke()
+	})
+	var _ ContextType = interceptor
+
+	signer, err := interceptor.GenerateECDSAKeyPair([]byte(testId), elliptic.P256())
+	assert.Nil(t, err)
+	_, err = signer.Sign(rand.Reader, make([]byte, 32), crypto.SHA256)
+	assert.Nil(t, err)
+	assert.Nil(t, signer.Delete())
+	assert.Len(t, calls, 3)
+	assert.Equal(t, "GenerateECDSAKeyPair", calls[0].Method)
+	assert.Equal(t, []interface{}{signer}, calls[0].Results)
+	assert.Equal(t, "Sign", calls[1].Method)
+	assert.Equal(t, signer, calls[1].Key)
+	assert.Equal(t, "Delete", calls[2].Method)
+}
+
+func TestInterceptorContext_Errors(t *testing.T) {
+	failure := errors.New("intercepted")
+	interceptor := NewInterceptorContext(NewMemoryContext(), func(call *Call, invoke func() error) error {
+		if call.Method == "Seal" || call.Method == "FindKey" {
+			return failure
+		}
+		return invoke()
+	})
+	key, err := interceptor.GenerateSecretKey([]byte(testId), 256, crypto11.CipherAES)
+	assert.Nil(t, err)
+	found, err := interceptor.FindKey([]byte(testId), nil)
+	assert.Equal(t, failure, err)
+	assert.Nil(t, found)
+
+	aead, err := key.NewGCM()
+	assert.Nil(t, err)
+	assert.PanicsWithValue(t, failure, func() {
+		aead.Seal(nil, make([]byte, aead.NonceSize()), []byte("secret"), nil)
+	})
+}
+
+func TestInterceptorContext_Stacked(t *testing.T) {
+	var recording bytes.Buffer
+	recorder := NewRecordingContext(NewMemoryContext(), &recording)
+	metrics := NewMetricsContext(recorder, noMetrics{})
+	key, err := metrics.GenerateSecretKey([]byte(testId), 256, crypto11.CipherAES)
+	assert.Nil(t, err)
+
+	// the recording gets its own key, not the one of the metrics or of the memory context
+	_, err = metrics.GetAttribute(key, crypto11.CkaId)
+	assert.Nil(t, err)
+	assert.Contains(t, recording.String(), `"method":"GetAttribute","args":[{"handle":1},258]`)
+}
@@ -0,0 +1,256 @@
+package hsm
+
+import (
+	"crypto"
+	"crypto/aes"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/eclipse-xfsc/crypto-provider-core/types"
+	"github.com/lestrrat-go/jwx/v2/jwa"
+	"github.com/lestrrat-go/jwx/v2/jwe"
+)
+
+// JWEOptions controls how a JWE is produced or decrypted with an HSM key.
+type JWEOptions struct {
+	// KeyAlgorithm overrides the key management algorithm derived from the key.
+	KeyAlgorithm jwa.KeyEncryptionAlgorithm
+	// Headers are added to the protected header next to alg, enc and kid.
+	Headers map[string]interface{}
+	// JSON selects the JSON serialization instead of the compact one.
+	JSON bool
+}
+
+// jweKeyDecrypter decrypts or derives the content encryption key on the HSM and implements jwe.KeyDecrypter.
+type jweKeyDecrypter struct {
+	id     []byte
+	public crypto.PublicKey
+	signer crypto.Signer
+	derive ecdhDeriver
+	rand   io.Reader
+}
+
+func keyEncryptionAlgorithm(pub crypto.PublicKey) (jwa.KeyEncryptionAlgorithm, error) {
+	switch pub.(type) {
+	case *rsa.PublicKey:
+		return jwa.RSA_OAEP_256, nil
+	case *ecdsa.PublicKey:
+		return jwa.ECDH_ES_A256KW, nil
+	default:
+		return "", fmt.Errorf("keys of type %T can not be used for JWE", pub)
+	}
+}
+
+func checkKeyEncryptionAlgorithm(pub crypto.PublicKey, alg jwa.KeyEncryptionAlgorithm) error {
+	switch pub.(type) {
+	case *rsa.PublicKey:
+		if alg == jwa.RSA_OAEP_256 {
+			return nil
+		}
+	case *ecdsa.PublicKey:
+		if alg == jwa.ECDH_ES || alg == jwa.ECDH_ES_A256KW {
+			return nil
+		}
+	}
+	return fmt.Errorf("key management algorithm %s is not supported for keys of type %T", alg, pub)
+}
+
+// EncryptJWE encrypts the plaintext with A256GCM for the HSM key. Only the public key is used.
+func (p HSMCryptoProvider) EncryptJWE(parameter types.CryptoIdentifier, plaintext []byte, options JWEOptions) (_ []byte, err error) {
+	p, op := p.observe("EncryptJWE", parameter.CryptoContext, parameter.KeyId)
+	defer op.end(&err)
+	signer, err := p.getSigner(parameter)
+	if err != nil {
+		return nil, err
+	}
+	if signer == nil {
+		return nil, keyNotFound(parameter.KeyId)
+	}
+	alg := options.KeyAlgorithm
+	if alg == "" {
+		if alg, err = keyEncryptionAlgorithm(signer.Public()); err != nil {
+			return nil, err
+		}
+	}
+	if err = checkKeyEncryptionAlgorithm(signer.Public(), alg); err != nil {
+		return nil, err
+	}
+
+	headers := jwe.NewHeaders()
+	for k, v := range options.Headers {
+		if err := headers.Set(k, v); err != nil {
+			return nil, err
+		}
+	}
+	if err := headers.Set(jwe.KeyIDKey, parameter.KeyId); err != nil {
+		return nil, err
+	}
+	encryptOptions := []jwe.EncryptOption{
+		jwe.WithKey(alg, signer.Public()),
+		jwe.WithContentEncryption(jwa.A256GCM),
+		jwe.WithProtectedHeaders(headers),
+	}
+	if options.JSON {
+		encryptOptions = append(encryptOptions, jwe.WithJSON())
+	}
+	return jwe.Encrypt(plaintext, encryptOptions...)
+}
+
+// DecryptJWE decrypts a compact or JSON serialized JWE. The content encryption key is decrypted
+// (RSA-OAEP-256) or derived (ECDH-ES, ECDH-ES+A256KW) with the private key on the HSM.
+func (p HSMCryptoProvider) DecryptJWE(parameter types.CryptoIdentifier, encrypted []byte, options JWEOptions) (_ []byte, err error) {
+	p, op := p.observe("DecryptJWE", parameter.CryptoContext, parameter.KeyId)
+	defer op.end(&err)
+	signer, err := p.getSigner(parameter)
+	if err != nil {
+		return nil, err
+	}
+	if signer == nil {
+		return nil, keyNotFound(parameter.KeyId)
+	}
+	msg, err := jwe.Parse(encrypted)
+	if err != nil {
+		return nil, err
+	}
+	if enc := msg.ProtectedHeaders().ContentEncryption(); enc != jwa.A256GCM {
+		return nil, fmt.Errorf("content encryption %s is not supported", enc)
+	}
+	alg := options.KeyAlgorithm
+	if alg == "" {
+		alg = msg.ProtectedHeaders().Algorithm()
+	}
+	if err = checkKeyEncryptionAlgorithm(signer.Public(), alg); err != nil {
+		return nil, err
+	}
+	decrypter := jweKeyDecrypter{
+		id:     []byte(parameter.KeyId),
+		public: signer.Public(),
+		signer: signer,
+		derive: p.controller.derive,
+		rand:   p.controller.rand,
+	}
+	return jwe.Decrypt(encrypted, jwe.WithKey(alg, decrypter), jwe.WithMessage(msg))
+}
+
+func (d jweKeyDecrypter) DecryptKey(alg jwa.KeyEncryptionAlgorithm, encryptedKey []byte, recipient jwe.Recipient, message *jwe.Message) ([]byte, error) {
+	switch alg {
+	case jwa.RSA_OAEP_256:
+		decrypter, ok := d.signer.(crypto.Decrypter)
+		if !ok {
+			return nil, fmt.Errorf("key %s can not decrypt", d.id)
+		}
+		return decrypter.Decrypt(d.rand, encryptedKey, &rsa.OAEPOptions{Hash: crypto.SHA256})
+	case jwa.ECDH_ES, jwa.ECDH_ES_A256KW:
+		return d.deriveKey(alg, encryptedKey, recipient.Headers(), message.ProtectedHeaders())
+	default:
+		return nil, fmt.Errorf("key management algorithm %s is not supported", alg)
+	}
+}
+
+func (d jweKeyDecrypter) deriveKey(alg jwa.KeyEncryptionAlgorithm, encryptedKey []byte, headers ...jwe.Headers) ([]byte, error) {
+	if d.derive == nil {
+		return nil, errors.New("ECDH key derivation is not available")
+	}
+	var epk crypto.PublicKey
+	var apu, apv []byte
+	var enc jwa.ContentEncryptionAlgorithm
+	for _, h := range headers {
+		if h == nil {
+			continue
+		}
+		if epk == nil && h.EphemeralPublicKey() != nil {
+			var raw ecdsa.PublicKey
+			if err := h.EphemeralPublicKey().Raw(&raw); err != nil {
+				return nil, err
+			}
+			epk = &raw
+		}
+		if apu == nil {
+			apu = h.AgreementPartyUInfo()
+		}
+		if apv == nil {
+			apv = h.AgreementPartyVInfo()
+		}
+		if enc == "" {
+			enc = h.ContentEncryption()
+		}
+	}
+	peer, ok := epk.(*ecdsa.PublicKey)
+	if !ok {
+		return nil, errors.New("missing ephemeral public key")
+	}
+	if own, ok := d.public.(*ecdsa.PublicKey); !ok || own.Curve != peer.Curve {
+		return nil, errors.New("ephemeral public key does not match the curve of the key")
+	}
+	z, err := d.derive.DeriveECDH(d.id, peer)
+	if err != nil {
+		return nil, err
+	}
+	// A256GCM and A256KW both use 256 bit keys
+	if alg == jwa.ECDH_ES {
+		return concatKDF(z, []byte(enc.String()), apu, apv, 32), nil
+	}
+	kek := concatKDF(z, []byte(alg.String()), apu, apv, 32)
+	return aesKeyUnwrap(kek, encryptedKey)
+}
+
+// concatKDF implements the single step KDF of NIST SP 800-56A as profiled by RFC 7518 section 4.6.2.
+func concatKDF(z, algID, apu, apv []byte, keyLen int) []byte {
+	lengthPrefixed := func(b []byte) []byte {
+		return binary.BigEndian.AppendUint32(nil, uint32(len(b)))
+	}
+	var otherInfo []byte
+	otherInfo = append(append(otherInfo, lengthPrefixed(algID)...), algID...)
+	otherInfo = append(append(otherInfo, lengthPrefixed(apu)...), apu...)
+	otherInfo = append(append(otherInfo, lengthPrefixed(apv)...), apv...)
+	otherInfo = binary.BigEndian.AppendUint32(otherInfo, uint32(keyLen*8))
+
+	var key []byte
+	for counter := uint32(1); len(key) < keyLen; counter++ {
+		h := sha256.New()
+		h.Write(binary.BigEndian.AppendUint32(nil, counter))
+		h.Write(z)
+		h.Write(otherInfo)
+		key = h.Sum(key)
+	}
+	return key[:keyLen]
+}
+
+var keyWrapIV = []byte{0xa6, 0xa6, 0xa6, 0xa6, 0xa6, 0xa6, 0xa6, 0xa6}
+
+// aesKeyUnwrap implements the AES key unwrap of RFC 3394.
+func aesKeyUnwrap(kek, wrapped []byte) ([]byte, error) {
+	if len(wrapped)%8 != 0 || len(wrapped) < 24 {
+		return nil, errors.New("invalid wrapped key length")
+	}
+	block, err := aes.NewCipher(kek)
+	if err != nil {
+		return nil, err
+	}
+	n := len(wrapped)/8 - 1
+	a := make([]byte, 8)
+	copy(a, wrapped[:8])
+	r := make([]byte, n*8)
+	copy(r, wrapped[8:])
+	buf := make([]byte, 16)
+	for j := 5; j >= 0; j-- {
+		for i := n; i >= 1; i-- {
+			t := uint64(n*j + i)
+			binary.BigEndian.PutUint64(buf, binary.BigEndian.Uint64(a)^t)
+			copy(buf[8:], r[(i-1)*8:i*8])
+			block.Decrypt(buf, buf)
+			copy(a, buf[:8])
+			copy(r[(i-1)*8:i*8], buf[8:])
+		}
+	}
+	if subtle.ConstantTimeCompare(a, keyWrapIV) != 1 {
+		return nil, errors.New("key unwrap integrity check failed")
+	}
+	return r, nil
+}
@@ -0,0 +1,112 @@
+package hsm
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/hex"
+	"strings"
+	"testing"
+
+	"github.com/eclipse-xfsc/crypto-provider-core/types"
+	"github.com/lestrrat-go/jwx/v2/jwa"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHSMCryptoProvider_DecryptJWE_RSA(t *testing.T) {
+	key, _ := rsa.GenerateKey(rand.Reader, 2048)
+	var mockApi = new(ContextTypeMock)
+	mockApi.On("FindKeyPair", []byte(testId), []byte(nil)).Return(&SoftDecrypterMock{key}, nil)
+	provider := getTestHSMCryptoProvider(mockApi)
+	identifier := types.CryptoIdentifier{KeyId: testId}
+
+	encrypted, err := provider.EncryptJWE(identifier, []byte("presentation"), JWEOptions{})
+	assert.Nil(t, err)
+	decrypted, err := provider.DecryptJWE(identifier, encrypted, JWEOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, []byte("presentation"), decrypted)
+}
+
+func TestHSMCryptoProvider_DecryptJWE_ECDH(t *testing.T) {
+	key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	var mockApi = new(ContextTypeMock)
+	mockApi.On("FindKeyPair", []byte(testId), []byte(nil)).Return(&SoftSignerMock{key}, nil)
+	provider := getTestHSMCryptoProvider(mockApi)
+	provider.controller.derive = &SoftDeriverMock{keys: map[string]*ecdsa.PrivateKey{testId: key}}
+	identifier := types.CryptoIdentifier{KeyId: testId}
+
+	for _, alg := range []jwa.KeyEncryptionAlgorithm{jwa.ECDH_ES, jwa.ECDH_ES_A256KW} {
+		encrypted, err := provider.EncryptJWE(identifier, []byte("presentation"), JWEOptions{KeyAlgorithm: alg})
+		assert.Nil(t, err)
+		decrypted, err := provider.DecryptJWE(identifier, encrypted, JWEOptions{})
+		assert.Nil(t, err)
+		assert.Equal(t, []byte("presentation"), decrypted)
+	}
+}
+
+func TestHSMCryptoProvider_DecryptJWE_GeneratedKey(t *testing.T) {
+	provider, err := New(Options{Backend: DevBackend})
+	assert.Nil(t, err)
+	identifier := types.CryptoIdentifier{KeyId: testId}
+	assert.Nil(t, provider.GenerateKey(types.CryptoKeyParameter{Identifier: identifier, KeyType: types.Ecdsap256}))
+
+	for _, alg := range []jwa.KeyEncryptionAlgorithm{jwa.ECDH_ES, jwa.ECDH_ES_A256KW} {
+		encrypted, err := provider.EncryptJWE(identifier, []byte("presentation"), JWEOptions{KeyAlgorithm: alg})
+		assert.Nil(t, err)
+		decrypted, err := provider.DecryptJWE(identifier, encrypted, JWEOptions{})
+		assert.Nil(t, err)
+		assert.Equal(t, []byte("presentation"), decrypted)
+	}
+}
+
+func TestHSMCryptoProvider_DecryptJWE_DeriveNotPermitted(t *testing.T) {
+	memory := NewMemoryContext()
+	_, err := memory.GenerateECDSAKeyPair([]byte(testId), elliptic.P256())
+	assert.Nil(t, err)
+	provider := HSMCryptoProvider{controller: &hsmController{api: memory, rand: rand.Reader, derive: memory}}
+	identifier := types.CryptoIdentifier{KeyId: testId}
+
+	encrypted, err := provider.EncryptJWE(identifier, []byte("presentation"), JWEOptions{KeyAlgorithm: jwa.ECDH_ES})
+	assert.Nil(t, err)
+	_, err = provider.DecryptJWE(identifier, encrypted, JWEOptions{})
+	assert.ErrorContains(t, err, "CKR_KEY_FUNCTION_NOT_PERMITTED")
+}
+
+// TestConcatKDF uses the example of RFC 7518 appendix C.
+func TestConcatKDF(t *testing.T) {
+	z := []byte{158, 86, 217, 29, 129, 113, 53, 211, 114, 131, 66, 131, 191, 132, 38, 156,
+		251, 49, 110, 163, 218, 128, 106, 72, 246, 218, 167, 121, 140, 254, 144, 196}
+	key := concatKDF(z, []byte("A128GCM"), []byte("Alice"), []byte("Bob"), 16)
+	assert.Equal(t, "VqqN6vgjbSBcIijNcacQGg", base64.RawURLEncoding.EncodeToString(key))
+}
+
+// TestAESKeyUnwrap uses the examples of RFC 3394 section 4.
+func TestAESKeyUnwrap(t *testing.T) {
+	for _, vector := range []struct{ kek, key, wrapped string }{
+		{"000102030405060708090A0B0C0D0E0F", "00112233445566778899AABBCCDDEEFF", "1FA68B0A8112B447AEF34BD8FB5A7B829D3E862371D2CFE5"},
+		{"000102030405060708090A0B0C0D0E0F101112131415161718191A1B1C1D1E1F", "00112233445566778899AABBCCDDEEFF", "64E8C3F9CE0F5BA263E9777905818A2A93C8191E7D6E8AE7"},
+		{"000102030405060708090A0B0C0D0E0F101112131415161718191A1B1C1D1E1F", "00112233445566778899AABBCCDDEEFF000102030405060708090A0B0C0D0E0F", "28C9F404C4B810F4CBCCB35CFB87F8263F5786E2D80ED326CBC7F0E71A99F43BFB988B9B7A02DD21"},
+	} {
+		kek, _ := hex.DecodeString(vector.kek)
+		wrapped, _ := hex.DecodeString(vector.wrapped)
+		key, err := aesKeyUnwrap(kek, wrapped)
+		assert.Nil(t, err)
+		assert.Equal(t, vector.key, strings.ToUpper(hex.EncodeToString(key)))
+	}
+	kek, _ := hex.DecodeString("000102030405060708090A0B0C0D0E0F")
+	wrapped, _ := hex.DecodeString("1FA68B0A8112B447AEF34BD8FB5A7B829D3E862371D2CFE6")
+	_, err := aesKeyUnwrap(kek, wrapped)
+	assert.EqualError(t, err, "key unwrap integrity check failed")
+}
+
+func TestHSMCryptoProvider_DecryptJWE_WrongAlgorithm(t *testing.T) {
+	key, _ := rsa.GenerateKey(rand.Reader, 2048)
+	var mockApi = new(ContextTypeMock)
+	mockApi.On("FindKeyPair", []byte(testId), []byte(nil)).Return(&SoftDecrypterMock{key}, nil)
+	provider := getTestHSMCryptoProvider(mockApi)
+
+	_, err := provider.EncryptJWE(types.CryptoIdentifier{KeyId: testId}, []byte("presentation"), JWEOptions{KeyAlgorithm: jwa.ECDH_ES})
+	assert.NotNil(t, err)
+}
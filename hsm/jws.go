@@ -0,0 +1,192 @@
+package hsm
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"fmt"
+
+	"github.com/eclipse-xfsc/crypto-provider-core/types"
+	"github.com/lestrrat-go/jwx/v2/jwa"
+	"github.com/lestrrat-go/jwx/v2/jws"
+	"github.com/lestrrat-go/jwx/v2/jwt"
+)
+
+// JWSOptions controls how a JWS is produced or verified with an HSM key.
+type JWSOptions struct {
+	// Algorithm overrides the algorithm derived from the key.
+	Algorithm jwa.SignatureAlgorithm
+	// Headers are added to the protected header next to alg and kid.
+	Headers map[string]interface{}
+	// JSON selects the JSON serialization instead of the compact one.
+	JSON bool
+	// Detached leaves the payload out of the serialization and signs it
+	// unencoded (b64:false, RFC 7797) as required by Data Integrity proofs.
+	Detached bool
+}
+
+// jwsSigner binds an HSM key to a JWS algorithm and implements jws.Signer.
+type jwsSigner struct {
+	alg    jwa.SignatureAlgorithm
+	signer crypto.Signer
+}
+
+func (s jwsSigner) Algorithm() jwa.SignatureAlgorithm {
+	return s.alg
+}
+
+// Sign ignores the key argument, the signature is always created with the bound HSM key.
+func (s jwsSigner) Sign(payload []byte, _ interface{}) ([]byte, error) {
+	signer, err := jws.NewSigner(s.alg)
+	if err != nil {
+		return nil, err
+	}
+	return signer.Sign(payload, s.signer)
+}
+
+func signatureAlgorithm(pub crypto.PublicKey) (jwa.SignatureAlgorithm, error) {
+	switch key := pub.(type) {
+	case *ecdsa.PublicKey:
+		switch key.Curve.Params().BitSize {
+		case 256:
+			return jwa.ES256, nil
+		case 384:
+			return jwa.ES384, nil
+		case 521:
+			return jwa.ES512, nil
+		}
+		return "", fmt.Errorf("unsupported curve %s", key.Curve.Params().Name)
+	case *rsa.PublicKey:
+		// Verify uses PSS for rsa keys, so JWS does the same.
+		return jwa.PS256, nil
+	default:
+		return "", fmt.Errorf("keys of type %T can not be used for JWS", pub)
+	}
+}
+
+func (p HSMCryptoProvider) jwsKey(parameter types.CryptoIdentifier, alg jwa.SignatureAlgorithm) (crypto.Signer, jwa.SignatureAlgorithm, error) {
+	signer, err := p.getSigner(parameter)
+	if err != nil {
+		return nil, "", err
+	}
+	if signer == nil {
+		return nil, "", keyNotFound(parameter.KeyId)
+	}
+	if alg == "" {
+		alg, err = signatureAlgorithm(signer.Public())
+		if err != nil {
+			return nil, "", err
+		}
+	}
+	return signer, alg, nil
+}
+
+func (o JWSOptions) protectedHeaders(parameter types.CryptoIdentifier, alg jwa.SignatureAlgorithm) (jws.Headers, error) {
+	headers := jws.NewHeaders()
+	for k, v := range o.Headers {
+		if err := headers.Set(k, v); err != nil {
+			return nil, err
+		}
+	}
+	if err := headers.Set(jws.AlgorithmKey, alg); err != nil {
+		return nil, err
+	}
+	if err := headers.Set(jws.KeyIDKey, parameter.KeyId); err != nil {
+		return nil, err
+	}
+	if o.Detached {
+		if err := headers.Set("b64", false); err != nil {
+			return nil, err
+		}
+		if err := headers.Set(jws.CriticalKey, []string{"b64"}); err != nil {
+			return nil, err
+		}
+	}
+	return headers, nil
+}
+
+// JWSSigner returns a jws.Signer which signs with the HSM key of the identifier.
+func (p HSMCryptoProvider) JWSSigner(parameter types.CryptoIdentifier, alg jwa.SignatureAlgorithm) (_ jws.Signer, err error) {
+	_, op := p.observe("JWSSigner", parameter.CryptoContext, parameter.KeyId)
+	defer op.end(&err)
+	signer, alg, err := p.jwsKey(parameter, alg)
+	if err != nil {
+		return nil, err
+	}
+	return jwsSigner{alg: alg, signer: signer}, nil
+}
+
+// SignJWS signs the payload with the HSM key and returns the serialized JWS.
+func (p HSMCryptoProvider) SignJWS(parameter types.CryptoIdentifier, payload []byte, options JWSOptions) (_ []byte, err error) {
+	p, op := p.observe("SignJWS", parameter.CryptoContext, parameter.KeyId)
+	defer op.end(&err)
+	signer, alg, err := p.jwsKey(parameter, options.Algorithm)
+	if err != nil {
+		return nil, err
+	}
+	headers, err := options.protectedHeaders(parameter, alg)
+	if err != nil {
+		return nil, err
+	}
+	signOptions := []jws.SignOption{jws.WithKey(alg, signer, jws.WithProtectedHeaders(headers))}
+	if options.JSON {
+		signOptions = append(signOptions, jws.WithJSON())
+	}
+	if options.Detached {
+		signOptions = append(signOptions, jws.WithDetachedPayload(payload))
+		payload = nil
+	}
+	return jws.Sign(payload, signOptions...)
+}
+
+// VerifyJWS verifies a compact or JSON serialized JWS against the HSM key and returns the payload.
+// For detached signatures the payload has to be supplied in detachedPayload.
+func (p HSMCryptoProvider) VerifyJWS(parameter types.CryptoIdentifier, signed []byte, detachedPayload []byte, options JWSOptions) (_ []byte, err error) {
+	p, op := p.observe("VerifyJWS", parameter.CryptoContext, parameter.KeyId)
+	defer op.end(&err)
+	signer, alg, err := p.jwsKey(parameter, options.Algorithm)
+	if err != nil {
+		return nil, err
+	}
+	verifyOptions := []jws.VerifyOption{jws.WithKey(alg, signer.Public())}
+	if detachedPayload != nil {
+		verifyOptions = append(verifyOptions, jws.WithDetachedPayload(detachedPayload))
+	}
+	payload, err := jws.Verify(signed, verifyOptions...)
+	if err != nil {
+		return nil, err
+	}
+	if detachedPayload != nil {
+		return detachedPayload, nil
+	}
+	return payload, nil
+}
+
+// SignJWT signs the token claims with the HSM key and returns the compact JWT.
+func (p HSMCryptoProvider) SignJWT(parameter types.CryptoIdentifier, token jwt.Token, options JWSOptions) (_ []byte, err error) {
+	p, op := p.observe("SignJWT", parameter.CryptoContext, parameter.KeyId)
+	defer op.end(&err)
+	if options.Detached || options.JSON {
+		return nil, fmt.Errorf("JWT only supports the compact serialization with attached payload")
+	}
+	signer, alg, err := p.jwsKey(parameter, options.Algorithm)
+	if err != nil {
+		return nil, err
+	}
+	headers, err := options.protectedHeaders(parameter, alg)
+	if err != nil {
+		return nil, err
+	}
+	return jwt.Sign(token, jwt.WithKey(alg, signer, jws.WithProtectedHeaders(headers)))
+}
+
+// VerifyJWT verifies the signature of the JWT against the HSM key and validates its claims.
+func (p HSMCryptoProvider) VerifyJWT(parameter types.CryptoIdentifier, signed []byte, options JWSOptions) (_ jwt.Token, err error) {
+	p, op := p.observe("VerifyJWT", parameter.CryptoContext, parameter.KeyId)
+	defer op.end(&err)
+	signer, alg, err := p.jwsKey(parameter, options.Algorithm)
+	if err != nil {
+		return nil, err
+	}
+	return jwt.Parse(signed, jwt.WithKey(alg, signer.Public()))
+}
@@ -0,0 +1,64 @@
+package hsm
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"testing"
+
+	"github.com/eclipse-xfsc/crypto-provider-core/types"
+	"github.com/lestrrat-go/jwx/v2/jwa"
+	"github.com/lestrrat-go/jwx/v2/jws"
+	"github.com/lestrrat-go/jwx/v2/jwt"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHSMCryptoProvider_SignJWS(t *testing.T) {
+	key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	var mockApi = new(ContextTypeMock)
+	mockApi.On("FindKeyPair", []byte(testId), []byte(nil)).Return(&SoftSignerMock{key}, nil)
+	provider := getTestHSMCryptoProvider(mockApi)
+	identifier := types.CryptoIdentifier{KeyId: testId}
+
+	signed, err := provider.SignJWS(identifier, []byte("payload"), JWSOptions{})
+	assert.Nil(t, err)
+	msg, err := jws.Parse(signed)
+	assert.Nil(t, err)
+	assert.Equal(t, jwa.ES256, msg.Signatures()[0].ProtectedHeaders().Algorithm())
+	assert.Equal(t, testId, msg.Signatures()[0].ProtectedHeaders().KeyID())
+
+	payload, err := provider.VerifyJWS(identifier, signed, nil, JWSOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, []byte("payload"), payload)
+}
+
+func TestHSMCryptoProvider_SignJWS_Detached(t *testing.T) {
+	key, _ := rsa.GenerateKey(rand.Reader, 2048)
+	var mockApi = new(ContextTypeMock)
+	mockApi.On("FindKeyPair", []byte(testId), []byte(nil)).Return(&SoftSignerMock{key}, nil)
+	provider := getTestHSMCryptoProvider(mockApi)
+	identifier := types.CryptoIdentifier{KeyId: testId}
+
+	signed, err := provider.SignJWS(identifier, []byte("payload"), JWSOptions{Detached: true})
+	assert.Nil(t, err)
+	_, err = provider.VerifyJWS(identifier, signed, []byte("payload"), JWSOptions{})
+	assert.Nil(t, err)
+	_, err = provider.VerifyJWS(identifier, signed, []byte("other"), JWSOptions{})
+	assert.NotNil(t, err)
+}
+
+func TestHSMCryptoProvider_SignJWT(t *testing.T) {
+	key, _ := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	var mockApi = new(ContextTypeMock)
+	mockApi.On("FindKeyPair", []byte(testId), []byte(nil)).Return(&SoftSignerMock{key}, nil)
+	provider := getTestHSMCryptoProvider(mockApi)
+	identifier := types.CryptoIdentifier{KeyId: testId}
+
+	token, _ := jwt.NewBuilder().Issuer("issuer").Build()
+	signed, err := provider.SignJWT(identifier, token, JWSOptions{})
+	assert.Nil(t, err)
+	parsed, err := provider.VerifyJWT(identifier, signed, JWSOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, "issuer", parsed.Issuer())
+}
@@ -0,0 +1,175 @@
+package hsm
+
+import (
+	"context"
+	"crypto"
+	"crypto/cipher"
+	"crypto/rsa"
+	"fmt"
+	"io"
+
+	"github.com/eclipse-xfsc/crypto-provider-core/types"
+	"github.com/miekg/pkcs11"
+)
+
+// hsmSigner is a crypto.Signer and, for RSA keys, crypto.Decrypter bound to the identifier it was created
+// for. Its operations are observed like those of the provider, errors are annotated with the operation
+// and key. The key is looked up again once if its session or object handle became invalid.
+type hsmSigner struct {
+	provider   HSMCryptoProvider
+	identifier types.CryptoIdentifier
+	signer     crypto.Signer
+}
+
+// hsmAEAD is the cipher.AEAD of an AES key bound to the identifier it was created for.
+type hsmAEAD struct {
+	provider   HSMCryptoProvider
+	identifier types.CryptoIdentifier
+	aead       cipher.AEAD
+}
+
+// Signer returns the HSM key as crypto.Signer for standard library APIs such as tls.Certificate or
+// x509.CreateCertificate. Operations fail once the context of the identifier is done.
+func (p HSMCryptoProvider) Signer(parameter types.CryptoIdentifier) (crypto.Signer, error) {
+	return p.hsmSigner(parameter)
+}
+
+// Decrypter returns the HSM RSA key as crypto.Decrypter. Operations fail once the context of the
+// identifier is done.
+func (p HSMCryptoProvider) Decrypter(parameter types.CryptoIdentifier) (crypto.Decrypter, error) {
+	signer, err := p.hsmSigner(parameter)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := signer.signer.(crypto.Decrypter); !ok {
+		return nil, fmt.Errorf("key %s can not decrypt", parameter.KeyId)
+	}
+	if _, ok := signer.Public().(*rsa.PublicKey); !ok {
+		return nil, fmt.Errorf("key %s can not decrypt", parameter.KeyId)
+	}
+	return signer, nil
+}
+
+// AEAD returns AES-GCM with the HSM AES key, it fails if the context of the identifier is done already.
+// Open fails once the context is done. Seal can not report errors, it ignores the context and panics
+// as required by cipher.AEAD if the HSM rejects the operation.
+func (p HSMCryptoProvider) AEAD(parameter types.CryptoIdentifier) (cipher.AEAD, error) {
+	if err := contextError(parameter.CryptoContext.Context); err != nil {
+		return nil, err
+	}
+	aead, err := p.gcm(parameter)
+	if err != nil {
+		return nil, err
+	}
+	return hsmAEAD{provider: p, identifier: parameter, aead: aead}, nil
+}
+
+func (p HSMCryptoProvider) hsmSigner(parameter types.CryptoIdentifier) (hsmSigner, error) {
+	signer, err := p.getSigner(parameter)
+	if err != nil {
+		return hsmSigner{}, fmt.Errorf("find key %s: %w", parameter.KeyId, err)
+	}
+	if signer == nil {
+		return hsmSigner{}, keyNotFound(parameter.KeyId)
+	}
+	return hsmSigner{provider: p, identifier: parameter, signer: signer}, nil
+}
+
+// isStaleHandle reports whether the error is caused by a session or object handle which is no longer
+// valid, e.g. after the HSM was restarted.
+func isStaleHandle(err error) bool {
+	return isPkcs11Error(err, pkcs11.CKR_SESSION_HANDLE_INVALID) || isPkcs11Error(err, pkcs11.CKR_SESSION_CLOSED) ||
+		isPkcs11Error(err, pkcs11.CKR_OBJECT_HANDLE_INVALID) || isPkcs11Error(err, pkcs11.CKR_KEY_HANDLE_INVALID)
+}
+
+func contextError(ctx context.Context) error {
+	if ctx == nil {
+		return nil
+	}
+	return ctx.Err()
+}
+
+func (s hsmSigner) Public() crypto.PublicKey {
+	return s.signer.Public()
+}
+
+func (s hsmSigner) Sign(rand io.Reader, digest []byte, opts crypto.SignerOpts) (signature []byte, err error) {
+	p, op := s.provider.observe("Signer.Sign", s.identifier.CryptoContext, s.identifier.KeyId)
+	defer op.end(&err)
+	if err := contextError(s.identifier.CryptoContext.Context); err != nil {
+		return nil, err
+	}
+	signature, err = s.signer.Sign(rand, digest, opts)
+	if isStaleHandle(err) {
+		p.controller.meter().Connected()
+		var fresh hsmSigner
+		if fresh, err = p.hsmSigner(s.identifier); err == nil {
+			signature, err = fresh.signer.Sign(rand, digest, opts)
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("sign with key %s: %w", s.identifier.KeyId, err)
+	}
+	return signature, nil
+}
+
+func (s hsmSigner) Decrypt(rand io.Reader, msg []byte, opts crypto.DecrypterOpts) (plaintext []byte, err error) {
+	p, op := s.provider.observe("Decrypter.Decrypt", s.identifier.CryptoContext, s.identifier.KeyId)
+	defer op.end(&err)
+	if err := contextError(s.identifier.CryptoContext.Context); err != nil {
+		return nil, err
+	}
+	decrypter, ok := s.signer.(crypto.Decrypter)
+	if !ok {
+		return nil, fmt.Errorf("key %s can not decrypt", s.identifier.KeyId)
+	}
+	plaintext, err = decrypter.Decrypt(rand, msg, opts)
+	if isStaleHandle(err) {
+		p.controller.meter().Connected()
+		var fresh hsmSigner
+		if fresh, err = p.hsmSigner(s.identifier); err == nil {
+			if decrypter, ok = fresh.signer.(crypto.Decrypter); !ok {
+				return nil, fmt.Errorf("key %s can not decrypt", s.identifier.KeyId)
+			}
+			plaintext, err = decrypter.Decrypt(rand, msg, opts)
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("decrypt with key %s: %w", s.identifier.KeyId, err)
+	}
+	return plaintext, nil
+}
+
+func (a hsmAEAD) NonceSize() int {
+	return a.aead.NonceSize()
+}
+
+func (a hsmAEAD) Overhead() int {
+	return a.aead.Overhead()
+}
+
+func (a hsmAEAD) Seal(dst, nonce, plaintext, additionalData []byte) []byte {
+	var err error
+	_, op := a.provider.observe("AEAD.Seal", a.identifier.CryptoContext, a.identifier.KeyId)
+	defer op.end(&err)
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("seal with key %s: %v", a.identifier.KeyId, r)
+			panic(r)
+		}
+	}()
+	return a.aead.Seal(dst, nonce, plaintext, additionalData)
+}
+
+func (a hsmAEAD) Open(dst, nonce, ciphertext, additionalData []byte) (plaintext []byte, err error) {
+	_, op := a.provider.observe("AEAD.Open", a.identifier.CryptoContext, a.identifier.KeyId)
+	defer op.end(&err)
+	if err := contextError(a.identifier.CryptoContext.Context); err != nil {
+		return nil, err
+	}
+	plaintext, err = a.aead.Open(dst, nonce, ciphertext, additionalData)
+	if err != nil {
+		return nil, fmt.Errorf("open with key %s: %w", a.identifier.KeyId, err)
+	}
+	return plaintext, nil
+}
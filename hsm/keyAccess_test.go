@@ -0,0 +1,119 @@
+package hsm
+
+import (
+	"context"
+	"crypto"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"io"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/eclipse-xfsc/crypto-provider-core/types"
+	"github.com/miekg/pkcs11"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHSMCryptoProvider_Signer(t *testing.T) {
+	key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	var mockApi = new(ContextTypeMock)
+	mockApi.On("FindKeyPair", []byte(testId), []byte(nil)).Return(&SoftSignerMock{key}, nil)
+	provider := getTestHSMCryptoProvider(mockApi)
+
+	signer, err := provider.Signer(types.CryptoIdentifier{KeyId: testId})
+	assert.Nil(t, err)
+	template := &x509.Certificate{SerialNumber: big.NewInt(1), Subject: pkix.Name{CommonName: "signer"}, NotAfter: time.Now().Add(time.Hour)}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, signer.Public(), signer)
+	assert.Nil(t, err)
+	certificate, _ := x509.ParseCertificate(der)
+	assert.Nil(t, certificate.CheckSignature(certificate.SignatureAlgorithm, certificate.RawTBSCertificate, certificate.Signature))
+
+	_, err = provider.Decrypter(types.CryptoIdentifier{KeyId: testId})
+	assert.NotNil(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	signer, err = provider.Signer(types.CryptoIdentifier{KeyId: testId, CryptoContext: types.CryptoContext{Context: ctx}})
+	assert.Nil(t, err)
+	cancel()
+	digest := sha256.Sum256([]byte("test"))
+	_, err = signer.Sign(rand.Reader, digest[:], crypto.SHA256)
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+func TestHSMCryptoProvider_Decrypter(t *testing.T) {
+	key, _ := rsa.GenerateKey(rand.Reader, 2048)
+	var mockApi = new(ContextTypeMock)
+	mockApi.On("FindKeyPair", []byte(testId), []byte(nil)).Return(&SoftDecrypterMock{key}, nil)
+	provider := getTestHSMCryptoProvider(mockApi)
+
+	decrypter, err := provider.Decrypter(types.CryptoIdentifier{KeyId: testId})
+	assert.Nil(t, err)
+	ciphertext, _ := rsa.EncryptOAEP(sha256.New(), rand.Reader, &key.PublicKey, []byte("secret"), nil)
+	plaintext, err := decrypter.Decrypt(rand.Reader, ciphertext, &rsa.OAEPOptions{Hash: crypto.SHA256})
+	assert.Nil(t, err)
+	assert.Equal(t, []byte("secret"), plaintext)
+
+	_, err = decrypter.Decrypt(rand.Reader, []byte("garbage"), &rsa.OAEPOptions{Hash: crypto.SHA256})
+	assert.ErrorContains(t, err, testId)
+}
+
+// staleSigner fails like a key whose session was closed by a restart of the HSM.
+type staleSigner struct {
+	*SoftSignerMock
+}
+
+func (s staleSigner) Sign(io.Reader, []byte, crypto.SignerOpts) ([]byte, error) {
+	return nil, pkcs11.Error(pkcs11.CKR_SESSION_HANDLE_INVALID)
+}
+
+func TestHSMCryptoProvider_Signer_StaleHandle(t *testing.T) {
+	key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	var mockApi = new(ContextTypeMock)
+	mockApi.On("FindKeyPair", []byte(testId), []byte(nil)).Return(staleSigner{&SoftSignerMock{key}}, nil).Once()
+	mockApi.On("FindKeyPair", []byte(testId), []byte(nil)).Return(&SoftSignerMock{key}, nil).Once()
+	provider := getTestHSMCryptoProvider(mockApi)
+
+	signer, err := provider.Signer(types.CryptoIdentifier{KeyId: testId})
+	assert.Nil(t, err)
+	digest := sha256.Sum256([]byte("test"))
+	signature, err := signer.Sign(rand.Reader, digest[:], crypto.SHA256)
+	assert.Nil(t, err)
+	assert.True(t, ecdsa.VerifyASN1(&key.PublicKey, digest[:], signature))
+	mockApi.AssertExpectations(t)
+}
+
+func TestHSMAEAD(t *testing.T) {
+	block, _ := aes.NewCipher(make([]byte, 32))
+	gcm, _ := cipher.NewGCM(block)
+	ctx, cancel := context.WithCancel(context.Background())
+	identifier := types.CryptoIdentifier{KeyId: testId, CryptoContext: types.CryptoContext{Context: ctx}}
+	aead := hsmAEAD{provider: getTestHSMCryptoProvider(new(ContextTypeMock)), identifier: identifier, aead: gcm}
+
+	nonce := make([]byte, aead.NonceSize())
+	sealed := aead.Seal(nil, nonce, []byte("secret"), nil)
+	opened, err := aead.Open(nil, nonce, sealed, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, []byte("secret"), opened)
+
+	cancel()
+	_, err = aead.Open(nil, nonce, sealed, nil)
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Equal(t, sealed, aead.Seal(nil, nonce, []byte("secret"), nil))
+}
+
+func TestHSMCryptoProvider_AEAD_Canceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	provider := getTestHSMCryptoProvider(new(ContextTypeMock))
+
+	_, err := provider.AEAD(types.CryptoIdentifier{KeyId: testId, CryptoContext: types.CryptoContext{Context: ctx}})
+	assert.ErrorIs(t, err, context.Canceled)
+}
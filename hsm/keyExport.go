@@ -0,0 +1,261 @@
+package hsm
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/eclipse-xfsc/crypto-provider-core/types"
+	"github.com/lestrrat-go/jwx/v2/jwk"
+	"github.com/mr-tron/base58"
+)
+
+// KeyFormat selects the encoding of public keys in CryptoKey.Key.
+type KeyFormat string
+
+const (
+	// PEM is a PKIX "PUBLIC KEY" block, the format expected by crypto-provider-core.
+	PEM KeyFormat = "pem"
+	// SPKI is the DER encoded SubjectPublicKeyInfo.
+	SPKI KeyFormat = "spki"
+	// JWK is a JSON Web Key with its RFC 7638 thumbprint as kid.
+	JWK KeyFormat = "jwk"
+	// Multibase is the base58btc multibase encoding of the multicodec prefixed key.
+	Multibase KeyFormat = "multibase"
+
+	DefaultKeyFormat = PEM
+)
+
+// validKeyFormat reports whether encodePublicKey supports the format.
+func validKeyFormat(format KeyFormat) bool {
+	switch format {
+	case PEM, SPKI, JWK, Multibase:
+		return true
+	}
+	return false
+}
+
+// multicodec prefixes of public keys, https://github.com/multiformats/multicodec
+const (
+	multicodecEd25519 = 0xed
+	multicodecP256    = 0x1200
+	multicodecP384    = 0x1201
+	multicodecP521    = 0x1202
+	multicodecRSA     = 0x1205
+)
+
+// ExportKey returns the public key of the identifier encoded in the given format.
+func (p HSMCryptoProvider) ExportKey(parameter types.CryptoIdentifier, format KeyFormat) (key *types.CryptoKey, err error) {
+	p, op := p.observe("ExportKey", parameter.CryptoContext, parameter.KeyId)
+	defer op.end(&err)
+	if key, err = p.exportKey(parameter, format); err != nil {
+		return nil, err
+	}
+	op.keyType = key.KeyType
+	return key, nil
+}
+
+func (p HSMCryptoProvider) exportKey(parameter types.CryptoIdentifier, format KeyFormat) (*types.CryptoKey, error) {
+	pub, err := p.publicKey(parameter)
+	if err != nil {
+		return nil, err
+	}
+	keyType, err := publicKeyType(pub)
+	if err != nil {
+		return nil, err
+	}
+	keyBytes, err := encodePublicKey(pub, format)
+	if err != nil {
+		return nil, err
+	}
+	key := &types.CryptoKey{Key: keyBytes}
+	key.Identifier = parameter
+	key.KeyType = keyType
+	return key, nil
+}
+
+func (p HSMCryptoProvider) publicKey(parameter types.CryptoIdentifier) (crypto.PublicKey, error) {
+	signer, err := p.getSigner(parameter)
+	if err != nil {
+		return nil, err
+	}
+	if signer == nil {
+		return nil, keyNotFound(parameter.KeyId)
+	}
+	return signer.Public(), nil
+}
+
+func publicKeyType(pub crypto.PublicKey) (types.KeyType, error) {
+	switch pubKey := pub.(type) {
+	case *ecdsa.PublicKey:
+		switch pubKey.Curve {
+		case elliptic.P256():
+			return types.Ecdsap256, nil
+		case elliptic.P384():
+			return types.Ecdsap384, nil
+		case elliptic.P521():
+			return types.Ecdsap512, nil
+		}
+		return "", fmt.Errorf("unsupported curve %s", pubKey.Curve.Params().Name)
+	case *rsa.PublicKey:
+		return constructKeyType(RSA, strconv.Itoa(pubKey.N.BitLen())), nil
+	case ed25519.PublicKey:
+		return types.Ed25519, nil
+	default:
+		return "", fmt.Errorf("keys of type %T are not retrievable", pub)
+	}
+}
+
+func encodePublicKey(pub crypto.PublicKey, format KeyFormat) ([]byte, error) {
+	switch format {
+	case PEM, "":
+		der, err := x509.MarshalPKIXPublicKey(pub)
+		if err != nil {
+			return nil, err
+		}
+		return pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}), nil
+	case SPKI:
+		return x509.MarshalPKIXPublicKey(pub)
+	case JWK:
+		key, err := publicJWK(pub)
+		if err != nil {
+			return nil, err
+		}
+		return json.Marshal(key)
+	case Multibase:
+		return multibaseKey(pub)
+	default:
+		return nil, fmt.Errorf("unsupported key format %s", format)
+	}
+}
+
+// publicJWK converts the public key to a JWK which carries its RFC 7638 thumbprint as kid.
+func publicJWK(pub crypto.PublicKey) (jwk.Key, error) {
+	key, err := jwk.FromRaw(pub)
+	if err != nil {
+		return nil, err
+	}
+	thumbprint, err := key.Thumbprint(crypto.SHA256)
+	if err != nil {
+		return nil, err
+	}
+	if err := key.Set(jwk.KeyIDKey, base64.RawURLEncoding.EncodeToString(thumbprint)); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+func multicodecKey(pub crypto.PublicKey) ([]byte, error) {
+	var codec uint64
+	var raw []byte
+	switch pubKey := pub.(type) {
+	case ed25519.PublicKey:
+		codec, raw = multicodecEd25519, pubKey
+	case *ecdsa.PublicKey:
+		switch pubKey.Curve {
+		case elliptic.P256():
+			codec = multicodecP256
+		case elliptic.P384():
+			codec = multicodecP384
+		case elliptic.P521():
+			codec = multicodecP521
+		default:
+			return nil, fmt.Errorf("unsupported curve %s", pubKey.Curve.Params().Name)
+		}
+		raw = elliptic.MarshalCompressed(pubKey.Curve, pubKey.X, pubKey.Y)
+	case *rsa.PublicKey:
+		codec, raw = multicodecRSA, x509.MarshalPKCS1PublicKey(pubKey)
+	default:
+		return nil, fmt.Errorf("keys of type %T are not retrievable", pub)
+	}
+	return append(binary.AppendUvarint(nil, codec), raw...), nil
+}
+
+// decodePublicKey parses a public key encoded by encodePublicKey, certificates attached by
+// attachCertificateChain are skipped.
+func decodePublicKey(data []byte, format KeyFormat) (crypto.PublicKey, error) {
+	switch format {
+	case PEM, "":
+		for block, rest := pem.Decode(data); block != nil; block, rest = pem.Decode(rest) {
+			if block.Type == "PUBLIC KEY" {
+				return x509.ParsePKIXPublicKey(block.Bytes)
+			}
+		}
+		return nil, errors.New("no PEM public key found")
+	case SPKI:
+		return x509.ParsePKIXPublicKey(data)
+	case JWK:
+		key, err := jwk.ParseKey(data)
+		if err != nil {
+			return nil, err
+		}
+		var pub interface{}
+		if err := key.Raw(&pub); err != nil {
+			return nil, err
+		}
+		return pub, nil
+	case Multibase:
+		return parseMultibaseKey(data)
+	default:
+		return nil, fmt.Errorf("unsupported key format %s", format)
+	}
+}
+
+// parseMultibaseKey is the inverse of multibaseKey.
+func parseMultibaseKey(data []byte) (crypto.PublicKey, error) {
+	encoded, ok := strings.CutPrefix(string(data), "z")
+	if !ok {
+		return nil, errors.New("multibase key is not base58btc encoded")
+	}
+	decoded, err := base58.Decode(encoded)
+	if err != nil {
+		return nil, err
+	}
+	codec, n := binary.Uvarint(decoded)
+	if n <= 0 {
+		return nil, errors.New("invalid multicodec prefix")
+	}
+	raw := decoded[n:]
+	var curve elliptic.Curve
+	switch codec {
+	case multicodecEd25519:
+		if len(raw) != ed25519.PublicKeySize {
+			return nil, errors.New("invalid ed25519 public key")
+		}
+		return ed25519.PublicKey(raw), nil
+	case multicodecRSA:
+		return x509.ParsePKCS1PublicKey(raw)
+	case multicodecP256:
+		curve = elliptic.P256()
+	case multicodecP384:
+		curve = elliptic.P384()
+	case multicodecP521:
+		curve = elliptic.P521()
+	default:
+		return nil, fmt.Errorf("unsupported multicodec 0x%x", codec)
+	}
+	x, y := elliptic.UnmarshalCompressed(curve, raw)
+	if x == nil {
+		return nil, errors.New("invalid compressed EC point")
+	}
+	return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+}
+
+func multibaseKey(pub crypto.PublicKey) ([]byte, error) {
+	key, err := multicodecKey(pub)
+	if err != nil {
+		return nil, err
+	}
+	return []byte("z" + base58.Encode(key)), nil
+}
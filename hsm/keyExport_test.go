@@ -0,0 +1,87 @@
+package hsm
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/eclipse-xfsc/crypto-provider-core/types"
+	"github.com/mr-tron/base58"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHSMCryptoProvider_ExportKey(t *testing.T) {
+	key, _ := rsa.GenerateKey(rand.Reader, 2048)
+	var mockApi = new(ContextTypeMock)
+	mockApi.On("FindKeyPair", []byte(testId), []byte(nil)).Return(&SoftSignerMock{key}, nil)
+	provider := getTestHSMCryptoProvider(mockApi)
+	identifier := types.CryptoIdentifier{KeyId: testId}
+
+	spki, err := provider.ExportKey(identifier, SPKI)
+	assert.Nil(t, err)
+	assert.Equal(t, types.Rsa2048, spki.KeyType)
+	pub, err := x509.ParsePKIXPublicKey(spki.Key)
+	assert.Nil(t, err)
+	assert.True(t, key.PublicKey.Equal(pub))
+
+	pem, err := provider.ExportKey(identifier, PEM)
+	assert.Nil(t, err)
+	block, err := pem.GetPem()
+	assert.Nil(t, err)
+	assert.Equal(t, spki.Key, block.Bytes)
+
+	jwkKey, err := provider.ExportKey(identifier, JWK)
+	assert.Nil(t, err)
+	var fields map[string]interface{}
+	assert.Nil(t, json.Unmarshal(jwkKey.Key, &fields))
+	assert.Equal(t, "RSA", fields["kty"])
+	assert.NotEmpty(t, fields["kid"])
+
+	_, err = provider.ExportKey(identifier, "unknown")
+	assert.NotNil(t, err)
+}
+
+func TestNew_KeyFormat(t *testing.T) {
+	_, err := New(Options{Backend: DevBackend, KeyFormat: "jwks"})
+	assert.EqualError(t, err, "unsupported key format \"jwks\"")
+	provider, err := New(Options{Backend: DevBackend, KeyFormat: JWK})
+	assert.Nil(t, err)
+	assert.Equal(t, JWK, provider.controller.keyFormat)
+}
+
+func TestMultibaseKey(t *testing.T) {
+	pub, _, _ := ed25519.GenerateKey(rand.Reader)
+	encoded, err := multibaseKey(pub)
+	assert.Nil(t, err)
+	assert.True(t, strings.HasPrefix(string(encoded), "z6Mk"))
+	decoded, _ := base58.Decode(string(encoded[1:]))
+	assert.Equal(t, []byte(pub), decoded[2:])
+
+	key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	encoded, err = multibaseKey(key.Public())
+	assert.Nil(t, err)
+	assert.True(t, strings.HasPrefix(string(encoded), "zDn"))
+}
+
+func TestDecodePublicKey(t *testing.T) {
+	ecKey, _ := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	rsaKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	edKey, _, _ := ed25519.GenerateKey(rand.Reader)
+	for _, format := range []KeyFormat{PEM, SPKI, JWK, Multibase} {
+		for _, pub := range []interface{}{ecKey.Public(), rsaKey.Public(), edKey} {
+			encoded, err := encodePublicKey(pub, format)
+			assert.Nil(t, err)
+			decoded, err := decodePublicKey(encoded, format)
+			assert.Nil(t, err)
+			assert.Equal(t, pub, decoded, format)
+		}
+	}
+	_, err := decodePublicKey([]byte("abc"), Multibase)
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,1034 @@
+package hsm
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"runtime/debug"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ThalesIgnite/crypto11"
+	"github.com/eclipse-xfsc/crypto-provider-core/types"
+)
+
+// KMIP tags, KMIP 1.4 section 9.1.3.1 and KMIP 2.0 section 11.1.
+const (
+	kmipTagAttribute                   uint32 = 0x420008
+	kmipTagAttributeName               uint32 = 0x42000A
+	kmipTagAttributeValue              uint32 = 0x42000B
+	kmipTagBatchCount                  uint32 = 0x42000D
+	kmipTagBatchItem                   uint32 = 0x42000F
+	kmipTagBlockCipherMode             uint32 = 0x420011
+	kmipTagCertificateType             uint32 = 0x42001D
+	kmipTagCertificateValue            uint32 = 0x42001E
+	kmipTagCryptographicAlgorithm      uint32 = 0x420028
+	kmipTagCryptographicLength         uint32 = 0x42002A
+	kmipTagCryptographicParameters     uint32 = 0x42002B
+	kmipTagHashingAlgorithm            uint32 = 0x420038
+	kmipTagIVCounterNonce              uint32 = 0x42003D
+	kmipTagKeyBlock                    uint32 = 0x420040
+	kmipTagKeyFormatType               uint32 = 0x420042
+	kmipTagKeyMaterial                 uint32 = 0x420043
+	kmipTagKeyValue                    uint32 = 0x420045
+	kmipTagMaximumItems                uint32 = 0x42004F
+	kmipTagName                        uint32 = 0x420053
+	kmipTagNameType                    uint32 = 0x420054
+	kmipTagNameValue                   uint32 = 0x420055
+	kmipTagObjectType                  uint32 = 0x420057
+	kmipTagOperation                   uint32 = 0x42005C
+	kmipTagPaddingMethod               uint32 = 0x42005F
+	kmipTagProtocolVersion             uint32 = 0x420069
+	kmipTagProtocolVersionMajor        uint32 = 0x42006A
+	kmipTagProtocolVersionMinor        uint32 = 0x42006B
+	kmipTagPublicKey                   uint32 = 0x42006D
+	kmipTagRequestHeader               uint32 = 0x420077
+	kmipTagRequestMessage              uint32 = 0x420078
+	kmipTagRequestPayload              uint32 = 0x420079
+	kmipTagResponseHeader              uint32 = 0x42007A
+	kmipTagResponseMessage             uint32 = 0x42007B
+	kmipTagResponsePayload             uint32 = 0x42007C
+	kmipTagResultMessage               uint32 = 0x42007D
+	kmipTagResultReason                uint32 = 0x42007E
+	kmipTagResultStatus                uint32 = 0x42007F
+	kmipTagRevocationReason            uint32 = 0x420081
+	kmipTagRevocationReasonCode        uint32 = 0x420082
+	kmipTagState                       uint32 = 0x42008D
+	kmipTagTemplateAttribute           uint32 = 0x420091
+	kmipTagTimeStamp                   uint32 = 0x420092
+	kmipTagUniqueBatchItemID           uint32 = 0x420093
+	kmipTagUniqueIdentifier            uint32 = 0x420094
+	kmipTagValidityIndicator           uint32 = 0x42009B
+	kmipTagData                        uint32 = 0x4200C2
+	kmipTagSignatureData               uint32 = 0x4200C3
+	kmipTagAuthenticatedEncryptionData uint32 = 0x4200FE
+	kmipTagAuthenticatedEncryptionTag  uint32 = 0x4200FF
+	kmipTagAttributes                  uint32 = 0x420125
+	kmipTagCertificate                 uint32 = 0x420013
+	kmipTagCryptographicUsageMask      uint32 = 0x42002C
+	kmipTagAttributeReference          uint32 = 0x42013B
+)
+
+// KMIP enumerations.
+const (
+	kmipOperationCreate          uint32 = 0x01
+	kmipOperationRegister        uint32 = 0x03
+	kmipOperationLocate          uint32 = 0x08
+	kmipOperationGet             uint32 = 0x0A
+	kmipOperationGetAttributes   uint32 = 0x0B
+	kmipOperationActivate        uint32 = 0x12
+	kmipOperationRevoke          uint32 = 0x13
+	kmipOperationDestroy         uint32 = 0x14
+	kmipOperationEncrypt         uint32 = 0x1F
+	kmipOperationDecrypt         uint32 = 0x20
+	kmipOperationSign            uint32 = 0x21
+	kmipOperationSignatureVerify uint32 = 0x22
+
+	kmipObjectCertificate  uint32 = 0x01
+	kmipObjectSymmetricKey uint32 = 0x02
+	kmipObjectPublicKey    uint32 = 0x03
+	kmipObjectPrivateKey   uint32 = 0x04
+
+	kmipAlgorithmAES   uint32 = 0x03
+	kmipAlgorithmRSA   uint32 = 0x04
+	kmipAlgorithmECDSA uint32 = 0x06
+
+	kmipStatePreActive   uint32 = 0x01
+	kmipStateActive      uint32 = 0x02
+	kmipStateDeactivated uint32 = 0x03
+	kmipStateCompromised uint32 = 0x04
+
+	kmipResultSuccess         uint32 = 0x00
+	kmipResultOperationFailed uint32 = 0x01
+
+	kmipReasonItemNotFound         uint32 = 0x01
+	kmipReasonInvalidMessage       uint32 = 0x04
+	kmipReasonOperationNotSupport  uint32 = 0x05
+	kmipReasonInvalidField         uint32 = 0x07
+	kmipReasonFeatureNotSupported  uint32 = 0x08
+	kmipReasonCryptographicFailure uint32 = 0x0A
+	kmipReasonIllegalOperation     uint32 = 0x0B
+	kmipReasonPermissionDenied     uint32 = 0x0C
+	kmipReasonKeyFormatNotSupport  uint32 = 0x10
+	kmipReasonNotExtractable       uint32 = 0x17
+	kmipReasonObjectAlreadyExists  uint32 = 0x18
+	kmipReasonGeneralFailure       uint32 = 0x100
+
+	kmipKeyFormatX509        uint32 = 0x05
+	kmipCertificateTypeX509  uint32 = 0x01
+	kmipNameTypeText         uint32 = 0x01
+	kmipBlockCipherModeGCM   uint32 = 0x09
+	kmipPaddingPKCS1v15      uint32 = 0x03
+	kmipPaddingPSS           uint32 = 0x0A
+	kmipHashSHA256           uint32 = 0x04
+	kmipHashSHA384           uint32 = 0x05
+	kmipHashSHA512           uint32 = 0x06
+	kmipValidityValid        uint32 = 0x00
+	kmipValidityInvalid      uint32 = 0x01
+	kmipRevocationCompromise uint32 = 0x02
+	kmipRevocationCACompr    uint32 = 0x03
+)
+
+// KMIP 1.x attribute names of the attributes the server understands.
+var kmipAttributeNames = map[uint32]string{
+	kmipTagUniqueIdentifier:       "Unique Identifier",
+	kmipTagName:                   "Name",
+	kmipTagObjectType:             "Object Type",
+	kmipTagCryptographicAlgorithm: "Cryptographic Algorithm",
+	kmipTagCryptographicLength:    "Cryptographic Length",
+	kmipTagCryptographicUsageMask: "Cryptographic Usage Mask",
+	kmipTagState:                  "State",
+}
+
+// KMIPContextMapper maps the certificate of a KMIP client to the crypto context of its requests.
+type KMIPContextMapper func(certificate *x509.Certificate) (types.CryptoContext, error)
+
+// KMIPOptions configures the KMIP server.
+type KMIPOptions struct {
+	// ContextMapper maps client certificates to crypto contexts, the common name is used as namespace if nil
+	// and clients without common name are rejected.
+	ContextMapper KMIPContextMapper
+	// HandshakeTimeout limits the TLS handshake of a connection, 10 seconds if zero.
+	HandshakeTimeout time.Duration
+	// IdleTimeout limits the time a connection waits for the next request, 5 minutes if zero.
+	IdleTimeout time.Duration
+}
+
+// KMIPServer serves a subset of KMIP 1.4 and 2.0 (Create, Register, Get, Locate, Activate, Revoke,
+// Destroy, Encrypt, Decrypt, Sign, SignatureVerify and GetAttributes) with HSM keys.
+//
+// Unique identifiers are the key ids on the partition, prefixed with "<namespace>/" if the crypto
+// context of the client has a namespace, so clients only see the keys of their namespace. Private keys
+// never leave the HSM: Get of a key pair returns its public key. Lifecycle states are kept in memory as
+// PKCS#11 offers no attribute for them, keys without a known state are pre-active and must be activated
+// before they can be used, also after a restart of the server.
+type KMIPServer struct {
+	provider         HSMCryptoProvider
+	contextMapper    KMIPContextMapper
+	handshakeTimeout time.Duration
+	idleTimeout      time.Duration
+
+	mu     sync.Mutex
+	states map[string]uint32
+}
+
+type kmipError struct {
+	reason  uint32
+	message string
+}
+
+func (e *kmipError) Error() string {
+	return e.message
+}
+
+func kmipErrorf(reason uint32, format string, args ...interface{}) error {
+	return &kmipError{reason: reason, message: fmt.Sprintf(format, args...)}
+}
+
+// kmipRequest is the state of a batch item while it is processed.
+type kmipRequest struct {
+	context     types.CryptoContext
+	major       int32
+	payload     ttlv
+	placeholder *string
+}
+
+// NewKMIPServer creates a KMIP server for the HSM keys.
+func (p HSMCryptoProvider) NewKMIPServer(options KMIPOptions) *KMIPServer {
+	mapper := options.ContextMapper
+	if mapper == nil {
+		mapper = commonNameContext
+	}
+	server := &KMIPServer{provider: p, contextMapper: mapper, handshakeTimeout: options.HandshakeTimeout, idleTimeout: options.IdleTimeout, states: map[string]uint32{}}
+	if server.handshakeTimeout == 0 {
+		server.handshakeTimeout = 10 * time.Second
+	}
+	if server.idleTimeout == 0 {
+		server.idleTimeout = 5 * time.Minute
+	}
+	return server
+}
+
+// commonNameContext uses the common name of the client certificate as namespace. Clients without one
+// would otherwise see the keys of all namespaces, a / would let them reach into other namespaces.
+func commonNameContext(certificate *x509.Certificate) (types.CryptoContext, error) {
+	if certificate.Subject.CommonName == "" {
+		return types.CryptoContext{}, errors.New("client certificate has no common name")
+	}
+	if strings.Contains(certificate.Subject.CommonName, "/") {
+		return types.CryptoContext{}, errors.New("common name of the client certificate must not contain /")
+	}
+	return types.CryptoContext{Namespace: certificate.Subject.CommonName}, nil
+}
+
+// Serve accepts KMIP connections on the listener, which should be a TLS listener requiring client
+// certificates as mandated by the KMIP profiles.
+func (s *KMIPServer) Serve(listener net.Listener) error {
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			return err
+		}
+		go s.serveConn(conn)
+	}
+}
+
+func (s *KMIPServer) serveConn(conn net.Conn) {
+	defer conn.Close()
+	// a faulty request must not terminate the other connections
+	defer func() {
+		if r := recover(); r != nil {
+			s.provider.controller.log().Error("KMIP connection panicked", slog.String("remote_address", conn.RemoteAddr().String()), slog.Any("panic", r), slog.String("stack", string(debug.Stack())))
+		}
+	}()
+	tlsConn, ok := conn.(*tls.Conn)
+	if !ok {
+		return
+	}
+	if err := conn.SetDeadline(time.Now().Add(s.handshakeTimeout)); err != nil {
+		return
+	}
+	if err := tlsConn.Handshake(); err != nil {
+		return
+	}
+	if err := conn.SetDeadline(time.Time{}); err != nil {
+		return
+	}
+	certificates := tlsConn.ConnectionState().PeerCertificates
+	if len(certificates) == 0 {
+		return
+	}
+	context, err := s.contextMapper(certificates[0])
+	if err != nil {
+		return
+	}
+	for {
+		if err := conn.SetReadDeadline(time.Now().Add(s.idleTimeout)); err != nil {
+			return
+		}
+		request, err := readTTLV(conn)
+		if err != nil {
+			return
+		}
+		response, err := s.Handle(context, request)
+		if err != nil {
+			return
+		}
+		if _, err := conn.Write(response); err != nil {
+			return
+		}
+	}
+}
+
+// Handle processes a TTLV encoded KMIP request message of a client with the crypto context and returns
+// the TTLV encoded response message.
+func (s *KMIPServer) Handle(context types.CryptoContext, request []byte) ([]byte, error) {
+	message, rest, err := unmarshalTTLV(request)
+	if err != nil || len(rest) > 0 || message.Tag != kmipTagRequestMessage || message.Type != ttlvStructure {
+		return s.response(ttlvStruct(kmipTagProtocolVersion, ttlvInt(kmipTagProtocolVersionMajor, 1), ttlvInt(kmipTagProtocolVersionMinor, 4)),
+			[]ttlv{kmipFailure(0, nil, kmipErrorf(kmipReasonInvalidMessage, "invalid request message"))})
+	}
+	header, _ := message.child(kmipTagRequestHeader)
+	version, ok := header.child(kmipTagProtocolVersion)
+	major := version.integer(kmipTagProtocolVersionMajor)
+	if !ok || (major != 1 && major != 2) {
+		version = ttlvStruct(kmipTagProtocolVersion, ttlvInt(kmipTagProtocolVersionMajor, 1), ttlvInt(kmipTagProtocolVersionMinor, 4))
+		return s.response(version, []ttlv{kmipFailure(0, nil, kmipErrorf(kmipReasonInvalidMessage, "unsupported protocol version"))})
+	}
+
+	var placeholder string
+	var items []ttlv
+	for _, batchItem := range message.children(kmipTagBatchItem) {
+		operation := batchItem.enum(kmipTagOperation)
+		batchId, hasBatchId := batchItem.child(kmipTagUniqueBatchItemID)
+		var id *ttlv
+		if hasBatchId {
+			id = &batchId
+		}
+		payload, _ := batchItem.child(kmipTagRequestPayload)
+		result, err := s.operation(operation, kmipRequest{context: context, major: major, payload: payload, placeholder: &placeholder})
+		if err != nil {
+			items = append(items, kmipFailure(operation, id, err))
+			continue
+		}
+		item := []ttlv{ttlvEnum(kmipTagOperation, operation)}
+		if id != nil {
+			item = append(item, *id)
+		}
+		item = append(item, ttlvEnum(kmipTagResultStatus, kmipResultSuccess), ttlvStruct(kmipTagResponsePayload, result...))
+		items = append(items, ttlvStruct(kmipTagBatchItem, item...))
+	}
+	return s.response(version, items)
+}
+
+func (s *KMIPServer) response(version ttlv, items []ttlv) ([]byte, error) {
+	header := ttlvStruct(kmipTagResponseHeader, version, ttlvTime(kmipTagTimeStamp, time.Now()), ttlvInt(kmipTagBatchCount, int32(len(items))))
+	return ttlvStruct(kmipTagResponseMessage, append([]ttlv{header}, items...)...).marshal()
+}
+
+func kmipFailure(operation uint32, id *ttlv, err error) ttlv {
+	var kerr *kmipError
+	if !errors.As(err, &kerr) {
+		kerr = &kmipError{reason: kmipReasonGeneralFailure, message: err.Error()}
+	}
+	var item []ttlv
+	if operation != 0 {
+		item = append(item, ttlvEnum(kmipTagOperation, operation))
+	}
+	if id != nil {
+		item = append(item, *id)
+	}
+	item = append(item,
+		ttlvEnum(kmipTagResultStatus, kmipResultOperationFailed),
+		ttlvEnum(kmipTagResultReason, kerr.reason),
+		ttlvText(kmipTagResultMessage, kerr.message))
+	return ttlvStruct(kmipTagBatchItem, item...)
+}
+
+func (s *KMIPServer) operation(operation uint32, request kmipRequest) ([]ttlv, error) {
+	switch operation {
+	case kmipOperationCreate:
+		return s.create(request)
+	case kmipOperationRegister:
+		return s.register(request)
+	case kmipOperationGet:
+		return s.get(request)
+	case kmipOperationLocate:
+		return s.locate(request)
+	case kmipOperationGetAttributes:
+		return s.getAttributes(request)
+	case kmipOperationActivate:
+		return s.activate(request)
+	case kmipOperationRevoke:
+		return s.revoke(request)
+	case kmipOperationDestroy:
+		return s.destroy(request)
+	case kmipOperationEncrypt:
+		return s.encrypt(request)
+	case kmipOperationDecrypt:
+		return s.decrypt(request)
+	case kmipOperationSign:
+		return s.sign(request)
+	case kmipOperationSignatureVerify:
+		return s.signatureVerify(request)
+	default:
+		return nil, kmipErrorf(kmipReasonOperationNotSupport, "operation %#x is not supported", operation)
+	}
+}
+
+// keyId maps the unique identifier of the client to the key id on the partition.
+func (r kmipRequest) keyId(uid string) string {
+	if r.context.Namespace == "" {
+		return uid
+	}
+	return r.context.Namespace + "/" + uid
+}
+
+// uid maps the key id on the partition to the unique identifier of the client, ok is false for keys
+// outside the namespace of the client.
+func (r kmipRequest) uid(keyId string) (string, bool) {
+	if r.context.Namespace == "" {
+		return keyId, true
+	}
+	return strings.CutPrefix(keyId, r.context.Namespace+"/")
+}
+
+func (r kmipRequest) identifier(uid string) types.CryptoIdentifier {
+	return types.CryptoIdentifier{KeyId: r.keyId(uid), CryptoContext: r.context}
+}
+
+// uniqueIdentifier returns the unique identifier of the payload or the ID placeholder of the batch.
+func (r kmipRequest) uniqueIdentifier() (string, error) {
+	uid := r.payload.text(kmipTagUniqueIdentifier)
+	if uid == "" {
+		uid = *r.placeholder
+	}
+	if uid == "" {
+		return "", kmipErrorf(kmipReasonInvalidField, "missing unique identifier")
+	}
+	*r.placeholder = uid
+	return uid, nil
+}
+
+// attributes returns the attributes of the payload in KMIP 2.0 form, KMIP 1.x template attributes are converted.
+func (r kmipRequest) attributes(templateTags ...uint32) ([]ttlv, error) {
+	if attributes, ok := r.payload.child(kmipTagAttributes); ok {
+		return attributes.items(), nil
+	}
+	var attributes []ttlv
+	var templates []ttlv
+	for _, tag := range templateTags {
+		templates = append(templates, r.payload.children(tag)...)
+	}
+	templates = append(templates, r.payload)
+	for _, template := range templates {
+		for _, attribute := range template.children(kmipTagAttribute) {
+			tag, ok := kmipAttributeTag(attribute.text(kmipTagAttributeName))
+			if !ok {
+				return nil, kmipErrorf(kmipReasonInvalidField, "unsupported attribute %s", attribute.text(kmipTagAttributeName))
+			}
+			value, _ := attribute.child(kmipTagAttributeValue)
+			value.Tag = tag
+			attributes = append(attributes, value)
+		}
+	}
+	return attributes, nil
+}
+
+func kmipAttributeTag(name string) (uint32, bool) {
+	for tag, attributeName := range kmipAttributeNames {
+		if attributeName == name {
+			return tag, true
+		}
+	}
+	return 0, false
+}
+
+// encodeAttributes encodes attributes for the protocol version of the client.
+func (r kmipRequest) encodeAttributes(attributes []ttlv) []ttlv {
+	if r.major >= 2 {
+		return []ttlv{ttlvStruct(kmipTagAttributes, attributes...)}
+	}
+	encoded := make([]ttlv, 0, len(attributes))
+	for _, attribute := range attributes {
+		value := attribute
+		value.Tag = kmipTagAttributeValue
+		encoded = append(encoded, ttlvStruct(kmipTagAttribute, ttlvText(kmipTagAttributeName, kmipAttributeNames[attribute.Tag]), value))
+	}
+	return encoded
+}
+
+func findAttribute(attributes []ttlv, tag uint32) (ttlv, bool) {
+	for _, attribute := range attributes {
+		if attribute.Tag == tag {
+			return attribute, true
+		}
+	}
+	return ttlv{}, false
+}
+
+func kmipName(uid string) ttlv {
+	return ttlvStruct(kmipTagName, ttlvText(kmipTagNameValue, uid), ttlvEnum(kmipTagNameType, kmipNameTypeText))
+}
+
+// kmipObject describes a key on the partition.
+type kmipObject struct {
+	objectType uint32
+	algorithm  uint32
+	length     int32
+	signer     crypto11.Signer
+}
+
+func (s *KMIPServer) object(keyId string) (*kmipObject, error) {
+	api := s.provider.controller.api
+	signer, err := api.FindKeyPair([]byte(keyId), nil)
+	if err != nil {
+		return nil, err
+	}
+	if signer != nil {
+		switch pub := signer.Public().(type) {
+		case *rsa.PublicKey:
+			return &kmipObject{objectType: kmipObjectPrivateKey, algorithm: kmipAlgorithmRSA, length: int32(pub.N.BitLen()), signer: signer}, nil
+		case *ecdsa.PublicKey:
+			return &kmipObject{objectType: kmipObjectPrivateKey, algorithm: kmipAlgorithmECDSA, length: int32(pub.Curve.Params().BitSize), signer: signer}, nil
+		default:
+			return nil, kmipErrorf(kmipReasonFeatureNotSupported, "keys of type %T are not supported", pub)
+		}
+	}
+	key, err := api.FindKey([]byte(keyId), nil)
+	if err != nil {
+		return nil, err
+	}
+	if key == nil {
+		return nil, nil
+	}
+	object := &kmipObject{objectType: kmipObjectSymmetricKey, algorithm: kmipAlgorithmAES}
+	if attribute, err := api.GetAttribute(key, crypto11.CkaValueLen); err == nil && attribute != nil {
+		object.length = int32(ulongAttribute(attribute.Value) * 8)
+	}
+	return object, nil
+}
+
+// ulongAttribute decodes a CK_ULONG attribute, which PKCS#11 stores in native byte order.
+func ulongAttribute(b []byte) uint64 {
+	switch len(b) {
+	case 4:
+		return uint64(binary.NativeEndian.Uint32(b))
+	case 8:
+		return binary.NativeEndian.Uint64(b)
+	}
+	return 0
+}
+
+func (s *KMIPServer) requireObject(request kmipRequest, uid string) (*kmipObject, error) {
+	object, err := s.object(request.keyId(uid))
+	if err != nil {
+		return nil, err
+	}
+	if object == nil {
+		return nil, kmipErrorf(kmipReasonItemNotFound, "object %s not found", uid)
+	}
+	return object, nil
+}
+
+func (s *KMIPServer) state(keyId string) uint32 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if state, ok := s.states[keyId]; ok {
+		return state
+	}
+	return kmipStatePreActive
+}
+
+func (s *KMIPServer) setState(keyId string, state uint32) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.states[keyId] = state
+}
+
+func (s *KMIPServer) requireState(request kmipRequest, uid string, states ...uint32) error {
+	state := s.state(request.keyId(uid))
+	for _, allowed := range states {
+		if state == allowed {
+			return nil
+		}
+	}
+	return kmipErrorf(kmipReasonPermissionDenied, "object %s is not in a state allowing the operation", uid)
+}
+
+func (s *KMIPServer) create(request kmipRequest) ([]ttlv, error) {
+	if objectType := request.payload.enum(kmipTagObjectType); objectType != kmipObjectSymmetricKey {
+		return nil, kmipErrorf(kmipReasonFeatureNotSupported, "only symmetric keys can be created, use existing key pairs of the partition")
+	}
+	attributes, err := request.attributes(kmipTagTemplateAttribute)
+	if err != nil {
+		return nil, err
+	}
+	if algorithm, ok := findAttribute(attributes, kmipTagCryptographicAlgorithm); ok && algorithm.Value != kmipAlgorithmAES {
+		return nil, kmipErrorf(kmipReasonInvalidField, "only AES keys are supported")
+	}
+	if length, ok := findAttribute(attributes, kmipTagCryptographicLength); ok && length.Value != int32(256) {
+		return nil, kmipErrorf(kmipReasonInvalidField, "only 256 bit keys are supported")
+	}
+	uid := ""
+	if name, ok := findAttribute(attributes, kmipTagName); ok {
+		uid = name.text(kmipTagNameValue)
+	}
+	if uid == "" {
+		if uid, err = newUUID(s.provider.controller.rand); err != nil {
+			return nil, err
+		}
+	}
+	if strings.Contains(uid, "/") {
+		return nil, kmipErrorf(kmipReasonInvalidField, "names must not contain /")
+	}
+	existing, err := s.object(request.keyId(uid))
+	if err != nil {
+		return nil, err
+	}
+	if existing != nil {
+		return nil, kmipErrorf(kmipReasonObjectAlreadyExists, "object %s already exists", uid)
+	}
+	if err = s.provider.GenerateKey(types.CryptoKeyParameter{Identifier: request.identifier(uid), KeyType: types.Aes256GCM}); err != nil {
+		return nil, err
+	}
+	s.setState(request.keyId(uid), kmipStatePreActive)
+	*request.placeholder = uid
+	return []ttlv{ttlvEnum(kmipTagObjectType, kmipObjectSymmetricKey), ttlvText(kmipTagUniqueIdentifier, uid)}, nil
+}
+
+// register stores a certificate with the key pair named by the Name attribute and returns the unique
+// identifier of the key pair. Keys can not be imported into the partition.
+func (s *KMIPServer) register(request kmipRequest) ([]ttlv, error) {
+	if request.payload.enum(kmipTagObjectType) != kmipObjectCertificate {
+		return nil, kmipErrorf(kmipReasonFeatureNotSupported, "only certificates can be registered, keys are generated on the HSM")
+	}
+	certificate, _ := request.payload.child(kmipTagCertificate)
+	if certificate.enum(kmipTagCertificateType) != kmipCertificateTypeX509 {
+		return nil, kmipErrorf(kmipReasonInvalidField, "only X.509 certificates are supported")
+	}
+	parsed, err := x509.ParseCertificate(certificate.bytes(kmipTagCertificateValue))
+	if err != nil {
+		return nil, kmipErrorf(kmipReasonInvalidField, "invalid certificate: %s", err)
+	}
+	attributes, err := request.attributes(kmipTagTemplateAttribute)
+	if err != nil {
+		return nil, err
+	}
+	name, ok := findAttribute(attributes, kmipTagName)
+	if !ok {
+		return nil, kmipErrorf(kmipReasonInvalidField, "the Name attribute must name the key pair of the certificate")
+	}
+	uid := name.text(kmipTagNameValue)
+	object, err := s.requireObject(request, uid)
+	if err != nil {
+		return nil, err
+	}
+	if object.objectType != kmipObjectPrivateKey {
+		return nil, kmipErrorf(kmipReasonInvalidField, "object %s is no key pair", uid)
+	}
+	if err = s.provider.ImportCertificateChain(request.identifier(uid), []*x509.Certificate{parsed}); err != nil {
+		return nil, kmipErrorf(kmipReasonInvalidField, "%s", err)
+	}
+	*request.placeholder = uid
+	return []ttlv{ttlvText(kmipTagUniqueIdentifier, uid)}, nil
+}
+
+func (s *KMIPServer) get(request kmipRequest) ([]ttlv, error) {
+	uid, err := request.uniqueIdentifier()
+	if err != nil {
+		return nil, err
+	}
+	object, err := s.requireObject(request, uid)
+	if err != nil {
+		return nil, err
+	}
+	if object.objectType != kmipObjectPrivateKey {
+		return nil, kmipErrorf(kmipReasonNotExtractable, "object %s is not extractable", uid)
+	}
+	if format, ok := request.payload.child(kmipTagKeyFormatType); ok && format.Value != kmipKeyFormatX509 {
+		return nil, kmipErrorf(kmipReasonKeyFormatNotSupport, "public keys are only available as X.509 SubjectPublicKeyInfo")
+	}
+	der, err := x509.MarshalPKIXPublicKey(object.signer.Public())
+	if err != nil {
+		return nil, err
+	}
+	keyBlock := ttlvStruct(kmipTagKeyBlock,
+		ttlvEnum(kmipTagKeyFormatType, kmipKeyFormatX509),
+		ttlvStruct(kmipTagKeyValue, ttlvBytes(kmipTagKeyMaterial, der)),
+		ttlvEnum(kmipTagCryptographicAlgorithm, object.algorithm),
+		ttlvInt(kmipTagCryptographicLength, object.length))
+	return []ttlv{
+		ttlvEnum(kmipTagObjectType, kmipObjectPublicKey),
+		ttlvText(kmipTagUniqueIdentifier, uid),
+		ttlvStruct(kmipTagPublicKey, keyBlock),
+	}, nil
+}
+
+func (s *KMIPServer) locate(request kmipRequest) ([]ttlv, error) {
+	attributes, err := request.attributes()
+	if err != nil {
+		return nil, err
+	}
+	keyIds, err := s.provider.keyPairIds()
+	if err != nil {
+		return nil, err
+	}
+	secretKeys, err := s.provider.controller.api.FindAllKeys()
+	if err != nil {
+		return nil, err
+	}
+	for _, key := range secretKeys {
+		attribute, err := s.provider.controller.api.GetAttribute(key, crypto11.CkaId)
+		if err != nil {
+			return nil, err
+		}
+		if attribute != nil {
+			keyIds = append(keyIds, string(attribute.Value))
+		}
+	}
+
+	maximum := request.payload.integer(kmipTagMaximumItems)
+	var found []ttlv
+	for _, keyId := range keyIds {
+		uid, ok := request.uid(keyId)
+		if !ok {
+			continue
+		}
+		if maximum > 0 && int32(len(found)) >= maximum {
+			break
+		}
+		matches, err := s.matches(request, uid, attributes)
+		if err != nil {
+			return nil, err
+		}
+		if matches {
+			found = append(found, ttlvText(kmipTagUniqueIdentifier, uid))
+		}
+	}
+	if len(found) > 0 {
+		*request.placeholder = found[0].Value.(string)
+	}
+	return found, nil
+}
+
+func (s *KMIPServer) matches(request kmipRequest, uid string, filter []ttlv) (bool, error) {
+	if len(filter) == 0 {
+		return true, nil
+	}
+	attributes, err := s.attributesOf(request, uid)
+	if err != nil {
+		return false, err
+	}
+	for _, wanted := range filter {
+		actual, ok := findAttribute(attributes, wanted.Tag)
+		if !ok {
+			return false, nil
+		}
+		if wanted.Tag == kmipTagName {
+			if actual.text(kmipTagNameValue) != wanted.text(kmipTagNameValue) {
+				return false, nil
+			}
+		} else if actual.Value != wanted.Value {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+func (s *KMIPServer) attributesOf(request kmipRequest, uid string) ([]ttlv, error) {
+	object, err := s.requireObject(request, uid)
+	if err != nil {
+		return nil, err
+	}
+	return []ttlv{
+		ttlvText(kmipTagUniqueIdentifier, uid),
+		kmipName(uid),
+		ttlvEnum(kmipTagObjectType, object.objectType),
+		ttlvEnum(kmipTagCryptographicAlgorithm, object.algorithm),
+		ttlvInt(kmipTagCryptographicLength, object.length),
+		ttlvEnum(kmipTagState, s.state(request.keyId(uid))),
+	}, nil
+}
+
+func (s *KMIPServer) getAttributes(request kmipRequest) ([]ttlv, error) {
+	uid, err := request.uniqueIdentifier()
+	if err != nil {
+		return nil, err
+	}
+	attributes, err := s.attributesOf(request, uid)
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, name := range request.payload.children(kmipTagAttributeName) {
+		value, ok := name.Value.(string)
+		if !ok {
+			return nil, kmipErrorf(kmipReasonInvalidField, "attribute names must be text strings")
+		}
+		names = append(names, value)
+	}
+	for _, reference := range request.payload.children(kmipTagAttributeReference) {
+		if tag, ok := reference.Value.(uint32); ok {
+			names = append(names, kmipAttributeNames[tag])
+		}
+	}
+	if len(names) > 0 {
+		var selected []ttlv
+		for _, attribute := range attributes {
+			for _, name := range names {
+				if kmipAttributeNames[attribute.Tag] == name {
+					selected = append(selected, attribute)
+				}
+			}
+		}
+		attributes = selected
+	}
+	return append([]ttlv{ttlvText(kmipTagUniqueIdentifier, uid)}, request.encodeAttributes(attributes)...), nil
+}
+
+func (s *KMIPServer) activate(request kmipRequest) ([]ttlv, error) {
+	uid, err := request.uniqueIdentifier()
+	if err != nil {
+		return nil, err
+	}
+	if _, err = s.requireObject(request, uid); err != nil {
+		return nil, err
+	}
+	if s.state(request.keyId(uid)) != kmipStatePreActive {
+		return nil, kmipErrorf(kmipReasonIllegalOperation, "object %s is not pre-active", uid)
+	}
+	s.setState(request.keyId(uid), kmipStateActive)
+	return []ttlv{ttlvText(kmipTagUniqueIdentifier, uid)}, nil
+}
+
+func (s *KMIPServer) revoke(request kmipRequest) ([]ttlv, error) {
+	uid, err := request.uniqueIdentifier()
+	if err != nil {
+		return nil, err
+	}
+	if _, err = s.requireObject(request, uid); err != nil {
+		return nil, err
+	}
+	reason, _ := request.payload.child(kmipTagRevocationReason)
+	switch reason.enum(kmipTagRevocationReasonCode) {
+	case kmipRevocationCompromise, kmipRevocationCACompr:
+		s.setState(request.keyId(uid), kmipStateCompromised)
+	default:
+		if s.state(request.keyId(uid)) != kmipStateCompromised {
+			s.setState(request.keyId(uid), kmipStateDeactivated)
+		}
+	}
+	return []ttlv{ttlvText(kmipTagUniqueIdentifier, uid)}, nil
+}
+
+// destroy deletes the key from the partition. Active keys must be revoked first.
+func (s *KMIPServer) destroy(request kmipRequest) ([]ttlv, error) {
+	uid, err := request.uniqueIdentifier()
+	if err != nil {
+		return nil, err
+	}
+	if _, err = s.requireObject(request, uid); err != nil {
+		return nil, err
+	}
+	if s.state(request.keyId(uid)) == kmipStateActive {
+		return nil, kmipErrorf(kmipReasonPermissionDenied, "active object %s must be revoked before it is destroyed", uid)
+	}
+	if err = s.provider.DeleteKey(request.identifier(uid)); err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	delete(s.states, request.keyId(uid))
+	s.mu.Unlock()
+	return []ttlv{ttlvText(kmipTagUniqueIdentifier, uid)}, nil
+}
+
+func (s *KMIPServer) symmetricKey(request kmipRequest, states ...uint32) (string, error) {
+	uid, err := request.uniqueIdentifier()
+	if err != nil {
+		return "", err
+	}
+	object, err := s.requireObject(request, uid)
+	if err != nil {
+		return "", err
+	}
+	if object.objectType != kmipObjectSymmetricKey {
+		return "", kmipErrorf(kmipReasonIllegalOperation, "object %s is no symmetric key", uid)
+	}
+	parameters, _ := request.payload.child(kmipTagCryptographicParameters)
+	if mode, ok := parameters.child(kmipTagBlockCipherMode); ok && mode.Value != kmipBlockCipherModeGCM {
+		return "", kmipErrorf(kmipReasonFeatureNotSupported, "only GCM is supported")
+	}
+	return uid, s.requireState(request, uid, states...)
+}
+
+func (s *KMIPServer) encrypt(request kmipRequest) (result []ttlv, err error) {
+	uid, err := s.symmetricKey(request, kmipStateActive)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := s.provider.AEAD(request.identifier(uid))
+	if err != nil {
+		return nil, err
+	}
+	nonce := request.payload.bytes(kmipTagIVCounterNonce)
+	if nonce == nil {
+		nonce = make([]byte, aead.NonceSize())
+		if _, err = io.ReadFull(s.provider.controller.rand, nonce); err != nil {
+			return nil, err
+		}
+	} else if len(nonce) != aead.NonceSize() {
+		return nil, kmipErrorf(kmipReasonInvalidField, "the nonce must have %d bytes", aead.NonceSize())
+	}
+	// crypto11 panics if the HSM rejects the operation
+	defer func() {
+		if r := recover(); r != nil {
+			result, err = nil, kmipErrorf(kmipReasonCryptographicFailure, "%v", r)
+		}
+	}()
+	sealed := aead.Seal(nil, nonce, request.payload.bytes(kmipTagData), request.payload.bytes(kmipTagAuthenticatedEncryptionData))
+	ciphertext, tag := sealed[:len(sealed)-aead.Overhead()], sealed[len(sealed)-aead.Overhead():]
+	return []ttlv{
+		ttlvText(kmipTagUniqueIdentifier, uid),
+		ttlvBytes(kmipTagData, ciphertext),
+		ttlvBytes(kmipTagIVCounterNonce, nonce),
+		ttlvBytes(kmipTagAuthenticatedEncryptionTag, tag),
+	}, nil
+}
+
+func (s *KMIPServer) decrypt(request kmipRequest) ([]ttlv, error) {
+	uid, err := s.symmetricKey(request, kmipStateActive, kmipStateDeactivated, kmipStateCompromised)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := s.provider.AEAD(request.identifier(uid))
+	if err != nil {
+		return nil, err
+	}
+	nonce := request.payload.bytes(kmipTagIVCounterNonce)
+	if len(nonce) != aead.NonceSize() {
+		return nil, kmipErrorf(kmipReasonInvalidField, "the nonce must have %d bytes", aead.NonceSize())
+	}
+	sealed := append(request.payload.bytes(kmipTagData), request.payload.bytes(kmipTagAuthenticatedEncryptionTag)...)
+	plaintext, err := aead.Open(nil, nonce, sealed, request.payload.bytes(kmipTagAuthenticatedEncryptionData))
+	if err != nil {
+		return nil, kmipErrorf(kmipReasonCryptographicFailure, "%s", err)
+	}
+	return []ttlv{ttlvText(kmipTagUniqueIdentifier, uid), ttlvBytes(kmipTagData, plaintext)}, nil
+}
+
+// signatureParameters returns the hash and signer options of the cryptographic parameters, SHA-256 and
+// PSS for RSA keys if not given.
+func signatureParameters(request kmipRequest, pub crypto.PublicKey) (crypto.Hash, crypto.SignerOpts, error) {
+	parameters, _ := request.payload.child(kmipTagCryptographicParameters)
+	hash := crypto.SHA256
+	if algorithm, ok := parameters.child(kmipTagHashingAlgorithm); ok {
+		switch algorithm.Value {
+		case kmipHashSHA256:
+		case kmipHashSHA384:
+			hash = crypto.SHA384
+		case kmipHashSHA512:
+			hash = crypto.SHA512
+		default:
+			return 0, nil, kmipErrorf(kmipReasonFeatureNotSupported, "unsupported hashing algorithm %#x", algorithm.Value)
+		}
+	}
+	if _, ok := pub.(*rsa.PublicKey); !ok {
+		return hash, hash, nil
+	}
+	padding, ok := parameters.child(kmipTagPaddingMethod)
+	if !ok || padding.Value == kmipPaddingPSS {
+		return hash, &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash, Hash: hash}, nil
+	}
+	if padding.Value == kmipPaddingPKCS1v15 {
+		return hash, hash, nil
+	}
+	return 0, nil, kmipErrorf(kmipReasonFeatureNotSupported, "unsupported padding method %#x", padding.Value)
+}
+
+func (s *KMIPServer) keyPair(request kmipRequest, states ...uint32) (string, *kmipObject, error) {
+	uid, err := request.uniqueIdentifier()
+	if err != nil {
+		return "", nil, err
+	}
+	object, err := s.requireObject(request, uid)
+	if err != nil {
+		return "", nil, err
+	}
+	if object.objectType != kmipObjectPrivateKey {
+		return "", nil, kmipErrorf(kmipReasonIllegalOperation, "object %s is no key pair", uid)
+	}
+	return uid, object, s.requireState(request, uid, states...)
+}
+
+func (s *KMIPServer) sign(request kmipRequest) ([]ttlv, error) {
+	uid, object, err := s.keyPair(request, kmipStateActive)
+	if err != nil {
+		return nil, err
+	}
+	hash, opts, err := signatureParameters(request, object.signer.Public())
+	if err != nil {
+		return nil, err
+	}
+	signer, err := s.provider.Signer(request.identifier(uid))
+	if err != nil {
+		return nil, err
+	}
+	digest := hash.New()
+	digest.Write(request.payload.bytes(kmipTagData))
+	signature, err := signer.Sign(s.provider.controller.rand, digest.Sum(nil), opts)
+	if err != nil {
+		return nil, kmipErrorf(kmipReasonCryptographicFailure, "%s", err)
+	}
+	return []ttlv{ttlvText(kmipTagUniqueIdentifier, uid), ttlvBytes(kmipTagSignatureData, signature)}, nil
+}
+
+func (s *KMIPServer) signatureVerify(request kmipRequest) ([]ttlv, error) {
+	uid, object, err := s.keyPair(request, kmipStateActive, kmipStateDeactivated, kmipStateCompromised)
+	if err != nil {
+		return nil, err
+	}
+	hash, opts, err := signatureParameters(request, object.signer.Public())
+	if err != nil {
+		return nil, err
+	}
+	digest := hash.New()
+	digest.Write(request.payload.bytes(kmipTagData))
+	signature := request.payload.bytes(kmipTagSignatureData)
+	valid := false
+	switch pub := object.signer.Public().(type) {
+	case *ecdsa.PublicKey:
+		valid = ecdsa.VerifyASN1(pub, digest.Sum(nil), signature)
+	case *rsa.PublicKey:
+		if pss, ok := opts.(*rsa.PSSOptions); ok {
+			valid = rsa.VerifyPSS(pub, hash, digest.Sum(nil), signature, pss) == nil
+		} else {
+			valid = rsa.VerifyPKCS1v15(pub, hash, digest.Sum(nil), signature) == nil
+		}
+	}
+	indicator := kmipValidityInvalid
+	if valid {
+		indicator = kmipValidityValid
+	}
+	return []ttlv{ttlvText(kmipTagUniqueIdentifier, uid), ttlvEnum(kmipTagValidityIndicator, indicator)}, nil
+}
+
+// newUUID returns a random RFC 4122 version 4 UUID.
+func newUUID(rand io.Reader) (string, error) {
+	b := make([]byte, 16)
+	if _, err := io.ReadFull(rand, b); err != nil {
+		return "", err
+	}
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[:4], b[4:6], b[6:8], b[8:10], b[10:]), nil
+}
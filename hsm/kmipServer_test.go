@@ -0,0 +1,259 @@
+package hsm
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/binary"
+	"encoding/hex"
+	"math/big"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/ThalesIgnite/crypto11"
+	"github.com/eclipse-xfsc/crypto-provider-core/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+var testKMIPContext = types.CryptoContext{Namespace: "tenant"}
+
+func kmipRequestMessage(t *testing.T, major int32, items ...ttlv) []byte {
+	header := ttlvStruct(kmipTagRequestHeader,
+		ttlvStruct(kmipTagProtocolVersion, ttlvInt(kmipTagProtocolVersionMajor, major), ttlvInt(kmipTagProtocolVersionMinor, 0)),
+		ttlvInt(kmipTagBatchCount, int32(len(items))))
+	message, err := ttlvStruct(kmipTagRequestMessage, append([]ttlv{header}, items...)...).marshal()
+	assert.Nil(t, err)
+	return message
+}
+
+func kmipBatchItem(operation uint32, payload ...ttlv) ttlv {
+	return ttlvStruct(kmipTagBatchItem, ttlvEnum(kmipTagOperation, operation), ttlvStruct(kmipTagRequestPayload, payload...))
+}
+
+// kmipCall sends the batch items and returns the response batch items.
+func kmipCall(t *testing.T, server *KMIPServer, major int32, items ...ttlv) []ttlv {
+	response, err := server.Handle(testKMIPContext, kmipRequestMessage(t, major, items...))
+	assert.Nil(t, err)
+	message, rest, err := unmarshalTTLV(response)
+	assert.Nil(t, err)
+	assert.Empty(t, rest)
+	assert.Equal(t, kmipTagResponseMessage, message.Tag)
+	return message.children(kmipTagBatchItem)
+}
+
+func assertKMIPSuccess(t *testing.T, item ttlv) ttlv {
+	assert.Equal(t, kmipResultSuccess, item.enum(kmipTagResultStatus), item.text(kmipTagResultMessage))
+	payload, _ := item.child(kmipTagResponsePayload)
+	return payload
+}
+
+func assertKMIPFailure(t *testing.T, item ttlv, reason uint32) {
+	assert.Equal(t, kmipResultOperationFailed, item.enum(kmipTagResultStatus))
+	assert.Equal(t, reason, item.enum(kmipTagResultReason), item.text(kmipTagResultMessage))
+}
+
+func TestTTLV_Encoding(t *testing.T) {
+	// examples of the KMIP 1.4 specification, section 9.1.2
+	examples := map[string]ttlv{
+		"42002002000000040000000800000000":                                                 ttlvInt(0x420020, 8),
+		"4200200500000004000000ff00000000":                                                 ttlvEnum(0x420020, 255),
+		"42002006000000080000000000000001":                                                 ttlvBool(0x420020, true),
+		"420020070000000b48656c6c6f20576f726c640000000000":                                 ttlvText(0x420020, "Hello World"),
+		"42002008000000030102030000000000":                                                 ttlvBytes(0x420020, []byte{1, 2, 3}),
+		"42002001000000204200040500000004000000fe000000004200050200000004000000ff00000000": ttlvStruct(0x420020, ttlvEnum(0x420004, 254), ttlvInt(0x420005, 255)),
+	}
+	for encoding, item := range examples {
+		encoded, err := item.marshal()
+		assert.Nil(t, err)
+		assert.Equal(t, encoding, hex.EncodeToString(encoded))
+		decoded, rest, err := unmarshalTTLV(encoded)
+		assert.Nil(t, err)
+		assert.Empty(t, rest)
+		assert.Equal(t, item, decoded)
+	}
+
+	_, _, err := unmarshalTTLV([]byte{0x42, 0x00, 0x20, 0x02, 0, 0, 0, 8, 0, 0, 0, 8})
+	assert.NotNil(t, err)
+}
+
+func TestKMIPServer_SymmetricKeyLifecycle(t *testing.T) {
+	var mockApi = new(ContextTypeMock)
+	key := &crypto11.SecretKey{}
+	mockApi.On("FindKeyPair", []byte("tenant/k1"), []byte(nil)).Return(nil, nil)
+	mockApi.On("FindKey", []byte("tenant/k1"), []byte(nil)).Return(nil, nil).Once()
+	mockApi.On("GenerateSecretKey", []byte("tenant/k1"), 256, crypto11.CipherAES).Return(nil, nil).Once()
+	mockApi.On("FindKey", []byte("tenant/k1"), []byte(nil)).Return(key, nil)
+	mockApi.On("GetAttribute", key, crypto11.CkaValueLen).Return(&crypto11.Attribute{Value: binary.NativeEndian.AppendUint64(nil, 32)}, nil)
+	server := getTestHSMCryptoProvider(mockApi).NewKMIPServer(KMIPOptions{})
+
+	name := ttlvStruct(kmipTagAttribute, ttlvText(kmipTagAttributeName, "Name"), ttlvStruct(kmipTagAttributeValue, ttlvText(kmipTagNameValue, "k1"), ttlvEnum(kmipTagNameType, kmipNameTypeText)))
+	algorithm := ttlvStruct(kmipTagAttribute, ttlvText(kmipTagAttributeName, "Cryptographic Algorithm"), ttlvEnum(kmipTagAttributeValue, kmipAlgorithmAES))
+	items := kmipCall(t, server, 1,
+		kmipBatchItem(kmipOperationCreate, ttlvEnum(kmipTagObjectType, kmipObjectSymmetricKey), ttlvStruct(kmipTagTemplateAttribute, name, algorithm)),
+		kmipBatchItem(kmipOperationGetAttributes, ttlvText(kmipTagAttributeName, "State")),
+		kmipBatchItem(kmipOperationEncrypt, ttlvBytes(kmipTagData, []byte("secret"))),
+		kmipBatchItem(kmipOperationActivate),
+		kmipBatchItem(kmipOperationGetAttributes),
+		kmipBatchItem(kmipOperationGet),
+		kmipBatchItem(kmipOperationCreate, ttlvEnum(kmipTagObjectType, kmipObjectSymmetricKey), ttlvStruct(kmipTagTemplateAttribute, name)),
+	)
+	assert.Len(t, items, 7)
+
+	payload := assertKMIPSuccess(t, items[0])
+	assert.Equal(t, "k1", payload.text(kmipTagUniqueIdentifier))
+
+	payload = assertKMIPSuccess(t, items[1])
+	attributes := payload.children(kmipTagAttribute)
+	assert.Len(t, attributes, 1)
+	assert.Equal(t, kmipStatePreActive, attributes[0].enum(kmipTagAttributeValue))
+
+	assertKMIPFailure(t, items[2], kmipReasonPermissionDenied)
+	assertKMIPSuccess(t, items[3])
+
+	payload = assertKMIPSuccess(t, items[4])
+	values := map[string]interface{}{}
+	for _, attribute := range payload.children(kmipTagAttribute) {
+		value, _ := attribute.child(kmipTagAttributeValue)
+		values[attribute.text(kmipTagAttributeName)] = value.Value
+	}
+	assert.Equal(t, kmipStateActive, values["State"])
+	assert.Equal(t, int32(256), values["Cryptographic Length"])
+	assert.Equal(t, kmipObjectSymmetricKey, values["Object Type"])
+
+	assertKMIPFailure(t, items[5], kmipReasonNotExtractable)
+	assertKMIPFailure(t, items[6], kmipReasonObjectAlreadyExists)
+}
+
+func TestKMIPServer_Sign(t *testing.T) {
+	key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	var mockApi = new(ContextTypeMock)
+	mockApi.On("FindKeyPair", []byte("tenant/signer"), []byte(nil)).Return(&SoftSignerMock{key}, nil)
+	mockApi.WithoutCertificates()
+	server := getTestHSMCryptoProvider(mockApi).NewKMIPServer(KMIPOptions{})
+
+	uid := ttlvText(kmipTagUniqueIdentifier, "signer")
+	items := kmipCall(t, server, 2,
+		kmipBatchItem(kmipOperationSign, uid, ttlvBytes(kmipTagData, []byte("data"))),
+		kmipBatchItem(kmipOperationActivate, uid),
+		kmipBatchItem(kmipOperationSign, uid, ttlvBytes(kmipTagData, []byte("data"))),
+		kmipBatchItem(kmipOperationGet, uid),
+		kmipBatchItem(kmipOperationGetAttributes, uid),
+	)
+	// keys without a known state are pre-active
+	assertKMIPFailure(t, items[0], kmipReasonPermissionDenied)
+	assertKMIPSuccess(t, items[1])
+	items = items[2:]
+	signature := assertKMIPSuccess(t, items[0]).bytes(kmipTagSignatureData)
+	assert.NotEmpty(t, signature)
+
+	publicKey, _ := assertKMIPSuccess(t, items[1]).child(kmipTagPublicKey)
+	keyBlock, _ := publicKey.child(kmipTagKeyBlock)
+	keyValue, _ := keyBlock.child(kmipTagKeyValue)
+	pub, err := x509.ParsePKIXPublicKey(keyValue.bytes(kmipTagKeyMaterial))
+	assert.Nil(t, err)
+	assert.True(t, key.PublicKey.Equal(pub))
+
+	attributes, ok := assertKMIPSuccess(t, items[2]).child(kmipTagAttributes)
+	assert.True(t, ok)
+	assert.Equal(t, kmipAlgorithmECDSA, attributes.enum(kmipTagCryptographicAlgorithm))
+	assert.Equal(t, int32(256), attributes.integer(kmipTagCryptographicLength))
+
+	items = kmipCall(t, server, 2,
+		kmipBatchItem(kmipOperationSignatureVerify, uid, ttlvBytes(kmipTagData, []byte("data")), ttlvBytes(kmipTagSignatureData, signature)),
+		kmipBatchItem(kmipOperationSignatureVerify, uid, ttlvBytes(kmipTagData, []byte("other")), ttlvBytes(kmipTagSignatureData, signature)),
+		kmipBatchItem(kmipOperationDestroy, uid),
+		kmipBatchItem(kmipOperationRevoke, uid, ttlvStruct(kmipTagRevocationReason, ttlvEnum(kmipTagRevocationReasonCode, kmipRevocationCompromise))),
+		kmipBatchItem(kmipOperationSign, uid, ttlvBytes(kmipTagData, []byte("data"))),
+		kmipBatchItem(kmipOperationDestroy, uid),
+	)
+	assert.Equal(t, kmipValidityValid, assertKMIPSuccess(t, items[0]).enum(kmipTagValidityIndicator))
+	assert.Equal(t, kmipValidityInvalid, assertKMIPSuccess(t, items[1]).enum(kmipTagValidityIndicator))
+	assertKMIPFailure(t, items[2], kmipReasonPermissionDenied)
+	assertKMIPSuccess(t, items[3])
+	assertKMIPFailure(t, items[4], kmipReasonPermissionDenied)
+	assertKMIPSuccess(t, items[5])
+}
+
+func TestKMIPServer_Locate(t *testing.T) {
+	var mockApi = new(ContextTypeMock)
+	own, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	other, _ := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	ownSigner, otherSigner := &SoftSignerMock{own}, &SoftSignerMock{other}
+	mockApi.On("FindAllKeyPairs").Return([]crypto11.Signer{ownSigner, otherSigner}, nil)
+	mockApi.On("FindAllKeys").Return([]SecretKey{}, nil)
+	mockApi.On("GetAttribute", ownSigner, crypto11.CkaId).Return(&crypto11.Attribute{Value: []byte("tenant/a")}, nil)
+	mockApi.On("GetAttribute", otherSigner, crypto11.CkaId).Return(&crypto11.Attribute{Value: []byte("other/b")}, nil)
+	mockApi.On("FindKeyPair", []byte("tenant/a"), []byte(nil)).Return(ownSigner, nil)
+	mockApi.On("FindKeyPair", []byte("tenant/missing"), []byte(nil)).Return(nil, nil)
+	mockApi.On("FindKey", []byte("tenant/missing"), []byte(nil)).Return(nil, nil)
+	server := getTestHSMCryptoProvider(mockApi).NewKMIPServer(KMIPOptions{})
+
+	items := kmipCall(t, server, 1,
+		kmipBatchItem(kmipOperationLocate),
+		kmipBatchItem(kmipOperationLocate, ttlvStruct(kmipTagAttribute, ttlvText(kmipTagAttributeName, "Cryptographic Algorithm"), ttlvEnum(kmipTagAttributeValue, kmipAlgorithmRSA))),
+		kmipBatchItem(kmipOperationGet, ttlvText(kmipTagUniqueIdentifier, "missing")),
+		kmipBatchItem(0x02),
+	)
+	found := assertKMIPSuccess(t, items[0]).children(kmipTagUniqueIdentifier)
+	assert.Len(t, found, 1)
+	assert.Equal(t, "a", found[0].Value)
+	assert.Empty(t, assertKMIPSuccess(t, items[1]).children(kmipTagUniqueIdentifier))
+	assertKMIPFailure(t, items[2], kmipReasonItemNotFound)
+	assertKMIPFailure(t, items[3], kmipReasonOperationNotSupport)
+
+	response, err := server.Handle(testKMIPContext, []byte("garbage"))
+	assert.Nil(t, err)
+	message, _, err := unmarshalTTLV(response)
+	assert.Nil(t, err)
+	item, _ := message.child(kmipTagBatchItem)
+	assertKMIPFailure(t, item, kmipReasonInvalidMessage)
+	mockApi.AssertNotCalled(t, "FindKeyPair", []byte("other/b"), mock.Anything)
+}
+
+func TestKMIPServer_GetAttributes_InvalidName(t *testing.T) {
+	key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	var mockApi = new(ContextTypeMock)
+	mockApi.On("FindKeyPair", []byte("tenant/signer"), []byte(nil)).Return(&SoftSignerMock{key}, nil)
+	mockApi.WithoutCertificates()
+	server := getTestHSMCryptoProvider(mockApi).NewKMIPServer(KMIPOptions{})
+
+	items := kmipCall(t, server, 1, kmipBatchItem(kmipOperationGetAttributes, ttlvText(kmipTagUniqueIdentifier, "signer"), ttlvInt(kmipTagAttributeName, 1)))
+	assertKMIPFailure(t, items[0], kmipReasonInvalidField)
+}
+
+func TestKMIPServer_HandshakeTimeout(t *testing.T) {
+	key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	template := &x509.Certificate{SerialNumber: big.NewInt(1), NotAfter: time.Now().Add(time.Hour)}
+	der, _ := x509.CreateCertificate(rand.Reader, template, template, key.Public(), key)
+	config := &tls.Config{Certificates: []tls.Certificate{{Certificate: [][]byte{der}, PrivateKey: key}}, ClientAuth: tls.RequireAnyClientCert}
+	server := getTestHSMCryptoProvider(new(ContextTypeMock)).NewKMIPServer(KMIPOptions{HandshakeTimeout: 50 * time.Millisecond})
+	client, conn := net.Pipe()
+	defer client.Close()
+
+	done := make(chan struct{})
+	go func() {
+		server.serveConn(tls.Server(conn, config))
+		close(done)
+	}()
+	// the client never starts the handshake
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("connection was not closed after the handshake timeout")
+	}
+}
+
+func TestKMIPServer_CommonNameContext(t *testing.T) {
+	context, err := commonNameContext(&x509.Certificate{Subject: pkix.Name{CommonName: "tenant"}})
+	assert.Nil(t, err)
+	assert.Equal(t, "tenant", context.Namespace)
+	_, err = commonNameContext(&x509.Certificate{})
+	assert.EqualError(t, err, "client certificate has no common name")
+	_, err = commonNameContext(&x509.Certificate{Subject: pkix.Name{CommonName: "tenant/other"}})
+	assert.EqualError(t, err, "common name of the client certificate must not contain /")
+}
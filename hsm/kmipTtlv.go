@@ -0,0 +1,223 @@
+package hsm
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+)
+
+// KMIP item types of the TTLV encoding, KMIP 1.4 section 9.1.1.
+const (
+	ttlvStructure   byte = 0x01
+	ttlvInteger     byte = 0x02
+	ttlvLongInteger byte = 0x03
+	ttlvBigInteger  byte = 0x04
+	ttlvEnumeration byte = 0x05
+	ttlvBoolean     byte = 0x06
+	ttlvTextString  byte = 0x07
+	ttlvByteString  byte = 0x08
+	ttlvDateTime    byte = 0x09
+	ttlvInterval    byte = 0x0A
+)
+
+const maxKMIPMessageSize = 1 << 20
+
+// ttlv is a decoded KMIP item. Value holds []ttlv for structures, int32 for integers, int64 for long
+// integers, uint32 for enumerations and intervals, bool, string, time.Time and []byte for byte strings
+// and big integers.
+type ttlv struct {
+	Tag   uint32
+	Type  byte
+	Value interface{}
+}
+
+func ttlvStruct(tag uint32, items ...ttlv) ttlv {
+	return ttlv{Tag: tag, Type: ttlvStructure, Value: items}
+}
+
+func ttlvInt(tag uint32, v int32) ttlv {
+	return ttlv{Tag: tag, Type: ttlvInteger, Value: v}
+}
+
+func ttlvEnum(tag uint32, v uint32) ttlv {
+	return ttlv{Tag: tag, Type: ttlvEnumeration, Value: v}
+}
+
+func ttlvBool(tag uint32, v bool) ttlv {
+	return ttlv{Tag: tag, Type: ttlvBoolean, Value: v}
+}
+
+func ttlvText(tag uint32, v string) ttlv {
+	return ttlv{Tag: tag, Type: ttlvTextString, Value: v}
+}
+
+func ttlvBytes(tag uint32, v []byte) ttlv {
+	return ttlv{Tag: tag, Type: ttlvByteString, Value: v}
+}
+
+func ttlvTime(tag uint32, v time.Time) ttlv {
+	return ttlv{Tag: tag, Type: ttlvDateTime, Value: v}
+}
+
+// items returns the children of a structure.
+func (t ttlv) items() []ttlv {
+	items, _ := t.Value.([]ttlv)
+	return items
+}
+
+func (t ttlv) child(tag uint32) (ttlv, bool) {
+	for _, item := range t.items() {
+		if item.Tag == tag {
+			return item, true
+		}
+	}
+	return ttlv{}, false
+}
+
+func (t ttlv) children(tag uint32) []ttlv {
+	var children []ttlv
+	for _, item := range t.items() {
+		if item.Tag == tag {
+			children = append(children, item)
+		}
+	}
+	return children
+}
+
+func (t ttlv) text(tag uint32) string {
+	child, _ := t.child(tag)
+	v, _ := child.Value.(string)
+	return v
+}
+
+func (t ttlv) bytes(tag uint32) []byte {
+	child, _ := t.child(tag)
+	v, _ := child.Value.([]byte)
+	return v
+}
+
+func (t ttlv) enum(tag uint32) uint32 {
+	child, _ := t.child(tag)
+	v, _ := child.Value.(uint32)
+	return v
+}
+
+func (t ttlv) integer(tag uint32) int32 {
+	child, _ := t.child(tag)
+	v, _ := child.Value.(int32)
+	return v
+}
+
+func padding(n int) int {
+	return (8 - n%8) % 8
+}
+
+func (t ttlv) marshal() ([]byte, error) {
+	var value []byte
+	switch t.Type {
+	case ttlvStructure:
+		for _, item := range t.items() {
+			encoded, err := item.marshal()
+			if err != nil {
+				return nil, err
+			}
+			value = append(value, encoded...)
+		}
+	case ttlvInteger:
+		value = binary.BigEndian.AppendUint32(nil, uint32(t.Value.(int32)))
+	case ttlvEnumeration, ttlvInterval:
+		value = binary.BigEndian.AppendUint32(nil, t.Value.(uint32))
+	case ttlvLongInteger:
+		value = binary.BigEndian.AppendUint64(nil, uint64(t.Value.(int64)))
+	case ttlvBoolean:
+		var b uint64
+		if t.Value.(bool) {
+			b = 1
+		}
+		value = binary.BigEndian.AppendUint64(nil, b)
+	case ttlvDateTime:
+		value = binary.BigEndian.AppendUint64(nil, uint64(t.Value.(time.Time).Unix()))
+	case ttlvTextString:
+		value = []byte(t.Value.(string))
+	case ttlvByteString, ttlvBigInteger:
+		value = t.Value.([]byte)
+	default:
+		return nil, fmt.Errorf("unsupported TTLV type %d", t.Type)
+	}
+	encoded := make([]byte, 8, 8+len(value)+padding(len(value)))
+	binary.BigEndian.PutUint32(encoded, t.Tag<<8|uint32(t.Type))
+	binary.BigEndian.PutUint32(encoded[4:], uint32(len(value)))
+	encoded = append(encoded, value...)
+	return append(encoded, make([]byte, padding(len(value)))...), nil
+}
+
+func unmarshalTTLV(data []byte) (ttlv, []byte, error) {
+	if len(data) < 8 {
+		return ttlv{}, nil, errors.New("truncated TTLV header")
+	}
+	header := binary.BigEndian.Uint32(data)
+	t := ttlv{Tag: header >> 8, Type: byte(header)}
+	length := int(binary.BigEndian.Uint32(data[4:]))
+	padded := length
+	if t.Type != ttlvStructure {
+		padded += padding(length)
+	}
+	if length < 0 || len(data)-8 < padded {
+		return ttlv{}, nil, errors.New("truncated TTLV value")
+	}
+	value, rest := data[8:8+length], data[8+padded:]
+	fixedLength := map[byte]int{ttlvInteger: 4, ttlvEnumeration: 4, ttlvInterval: 4, ttlvLongInteger: 8, ttlvBoolean: 8, ttlvDateTime: 8}
+	if expected, ok := fixedLength[t.Type]; ok && length != expected {
+		return ttlv{}, nil, fmt.Errorf("invalid length %d of TTLV type %d", length, t.Type)
+	}
+	switch t.Type {
+	case ttlvStructure:
+		var items []ttlv
+		for len(value) > 0 {
+			item, remaining, err := unmarshalTTLV(value)
+			if err != nil {
+				return ttlv{}, nil, err
+			}
+			items = append(items, item)
+			value = remaining
+		}
+		t.Value = items
+	case ttlvInteger:
+		t.Value = int32(binary.BigEndian.Uint32(value))
+	case ttlvEnumeration, ttlvInterval:
+		t.Value = binary.BigEndian.Uint32(value)
+	case ttlvLongInteger:
+		t.Value = int64(binary.BigEndian.Uint64(value))
+	case ttlvBoolean:
+		t.Value = binary.BigEndian.Uint64(value) != 0
+	case ttlvDateTime:
+		t.Value = time.Unix(int64(binary.BigEndian.Uint64(value)), 0).UTC()
+	case ttlvTextString:
+		t.Value = string(value)
+	case ttlvByteString, ttlvBigInteger:
+		t.Value = append([]byte(nil), value...)
+	default:
+		return ttlv{}, nil, fmt.Errorf("unsupported TTLV type %d", t.Type)
+	}
+	return t, rest, nil
+}
+
+// readTTLV reads one TTLV encoded message from the connection.
+func readTTLV(r io.Reader) ([]byte, error) {
+	header := make([]byte, 8)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return nil, err
+	}
+	length := binary.BigEndian.Uint32(header[4:])
+	if length > maxKMIPMessageSize {
+		return nil, fmt.Errorf("KMIP message of %d bytes exceeds the limit", length)
+	}
+	message := make([]byte, 8+int(length))
+	copy(message, header)
+	if _, err := io.ReadFull(r, message[8:]); err != nil {
+		return nil, err
+	}
+	return message, nil
+}
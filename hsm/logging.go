@@ -0,0 +1,207 @@
+package hsm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/eclipse-xfsc/crypto-provider-core/types"
+	"github.com/miekg/pkcs11"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// sensitiveLogKeys are attribute keys whose values are never logged, whatever their type.
+var sensitiveLogKeys = map[string]bool{
+	"pin":        true,
+	"password":   true,
+	"secret":     true,
+	"plaintext":  true,
+	"key_value":  true,
+	"private":    true,
+	"passphrase": true,
+}
+
+// logHandler filters records below its level and redacts PINs and key material before passing them on.
+type logHandler struct {
+	handler slog.Handler
+	level   slog.Leveler
+}
+
+// newLogger returns the logger with redaction and, if level is set, the minimum level. The default
+// logger of the process is used if logger is nil, so host applications can inject theirs with
+// slog.SetDefault.
+func newLogger(logger *slog.Logger, level slog.Leveler) *slog.Logger {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	return slog.New(logHandler{handler: logger.Handler(), level: level})
+}
+
+// ParseLogLevel parses a level of slog, e.g. "DEBUG" or "warn", nil if text is empty.
+func ParseLogLevel(text string) (slog.Leveler, error) {
+	if text == "" {
+		return nil, nil
+	}
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(text)); err != nil {
+		return nil, fmt.Errorf("invalid log level %q", text)
+	}
+	return level, nil
+}
+
+func (h logHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	if h.level != nil && level < h.level.Level() {
+		return false
+	}
+	return h.handler.Enabled(ctx, level)
+}
+
+func (h logHandler) Handle(ctx context.Context, record slog.Record) error {
+	redacted := slog.NewRecord(record.Time, record.Level, record.Message, record.PC)
+	record.Attrs(func(attr slog.Attr) bool {
+		redacted.AddAttrs(redactAttr(attr))
+		return true
+	})
+	return h.handler.Handle(ctx, redacted)
+}
+
+func (h logHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	redacted := make([]slog.Attr, len(attrs))
+	for i, attr := range attrs {
+		redacted[i] = redactAttr(attr)
+	}
+	return logHandler{handler: h.handler.WithAttrs(redacted), level: h.level}
+}
+
+func (h logHandler) WithGroup(name string) slog.Handler {
+	return logHandler{handler: h.handler.WithGroup(name), level: h.level}
+}
+
+// redactAttr replaces values of sensitive keys and all byte slices, which may be key material, with
+// "redacted".
+func redactAttr(attr slog.Attr) slog.Attr {
+	value := attr.Value.Resolve()
+	if sensitiveLogKeys[strings.ToLower(attr.Key)] {
+		return slog.String(attr.Key, redacted)
+	}
+	switch value.Kind() {
+	case slog.KindGroup:
+		group := value.Group()
+		attrs := make([]any, len(group))
+		for i, groupAttr := range group {
+			attrs[i] = redactAttr(groupAttr)
+		}
+		return slog.Group(attr.Key, attrs...)
+	case slog.KindAny:
+		if b, ok := value.Any().([]byte); ok {
+			return slog.String(attr.Key, fmt.Sprintf("%s (%d bytes)", redacted, len(b)))
+		}
+	}
+	return slog.Attr{Key: attr.Key, Value: value}
+}
+
+// errorClass categorizes errors for logs: the PKCS#11 return value, "unsupported", "not_found",
+// "canceled", "deadline" or "error".
+func errorClass(err error) string {
+	var p11Err pkcs11.Error
+	switch {
+	case err == nil:
+		return ""
+	case errors.As(err, &p11Err):
+		for name, code := range pkcs11ErrorNames {
+			if code == uint(p11Err) {
+				return name
+			}
+		}
+		return fmt.Sprintf("CKR_0x%X", uint(p11Err))
+	case errors.Is(err, errors.ErrUnsupported):
+		return "unsupported"
+	case errors.Is(err, ErrKeyNotFound):
+		return "not_found"
+	case errors.Is(err, context.Canceled):
+		return "canceled"
+	case errors.Is(err, context.DeadlineExceeded):
+		return "deadline"
+	}
+	return "error"
+}
+
+func (c *hsmController) log() *slog.Logger {
+	if c.logger == nil {
+		return slog.New(slog.DiscardHandler)
+	}
+	return c.logger
+}
+
+// operation is an operation of the provider being observed.
+type operation struct {
+	provider      HSMCryptoProvider
+	name          string
+	cryptoContext types.CryptoContext
+	keyId         string
+	// keyType is set by the operation once the key is known.
+	keyType types.KeyType
+	start   time.Time
+	ctx     context.Context
+	span    trace.Span
+}
+
+// observe starts an operation of the provider, end logs, measures and traces it. The operation has
+// to use the returned provider, whose calls to the partition are traced as children of its span.
+func (p HSMCryptoProvider) observe(name string, cryptoContext types.CryptoContext, keyId string) (HSMCryptoProvider, *operation) {
+	ctx := cryptoContext.Context
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	attrs := []attribute.KeyValue{attribute.String("hsm.operation", name)}
+	if keyId != "" {
+		attrs = append(attrs, attribute.String("hsm.key_id", keyId))
+	}
+	if cryptoContext.Namespace != "" {
+		attrs = append(attrs, attribute.String("hsm.namespace", cryptoContext.Namespace))
+	}
+	if cryptoContext.Group != "" {
+		attrs = append(attrs, attribute.String("hsm.group", cryptoContext.Group))
+	}
+	ctx, span := p.controller.trace().Start(ctx, "hsm."+name, trace.WithAttributes(attrs...))
+	op := &operation{provider: p, name: name, cryptoContext: cryptoContext, keyId: keyId, start: time.Now(), ctx: ctx, span: span}
+	if span.IsRecording() {
+		controller := *p.controller
+		controller.api = newTracingContext(controller.api, ctx, controller.trace())
+		p = HSMCryptoProvider{controller: &controller}
+	}
+	return p, op
+}
+
+// end logs, measures and traces the end of the operation with the error stored in err.
+func (o *operation) end(err *error) {
+	duration := time.Since(o.start)
+	o.provider.controller.meter().Operation(o.name, o.keyType, o.cryptoContext.Namespace, errorClass(*err), duration)
+	if o.keyType != "" {
+		o.span.SetAttributes(attribute.String("hsm.key_type", string(o.keyType)))
+	}
+	endSpan(o.span, *err)
+	attrs := []slog.Attr{
+		slog.String("operation", o.name),
+		slog.Duration("duration", duration),
+	}
+	if o.keyId != "" {
+		attrs = append(attrs, slog.String("key_id", o.keyId))
+	}
+	if o.keyType != "" {
+		attrs = append(attrs, slog.String("key_type", string(o.keyType)))
+	}
+	if o.cryptoContext.Namespace != "" || o.cryptoContext.Group != "" {
+		attrs = append(attrs, slog.Group("crypto_context", slog.String("namespace", o.cryptoContext.Namespace), slog.String("group", o.cryptoContext.Group)))
+	}
+	if *err == nil {
+		o.provider.controller.log().LogAttrs(o.ctx, slog.LevelDebug, "operation succeeded", attrs...)
+		return
+	}
+	attrs = append(attrs, slog.String("error_class", errorClass(*err)), slog.String("error", (*err).Error()))
+	o.provider.controller.log().LogAttrs(o.ctx, slog.LevelWarn, "operation failed", attrs...)
+}
@@ -0,0 +1,123 @@
+package hsm
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/eclipse-xfsc/crypto-provider-core/types"
+	"github.com/miekg/pkcs11"
+	"github.com/stretchr/testify/assert"
+)
+
+func getLoggingTestProvider(level slog.Leveler) (HSMCryptoProvider, *ContextTypeMock, *bytes.Buffer) {
+	var output bytes.Buffer
+	mockApi := new(ContextTypeMock)
+	provider := getTestHSMCryptoProvider(mockApi)
+	provider.controller.logger = newLogger(slog.New(slog.NewJSONHandler(&output, &slog.HandlerOptions{Level: slog.LevelDebug})), level)
+	return provider, mockApi, &output
+}
+
+func logLines(t *testing.T, output *bytes.Buffer) []map[string]interface{} {
+	var lines []map[string]interface{}
+	for _, line := range strings.Split(strings.TrimSpace(output.String()), "\n") {
+		if line == "" {
+			continue
+		}
+		var entry map[string]interface{}
+		assert.Nil(t, json.Unmarshal([]byte(line), &entry))
+		lines = append(lines, entry)
+	}
+	return lines
+}
+
+func TestLogging_Redaction(t *testing.T) {
+	var output bytes.Buffer
+	logger := newLogger(slog.New(slog.NewJSONHandler(&output, nil)), nil)
+	logger.With(slog.String("pin", "1234")).Info("configured",
+		slog.Any("value", []byte{1, 2, 3}),
+		slog.Group("partition", slog.String("Password", "secret"), slog.String("label", "p1")))
+
+	lines := logLines(t, &output)
+	assert.Len(t, lines, 1)
+	assert.Equal(t, "redacted", lines[0]["pin"])
+	assert.Equal(t, "redacted (3 bytes)", lines[0]["value"])
+	assert.Equal(t, map[string]interface{}{"Password": "redacted", "label": "p1"}, lines[0]["partition"])
+	assert.NotContains(t, output.String(), "1234")
+}
+
+func TestLogging_Operations(t *testing.T) {
+	provider, mockApi, output := getLoggingTestProvider(slog.LevelDebug)
+	mockApi.On("FindKeyPair", []byte(testId), []byte(nil)).Return(&SignerMock{}, nil).Once()
+	mockApi.On("FindKeyPair", []byte(testId), []byte(nil)).Return(nil, pkcs11.Error(pkcs11.CKR_DEVICE_ERROR)).Once()
+	identifier := types.CryptoIdentifier{KeyId: testId, CryptoContext: types.CryptoContext{Namespace: "tenant"}}
+
+	_, err := provider.Sign(identifier, []byte("data"))
+	assert.Nil(t, err)
+	_, err = provider.Sign(identifier, []byte("data"))
+	assert.NotNil(t, err)
+	assert.ErrorIs(t, provider.RotateKey(identifier), errors.ErrUnsupported)
+
+	lines := logLines(t, output)
+	assert.Len(t, lines, 3)
+	assert.Equal(t, "DEBUG", lines[0]["level"])
+	assert.Equal(t, "Sign", lines[0]["operation"])
+	assert.Equal(t, testId, lines[0]["key_id"])
+	assert.Equal(t, map[string]interface{}{"namespace": "tenant", "group": ""}, lines[0]["crypto_context"])
+	assert.Contains(t, lines[0], "duration")
+	assert.Equal(t, "WARN", lines[1]["level"])
+	assert.Equal(t, "CKR_DEVICE_ERROR", lines[1]["error_class"])
+	assert.Equal(t, "unsupported", lines[2]["error_class"])
+	assert.NotContains(t, output.String(), "data")
+}
+
+func TestLogging_ExtensionOperations(t *testing.T) {
+	provider, mockApi, output := getLoggingTestProvider(slog.LevelDebug)
+	key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	mockApi.On("FindKeyPair", []byte(testId), []byte(nil)).Return(&SoftSignerMock{key}, nil)
+	mockApi.WithoutCertificates()
+	identifier := types.CryptoIdentifier{KeyId: testId, CryptoContext: types.CryptoContext{Namespace: "tenant"}}
+
+	_, err := provider.SignJWS(identifier, []byte("payload"), JWSOptions{})
+	assert.Nil(t, err)
+	_, err = provider.SignCMS(identifier, []byte("content"), CMSOptions{})
+	assert.NotNil(t, err)
+	_, err = provider.SignCOSE(identifier, []byte("payload"), COSEOptions{})
+	assert.Nil(t, err)
+
+	// nested operations such as ExportKey of SignCOSE are not logged separately
+	lines := logLines(t, output)
+	assert.Len(t, lines, 3)
+	assert.Equal(t, "SignJWS", lines[0]["operation"])
+	assert.Equal(t, testId, lines[0]["key_id"])
+	assert.Equal(t, "SignCMS", lines[1]["operation"])
+	assert.Equal(t, "WARN", lines[1]["level"])
+	assert.Equal(t, "SignCOSE", lines[2]["operation"])
+	assert.NotContains(t, output.String(), "payload")
+}
+
+func TestLogging_Level(t *testing.T) {
+	provider, _, output := getLoggingTestProvider(slog.LevelWarn)
+	_, err := provider.Hash(types.CryptoHashParameter{HashAlgorithm: types.Sha2256}, []byte("data"))
+	assert.Nil(t, err)
+	_, err = provider.Hash(types.CryptoHashParameter{HashAlgorithm: types.Sha2512}, []byte("data"))
+	assert.NotNil(t, err)
+	lines := logLines(t, output)
+	assert.Len(t, lines, 1)
+	assert.Equal(t, "Hash", lines[0]["operation"])
+
+	level, err := ParseLogLevel("debug")
+	assert.Nil(t, err)
+	assert.Equal(t, slog.LevelDebug, level)
+	level, err = ParseLogLevel("")
+	assert.Nil(t, err)
+	assert.Nil(t, level)
+	_, err = ParseLogLevel("loud")
+	assert.EqualError(t, err, "invalid log level \"loud\"")
+}
@@ -0,0 +1,590 @@
+package hsm
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/des"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/asn1"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"sync"
+
+	"github.com/ThalesIgnite/crypto11"
+	"github.com/miekg/pkcs11"
+)
+
+// MemoryContext is a ContextType which keeps software keys in memory, for development and tests without
+// HSM. Key pairs, secret keys and certificates behave like on a partition.
+type MemoryContext struct {
+	mutex        sync.Mutex
+	keyPairs     []*memoryKeyPair
+	secretKeys   []*memorySecretKey
+	certificates []*memoryCertificate
+}
+
+type memoryKeyPair struct {
+	context *MemoryContext
+	crypto.Signer
+	private crypto11.AttributeSet
+	public  crypto11.AttributeSet
+}
+
+// memoryRSAKeyPair additionally decrypts, like crypto11 RSA keys.
+type memoryRSAKeyPair struct {
+	*memoryKeyPair
+}
+
+// memorySecretKey is a software SecretKey, only AES and DES3 keys can encrypt.
+type memorySecretKey struct {
+	context    *MemoryContext
+	cipher     *crypto11.SymmetricCipher
+	block      cipher.Block
+	attributes crypto11.AttributeSet
+}
+
+type memoryCertificate struct {
+	attributes  crypto11.AttributeSet
+	certificate *x509.Certificate
+}
+
+var memoryCurveOids = map[elliptic.Curve]asn1.ObjectIdentifier{
+	elliptic.P224(): {1, 3, 132, 0, 33},
+	elliptic.P256(): {1, 2, 840, 10045, 3, 1, 7},
+	elliptic.P384(): {1, 3, 132, 0, 34},
+	elliptic.P521(): {1, 3, 132, 0, 35},
+}
+
+// NewMemoryContext returns an empty in-memory partition.
+func NewMemoryContext() *MemoryContext {
+	return &MemoryContext{}
+}
+
+func (k *memoryKeyPair) Delete() error {
+	k.context.mutex.Lock()
+	defer k.context.mutex.Unlock()
+	for i, keyPair := range k.context.keyPairs {
+		if keyPair == k {
+			k.context.keyPairs = append(k.context.keyPairs[:i], k.context.keyPairs[i+1:]...)
+			return nil
+		}
+	}
+	return nil
+}
+
+func (k memoryRSAKeyPair) Decrypt(rand io.Reader, msg []byte, opts crypto.DecrypterOpts) ([]byte, error) {
+	return k.Signer.(*rsa.PrivateKey).Decrypt(rand, msg, opts)
+}
+
+// signer returns the key pair as crypto11 returns it: RSA key pairs also implement crypto.Decrypter.
+func (k *memoryKeyPair) signer() crypto11.Signer {
+	if _, ok := k.Signer.(*rsa.PrivateKey); ok {
+		return memoryRSAKeyPair{k}
+	}
+	return k
+}
+
+func (k *memorySecretKey) BlockSize() int {
+	return k.cipher.BlockSize
+}
+
+func (k *memorySecretKey) Encrypt(dst, src []byte) {
+	if k.block == nil {
+		panic("secret key can not encrypt")
+	}
+	k.block.Encrypt(dst, src)
+}
+
+func (k *memorySecretKey) Decrypt(dst, src []byte) {
+	if k.block == nil {
+		panic("secret key can not decrypt")
+	}
+	k.block.Decrypt(dst, src)
+}
+
+func (k *memorySecretKey) NewGCM() (cipher.AEAD, error) {
+	if k.block == nil || k.cipher.GCMMech == 0 {
+		return nil, errors.New("secret key does not support GCM")
+	}
+	return cipher.NewGCM(k.block)
+}
+
+func (k *memorySecretKey) NewCBCEncrypter(iv []byte) (cipher.BlockMode, error) {
+	if err := k.checkCBC(iv); err != nil {
+		return nil, err
+	}
+	return cipher.NewCBCEncrypter(k.block, iv), nil
+}
+
+func (k *memorySecretKey) NewCBCDecrypter(iv []byte) (cipher.BlockMode, error) {
+	if err := k.checkCBC(iv); err != nil {
+		return nil, err
+	}
+	return cipher.NewCBCDecrypter(k.block, iv), nil
+}
+
+func (k *memorySecretKey) checkCBC(iv []byte) error {
+	if k.block == nil || k.cipher.CBCMech == 0 {
+		return errors.New("secret key does not support CBC")
+	}
+	if len(iv) != k.block.BlockSize() {
+		return fmt.Errorf("invalid IV length %d", len(iv))
+	}
+	return nil
+}
+
+func (k *memorySecretKey) Delete() error {
+	k.context.mutex.Lock()
+	defer k.context.mutex.Unlock()
+	for i, key := range k.context.secretKeys {
+		if key == k {
+			k.context.secretKeys = append(k.context.secretKeys[:i], k.context.secretKeys[i+1:]...)
+			return nil
+		}
+	}
+	return nil
+}
+
+// newBlock returns the block cipher of the key value, nil for ciphers without encryption.
+func newBlock(symmetricCipher *crypto11.SymmetricCipher, value []byte) (cipher.Block, error) {
+	switch symmetricCipher {
+	case crypto11.CipherAES:
+		return aes.NewCipher(value)
+	case crypto11.CipherDES3:
+		return des.NewTripleDESCipher(value)
+	}
+	return nil, nil
+}
+
+func keyPairOf(key interface{}) (*memoryKeyPair, bool) {
+	switch k := key.(type) {
+	case *memoryKeyPair:
+		return k, true
+	case memoryRSAKeyPair:
+		return k.memoryKeyPair, true
+	}
+	return nil, false
+}
+
+// matches reports whether the attributes contain all attributes of the template.
+func matches(attributes crypto11.AttributeSet, template crypto11.AttributeSet) bool {
+	for attributeType, attribute := range template {
+		value, ok := attributes[attributeType]
+		if !ok || !bytes.Equal(value.Value, attribute.Value) {
+			return false
+		}
+	}
+	return true
+}
+
+func idAndLabelTemplate(id []byte, label []byte) (crypto11.AttributeSet, error) {
+	if id == nil && label == nil {
+		return nil, errors.New("id and label cannot both be nil")
+	}
+	template := crypto11.NewAttributeSet()
+	if id != nil {
+		_ = template.Set(crypto11.CkaId, id)
+	}
+	if label != nil {
+		_ = template.Set(crypto11.CkaLabel, label)
+	}
+	return template, nil
+}
+
+func idAndLabelAttributes(id []byte, label []byte) (crypto11.AttributeSet, error) {
+	if id == nil {
+		return nil, errors.New("id cannot be nil")
+	}
+	if label == nil {
+		return crypto11.NewAttributeSetWithID(id)
+	}
+	return crypto11.NewAttributeSetWithIDAndLabel(id, label)
+}
+
+func (c *MemoryContext) addKeyPair(signer crypto.Signer, public, private crypto11.AttributeSet, keyType uint, publicAttributes []*crypto11.Attribute) (*memoryKeyPair, error) {
+	id, ok := private[crypto11.CkaId]
+	if !ok || len(id.Value) == 0 {
+		return nil, errors.New("private key attributes need a CKA_ID")
+	}
+	private.AddIfNotPresent([]*crypto11.Attribute{
+		pkcs11.NewAttribute(pkcs11.CKA_CLASS, pkcs11.CKO_PRIVATE_KEY),
+		pkcs11.NewAttribute(pkcs11.CKA_KEY_TYPE, keyType),
+		pkcs11.NewAttribute(pkcs11.CKA_TOKEN, true),
+		pkcs11.NewAttribute(pkcs11.CKA_PRIVATE, true),
+		pkcs11.NewAttribute(pkcs11.CKA_SIGN, true),
+		pkcs11.NewAttribute(pkcs11.CKA_SENSITIVE, true),
+		pkcs11.NewAttribute(pkcs11.CKA_EXTRACTABLE, false),
+	})
+	public.AddIfNotPresent(append([]*crypto11.Attribute{
+		crypto11.CopyAttribute(id),
+		pkcs11.NewAttribute(pkcs11.CKA_CLASS, pkcs11.CKO_PUBLIC_KEY),
+		pkcs11.NewAttribute(pkcs11.CKA_KEY_TYPE, keyType),
+		pkcs11.NewAttribute(pkcs11.CKA_TOKEN, true),
+		pkcs11.NewAttribute(pkcs11.CKA_VERIFY, true),
+	}, publicAttributes...))
+	if label, ok := private[crypto11.CkaLabel]; ok {
+		public.AddIfNotPresent([]*crypto11.Attribute{crypto11.CopyAttribute(label)})
+	}
+	keyPair := &memoryKeyPair{context: c, Signer: signer, private: private.Copy(), public: public.Copy()}
+	c.mutex.Lock()
+	c.keyPairs = append(c.keyPairs, keyPair)
+	c.mutex.Unlock()
+	return keyPair, nil
+}
+
+func (c *MemoryContext) GenerateRSAKeyPair(id []byte, bits int) (crypto11.SignerDecrypter, error) {
+	return c.GenerateRSAKeyPairWithLabel(id, nil, bits)
+}
+
+func (c *MemoryContext) GenerateRSAKeyPairWithLabel(id, label []byte, bits int) (crypto11.SignerDecrypter, error) {
+	attributes, err := idAndLabelAttributes(id, label)
+	if err != nil {
+		return nil, err
+	}
+	return c.GenerateRSAKeyPairWithAttributes(attributes.Copy(), attributes, bits)
+}
+
+func (c *MemoryContext) GenerateRSAKeyPairWithAttributes(public, private crypto11.AttributeSet, bits int) (crypto11.SignerDecrypter, error) {
+	key, err := rsa.GenerateKey(rand.Reader, bits)
+	if err != nil {
+		return nil, err
+	}
+	private.AddIfNotPresent([]*crypto11.Attribute{pkcs11.NewAttribute(pkcs11.CKA_DECRYPT, true)})
+	keyPair, err := c.addKeyPair(key, public, private, pkcs11.CKK_RSA, []*crypto11.Attribute{
+		pkcs11.NewAttribute(pkcs11.CKA_ENCRYPT, true),
+		pkcs11.NewAttribute(pkcs11.CKA_MODULUS, key.N.Bytes()),
+		pkcs11.NewAttribute(pkcs11.CKA_MODULUS_BITS, bits),
+		pkcs11.NewAttribute(pkcs11.CKA_PUBLIC_EXPONENT, big.NewInt(int64(key.E)).Bytes()),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return memoryRSAKeyPair{keyPair}, nil
+}
+
+func (c *MemoryContext) GenerateECDSAKeyPair(id []byte, curve elliptic.Curve) (crypto11.Signer, error) {
+	return c.GenerateECDSAKeyPairWithLabel(id, nil, curve)
+}
+
+func (c *MemoryContext) GenerateECDSAKeyPairWithLabel(id, label []byte, curve elliptic.Curve) (crypto11.Signer, error) {
+	attributes, err := idAndLabelAttributes(id, label)
+	if err != nil {
+		return nil, err
+	}
+	return c.GenerateECDSAKeyPairWithAttributes(attributes.Copy(), attributes, curve)
+}
+
+func (c *MemoryContext) GenerateECDSAKeyPairWithAttributes(public, private crypto11.AttributeSet, curve elliptic.Curve) (crypto11.Signer, error) {
+	oid, ok := memoryCurveOids[curve]
+	if !ok {
+		return nil, errors.New("unsupported elliptic curve")
+	}
+	key, err := ecdsa.GenerateKey(curve, rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	params, err := asn1.Marshal(oid)
+	if err != nil {
+		return nil, err
+	}
+	point, err := asn1.Marshal(elliptic.Marshal(curve, key.X, key.Y)) //nolint:staticcheck // CKA_EC_POINT is the uncompressed point
+	if err != nil {
+		return nil, err
+	}
+	private.AddIfNotPresent([]*crypto11.Attribute{pkcs11.NewAttribute(pkcs11.CKA_EC_PARAMS, params)})
+	keyPair, err := c.addKeyPair(key, public, private, pkcs11.CKK_EC, []*crypto11.Attribute{
+		pkcs11.NewAttribute(pkcs11.CKA_EC_PARAMS, params),
+		pkcs11.NewAttribute(pkcs11.CKA_EC_POINT, point),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return keyPair, nil
+}
+
+func (c *MemoryContext) GenerateSecretKey(id []byte, bits int, cipher *crypto11.SymmetricCipher) (SecretKey, error) {
+	return c.GenerateSecretKeyWithLabel(id, nil, bits, cipher)
+}
+
+func (c *MemoryContext) GenerateSecretKeyWithLabel(id, label []byte, bits int, cipher *crypto11.SymmetricCipher) (SecretKey, error) {
+	attributes, err := idAndLabelAttributes(id, label)
+	if err != nil {
+		return nil, err
+	}
+	return c.GenerateSecretKeyWithAttributes(attributes, bits, cipher)
+}
+
+func (c *MemoryContext) GenerateSecretKeyWithAttributes(template crypto11.AttributeSet, bits int, cipher *crypto11.SymmetricCipher) (SecretKey, error) {
+	if cipher == nil {
+		return nil, errors.New("cipher cannot be nil")
+	}
+	if id, ok := template[crypto11.CkaId]; !ok || len(id.Value) == 0 {
+		return nil, errors.New("secret key attributes need a CKA_ID")
+	}
+	if bits <= 0 || bits%8 != 0 {
+		return nil, fmt.Errorf("invalid key length %d", bits)
+	}
+	template.AddIfNotPresent([]*crypto11.Attribute{
+		pkcs11.NewAttribute(pkcs11.CKA_CLASS, pkcs11.CKO_SECRET_KEY),
+		pkcs11.NewAttribute(pkcs11.CKA_KEY_TYPE, cipher.GenParams[0].KeyType),
+		pkcs11.NewAttribute(pkcs11.CKA_TOKEN, true),
+		pkcs11.NewAttribute(pkcs11.CKA_PRIVATE, true),
+		pkcs11.NewAttribute(pkcs11.CKA_ENCRYPT, true),
+		pkcs11.NewAttribute(pkcs11.CKA_DECRYPT, true),
+		pkcs11.NewAttribute(pkcs11.CKA_SENSITIVE, true),
+		pkcs11.NewAttribute(pkcs11.CKA_EXTRACTABLE, false),
+		pkcs11.NewAttribute(pkcs11.CKA_VALUE_LEN, bits/8),
+	})
+	value := make([]byte, bits/8)
+	if _, err := rand.Read(value); err != nil {
+		return nil, err
+	}
+	block, err := newBlock(cipher, value)
+	if err != nil {
+		return nil, err
+	}
+	key := &memorySecretKey{context: c, cipher: cipher, block: block, attributes: template.Copy()}
+	c.mutex.Lock()
+	c.secretKeys = append(c.secretKeys, key)
+	c.mutex.Unlock()
+	return key, nil
+}
+
+func (c *MemoryContext) FindKeyPair(id []byte, label []byte) (crypto11.Signer, error) {
+	signers, err := c.FindKeyPairs(id, label)
+	if err != nil || len(signers) == 0 {
+		return nil, err
+	}
+	return signers[0], nil
+}
+
+func (c *MemoryContext) FindKeyPairs(id []byte, label []byte) ([]crypto11.Signer, error) {
+	template, err := idAndLabelTemplate(id, label)
+	if err != nil {
+		return nil, err
+	}
+	return c.FindKeyPairsWithAttributes(template)
+}
+
+func (c *MemoryContext) FindKeyPairWithAttributes(attributes crypto11.AttributeSet) (crypto11.Signer, error) {
+	signers, err := c.FindKeyPairsWithAttributes(attributes)
+	if err != nil || len(signers) == 0 {
+		return nil, err
+	}
+	return signers[0], nil
+}
+
+func (c *MemoryContext) FindKeyPairsWithAttributes(attributes crypto11.AttributeSet) ([]crypto11.Signer, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	var signers []crypto11.Signer
+	for _, keyPair := range c.keyPairs {
+		if matches(keyPair.private, attributes) {
+			signers = append(signers, keyPair.signer())
+		}
+	}
+	return signers, nil
+}
+
+func (c *MemoryContext) FindAllKeyPairs() ([]crypto11.Signer, error) {
+	return c.FindKeyPairsWithAttributes(crypto11.NewAttributeSet())
+}
+
+func (c *MemoryContext) FindKey(id []byte, label []byte) (SecretKey, error) {
+	keys, err := c.FindKeys(id, label)
+	if err != nil || len(keys) == 0 {
+		return nil, err
+	}
+	return keys[0], nil
+}
+
+func (c *MemoryContext) FindKeys(id []byte, label []byte) ([]SecretKey, error) {
+	template, err := idAndLabelTemplate(id, label)
+	if err != nil {
+		return nil, err
+	}
+	return c.FindKeysWithAttributes(template)
+}
+
+func (c *MemoryContext) FindKeyWithAttributes(attributes crypto11.AttributeSet) (SecretKey, error) {
+	keys, err := c.FindKeysWithAttributes(attributes)
+	if err != nil || len(keys) == 0 {
+		return nil, err
+	}
+	return keys[0], nil
+}
+
+func (c *MemoryContext) FindKeysWithAttributes(attributes crypto11.AttributeSet) ([]SecretKey, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	var keys []SecretKey
+	for _, key := range c.secretKeys {
+		if matches(key.attributes, attributes) {
+			keys = append(keys, key)
+		}
+	}
+	return keys, nil
+}
+
+func (c *MemoryContext) FindAllKeys() ([]SecretKey, error) {
+	return c.FindKeysWithAttributes(crypto11.NewAttributeSet())
+}
+
+// attributesOf returns the attributes of a secret key or of the private half of a key pair.
+func (c *MemoryContext) attributesOf(key interface{}) (crypto11.AttributeSet, error) {
+	if keyPair, ok := keyPairOf(key); ok {
+		return keyPair.private, nil
+	}
+	if secretKey, ok := key.(*memorySecretKey); ok && secretKey.context == c {
+		return secretKey.attributes, nil
+	}
+	return nil, fmt.Errorf("not a key of the memory context: %T", key)
+}
+
+func selectAttributes(set crypto11.AttributeSet, attributes []crypto11.AttributeType) (crypto11.AttributeSet, error) {
+	selected := crypto11.NewAttributeSet()
+	for _, attributeType := range attributes {
+		attribute, ok := set[attributeType]
+		if !ok {
+			return nil, fmt.Errorf("attribute 0x%x is not available", attributeType)
+		}
+		selected[attributeType] = crypto11.CopyAttribute(attribute)
+	}
+	return selected, nil
+}
+
+func (c *MemoryContext) GetAttributes(key interface{}, attributes []crypto11.AttributeType) (crypto11.AttributeSet, error) {
+	set, err := c.attributesOf(key)
+	if err != nil {
+		return nil, err
+	}
+	return selectAttributes(set, attributes)
+}
+
+func (c *MemoryContext) GetAttribute(key interface{}, attribute crypto11.AttributeType) (*crypto11.Attribute, error) {
+	set, err := c.GetAttributes(key, []crypto11.AttributeType{attribute})
+	if err != nil {
+		return nil, err
+	}
+	return set[attribute], nil
+}
+
+func (c *MemoryContext) GetPubAttributes(key interface{}, attributes []crypto11.AttributeType) (crypto11.AttributeSet, error) {
+	keyPair, ok := keyPairOf(key)
+	if !ok {
+		return nil, fmt.Errorf("not a key pair of the memory context: %T", key)
+	}
+	return selectAttributes(keyPair.public, attributes)
+}
+
+func (c *MemoryContext) GetPubAttribute(key interface{}, attribute crypto11.AttributeType) (*crypto11.Attribute, error) {
+	set, err := c.GetPubAttributes(key, []crypto11.AttributeType{attribute})
+	if err != nil {
+		return nil, err
+	}
+	return set[attribute], nil
+}
+
+func (c *MemoryContext) NewRandomReader() (io.Reader, error) {
+	return rand.Reader, nil
+}
+
+func (c *MemoryContext) ImportCertificateWithLabel(id []byte, label []byte, certificate *x509.Certificate) error {
+	if certificate == nil {
+		return errors.New("certificate cannot be nil")
+	}
+	attributes, err := crypto11.NewAttributeSetWithIDAndLabel(id, label)
+	if err != nil {
+		return err
+	}
+	_ = attributes.Set(crypto11.CkaClass, pkcs11.CKO_CERTIFICATE)
+	_ = attributes.Set(crypto11.CkaSerialNumber, certificate.SerialNumber.Bytes())
+	c.mutex.Lock()
+	c.certificates = append(c.certificates, &memoryCertificate{attributes: attributes, certificate: certificate})
+	c.mutex.Unlock()
+	return nil
+}
+
+func certificateTemplate(id []byte, label []byte, serial *big.Int) (crypto11.AttributeSet, error) {
+	if id == nil && label == nil && serial == nil {
+		return nil, errors.New("id, label and serial cannot all be nil")
+	}
+	template := crypto11.NewAttributeSet()
+	if id != nil {
+		_ = template.Set(crypto11.CkaId, id)
+	}
+	if label != nil {
+		_ = template.Set(crypto11.CkaLabel, label)
+	}
+	if serial != nil {
+		_ = template.Set(crypto11.CkaSerialNumber, serial.Bytes())
+	}
+	return template, nil
+}
+
+func (c *MemoryContext) FindCertificate(id []byte, label []byte, serial *big.Int) (*x509.Certificate, error) {
+	template, err := certificateTemplate(id, label, serial)
+	if err != nil {
+		return nil, err
+	}
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	for _, certificate := range c.certificates {
+		if matches(certificate.attributes, template) {
+			return certificate.certificate, nil
+		}
+	}
+	return nil, nil
+}
+
+func (c *MemoryContext) DeleteCertificate(id []byte, label []byte, serial *big.Int) error {
+	template, err := certificateTemplate(id, label, serial)
+	if err != nil {
+		return err
+	}
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	for i, certificate := range c.certificates {
+		if matches(certificate.attributes, template) {
+			c.certificates = append(c.certificates[:i], c.certificates[i+1:]...)
+			return nil
+		}
+	}
+	return nil
+}
+
+// DeriveECDH implements ecdhDeriver with the ECDSA key pair with CKA_ID id.
+func (c *MemoryContext) DeriveECDH(id []byte, peer *ecdsa.PublicKey) ([]byte, error) {
+	signer, err := c.FindKeyPair(id, nil)
+	if err != nil {
+		return nil, err
+	}
+	keyPair, _ := keyPairOf(signer)
+	if keyPair == nil {
+		return nil, fmt.Errorf("key pair %s not found", id)
+	}
+	private, ok := keyPair.Signer.(*ecdsa.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("key pair %s is no EC key", id)
+	}
+	if derive, ok := keyPair.private[crypto11.CkaDerive]; !ok || !bytes.Equal(derive.Value, []byte{1}) {
+		return nil, pkcs11.Error(pkcs11.CKR_KEY_FUNCTION_NOT_PERMITTED)
+	}
+	priv, err := private.ECDH()
+	if err != nil {
+		return nil, err
+	}
+	pub, err := peer.ECDH()
+	if err != nil {
+		return nil, err
+	}
+	return priv.ECDH(pub)
+}
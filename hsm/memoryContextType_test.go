@@ -0,0 +1,161 @@
+package hsm
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/ThalesIgnite/crypto11"
+	"github.com/eclipse-xfsc/crypto-provider-core/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMemoryContext_ImplementsContextType(t *testing.T) {
+	var _ ContextType = NewMemoryContext()
+	var _ ecdhDeriver = NewMemoryContext()
+}
+
+func TestNew_Backend(t *testing.T) {
+	_, err := New(Options{Backend: "other"})
+	assert.EqualError(t, err, "unsupported backend \"other\"")
+
+	provider, err := New(Options{Backend: DevBackend})
+	assert.Nil(t, err)
+	assert.IsType(t, &MemoryContext{}, provider.controller.api)
+	random, err := provider.GenerateRandom(types.CryptoContext{}, 16)
+	assert.Nil(t, err)
+	assert.Len(t, random, 16)
+}
+
+func TestMemoryContext_Provider(t *testing.T) {
+	provider, _ := New(Options{Backend: DevBackend})
+	for id, keyType := range map[string]types.KeyType{"ec": types.Ecdsap256, "rsa": types.Rsa2048, "aes": types.Aes256GCM} {
+		err := provider.GenerateKey(types.CryptoKeyParameter{Identifier: types.CryptoIdentifier{KeyId: id}, KeyType: keyType})
+		assert.Nil(t, err)
+	}
+
+	keys, err := provider.GetKeys(types.CryptoFilter{Filter: *regexp.MustCompile("")})
+	assert.Nil(t, err)
+	assert.Len(t, keys.Keys, 2)
+	key, err := provider.GetKey(types.CryptoIdentifier{KeyId: "ec"})
+	assert.Nil(t, err)
+	assert.Equal(t, types.Ecdsap256, key.KeyType)
+	assert.Contains(t, string(key.Key), "PUBLIC KEY")
+
+	signer, err := provider.Signer(types.CryptoIdentifier{KeyId: "ec"})
+	assert.Nil(t, err)
+	digest := sha256.Sum256([]byte("data"))
+	signature, err := signer.Sign(rand.Reader, digest[:], crypto.SHA256)
+	assert.Nil(t, err)
+	assert.True(t, ecdsa.VerifyASN1(signer.Public().(*ecdsa.PublicKey), digest[:], signature))
+	_, err = provider.Decrypter(types.CryptoIdentifier{KeyId: "ec"})
+	assert.NotNil(t, err)
+
+	decrypter, err := provider.Decrypter(types.CryptoIdentifier{KeyId: "rsa"})
+	assert.Nil(t, err)
+	ciphertext, _ := rsa.EncryptOAEP(sha256.New(), rand.Reader, decrypter.Public().(*rsa.PublicKey), []byte("secret"), nil)
+	plaintext, err := decrypter.Decrypt(rand.Reader, ciphertext, &rsa.OAEPOptions{Hash: crypto.SHA256})
+	assert.Nil(t, err)
+	assert.Equal(t, []byte("secret"), plaintext)
+
+	template := &x509.Certificate{SerialNumber: big.NewInt(1), Subject: pkix.Name{CommonName: "ec"}, NotBefore: time.Now(), NotAfter: time.Now().Add(time.Hour)}
+	der, _ := x509.CreateCertificate(rand.Reader, template, template, signer.Public(), signer)
+	certificate, _ := x509.ParseCertificate(der)
+	assert.Nil(t, provider.ImportCertificateChain(types.CryptoIdentifier{KeyId: "ec"}, []*x509.Certificate{certificate}))
+	chain, err := provider.GetCertificateChain(types.CryptoIdentifier{KeyId: "ec"})
+	assert.Nil(t, err)
+	assert.Equal(t, []*x509.Certificate{certificate}, chain)
+
+	assert.Nil(t, provider.DeleteKey(types.CryptoIdentifier{KeyId: "ec"}))
+	_, err = provider.GetKey(types.CryptoIdentifier{KeyId: "ec"})
+	assert.NotNil(t, err)
+	chain, _ = provider.GetCertificateChain(types.CryptoIdentifier{KeyId: "ec"})
+	assert.Empty(t, chain)
+}
+
+func TestMemoryContext_Attributes(t *testing.T) {
+	ctx := NewMemoryContext()
+	signer, err := ctx.GenerateRSAKeyPairWithLabel([]byte("id"), []byte("label"), 2048)
+	assert.Nil(t, err)
+	secret, err := ctx.GenerateSecretKey([]byte("secret"), 256, crypto11.CipherAES)
+	assert.Nil(t, err)
+
+	found, err := ctx.FindKeyPair(nil, []byte("label"))
+	assert.Nil(t, err)
+	assert.Equal(t, signer.Public(), found.Public())
+	found, err = ctx.FindKeyPair([]byte("other"), nil)
+	assert.Nil(t, err)
+	assert.Nil(t, found)
+	_, err = ctx.FindKeyPair(nil, nil)
+	assert.NotNil(t, err)
+
+	attributes := crypto11.NewAttributeSet()
+	_ = attributes.Set(crypto11.CkaDecrypt, true)
+	signers, err := ctx.FindKeyPairsWithAttributes(attributes)
+	assert.Nil(t, err)
+	assert.Len(t, signers, 1)
+
+	id, err := ctx.GetAttribute(signer, crypto11.CkaId)
+	assert.Nil(t, err)
+	assert.Equal(t, []byte("id"), id.Value)
+	modulus, err := ctx.GetPubAttribute(signer, crypto11.CkaModulus)
+	assert.Nil(t, err)
+	assert.Equal(t, signer.Public().(*rsa.PublicKey).N.Bytes(), modulus.Value)
+	_, err = ctx.GetAttribute(signer, crypto11.CkaValue)
+	assert.NotNil(t, err)
+
+	valueLen, err := ctx.GetAttribute(secret, crypto11.CkaValueLen)
+	assert.Nil(t, err)
+	assert.Len(t, valueLen.Value, 8)
+	keys, err := ctx.FindAllKeys()
+	assert.Nil(t, err)
+	assert.Equal(t, []SecretKey{secret}, keys)
+
+	assert.Nil(t, signer.Delete())
+	signers, _ = ctx.FindAllKeyPairs()
+	assert.Empty(t, signers)
+	assert.Nil(t, secret.Delete())
+	keys, _ = ctx.FindAllKeys()
+	assert.Empty(t, keys)
+}
+
+func TestMemoryContext_SecretKeys(t *testing.T) {
+	ctx := NewMemoryContext()
+	key, err := ctx.GenerateSecretKey([]byte("aes"), 256, crypto11.CipherAES)
+	assert.Nil(t, err)
+
+	aead, err := key.NewGCM()
+	assert.Nil(t, err)
+	nonce := make([]byte, aead.NonceSize())
+	opened, err := aead.Open(nil, nonce, aead.Seal(nil, nonce, []byte("secret"), nil), nil)
+	assert.Nil(t, err)
+	assert.Equal(t, []byte("secret"), opened)
+
+	iv := make([]byte, key.BlockSize())
+	encrypter, err := key.NewCBCEncrypter(iv)
+	assert.Nil(t, err)
+	decrypter, err := key.NewCBCDecrypter(iv)
+	assert.Nil(t, err)
+	block := make([]byte, 2*key.BlockSize())
+	encrypter.CryptBlocks(block, []byte("two blocks of plaintext for CBC!"))
+	decrypter.CryptBlocks(block, block)
+	assert.Equal(t, []byte("two blocks of plaintext for CBC!"), block)
+	_, err = key.NewCBCEncrypter(nil)
+	assert.NotNil(t, err)
+
+	hmac, err := ctx.GenerateSecretKey([]byte("hmac"), 256, crypto11.CipherHMACSHA256)
+	assert.Nil(t, err)
+	_, err = hmac.NewGCM()
+	assert.NotNil(t, err)
+	assert.Panics(t, func() { hmac.Encrypt(block, block) })
+	_, err = ctx.GenerateSecretKey([]byte("aes"), 100*8, crypto11.CipherAES)
+	assert.NotNil(t, err)
+}
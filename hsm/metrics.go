@@ -0,0 +1,45 @@
+package hsm
+
+import (
+	"time"
+
+	"github.com/eclipse-xfsc/crypto-provider-core/types"
+)
+
+// Metrics receives measurements of the provider, see PrometheusMetrics. Implementations must be safe
+// for concurrent use.
+type Metrics interface {
+	// Operation is called after every operation of HSMCryptoProvider. keyType is empty if the key is
+	// unknown, errorClass is empty on success.
+	Operation(operation string, keyType types.KeyType, namespace string, errorClass string, duration time.Duration)
+	// Call is called after every call to the partition, methods of ContextType and of its keys.
+	Call(method string, errorClass string, duration time.Duration)
+	// CallsInFlight changes the number of running calls to the partition by delta. crypto11 does not
+	// report the sessions of its pool in use, every call holds one.
+	CallsInFlight(delta int)
+	// MaxSessions reports the size of the session pool.
+	MaxSessions(sessions int)
+	// Connected is called whenever the PKCS#11 library is configured for the partition and on every
+	// reconnect: stale session or object handles are replaced or the session of ECDH is reopened.
+	Connected()
+}
+
+// noMetrics discards all measurements.
+type noMetrics struct{}
+
+func (noMetrics) Operation(string, types.KeyType, string, string, time.Duration) {}
+
+func (noMetrics) Call(string, string, time.Duration) {}
+
+func (noMetrics) CallsInFlight(int) {}
+
+func (noMetrics) MaxSessions(int) {}
+
+func (noMetrics) Connected() {}
+
+func (c *hsmController) meter() Metrics {
+	if c.metrics == nil {
+		return noMetrics{}
+	}
+	return c.metrics
+}
@@ -0,0 +1,26 @@
+package hsm
+
+import "time"
+
+// MetricsContext is a ContextType decorator which reports every call to the partition to Metrics.
+type MetricsContext struct {
+	*InterceptorContext
+	metrics Metrics
+}
+
+// NewMetricsContext decorates the context with the metrics.
+func NewMetricsContext(api ContextType, metrics Metrics) *MetricsContext {
+	m := &MetricsContext{metrics: metrics}
+	m.InterceptorContext = NewInterceptorContext(api, m.intercept)
+	return m
+}
+
+// intercept counts the call as running until it ends and reports it.
+func (m *MetricsContext) intercept(call *Call, invoke func() error) error {
+	start := time.Now()
+	m.metrics.CallsInFlight(1)
+	defer m.metrics.CallsInFlight(-1)
+	err := invoke()
+	m.metrics.Call(call.Method, errorClass(err), time.Since(start))
+	return err
+}
@@ -0,0 +1,88 @@
+package hsm
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/sha256"
+	"errors"
+	"testing"
+
+	"github.com/eclipse-xfsc/crypto-provider-core/types"
+	"github.com/miekg/pkcs11"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPrometheusMetrics_Operations(t *testing.T) {
+	metrics, err := NewPrometheusMetrics(prometheus.NewRegistry(), "tenant", " ")
+	assert.Nil(t, err)
+	provider, err := New(Options{
+		Backend: DevBackend,
+		Metrics: metrics,
+		Faults:  []Fault{{Methods: []string{"Sign"}, Times: 1, Error: pkcs11.Error(pkcs11.CKR_DEVICE_ERROR)}},
+	})
+	assert.Nil(t, err)
+	identifier := types.CryptoIdentifier{KeyId: testId, CryptoContext: types.CryptoContext{Namespace: "tenant"}}
+
+	assert.Nil(t, provider.GenerateKey(types.CryptoKeyParameter{Identifier: identifier, KeyType: types.Ecdsap256}))
+	_, err = provider.Sign(identifier, []byte("data"))
+	assert.NotNil(t, err)
+	_, err = provider.Sign(identifier, []byte("data"))
+	assert.Nil(t, err)
+	assert.ErrorIs(t, provider.RotateKey(identifier), errors.ErrUnsupported)
+	identifier.CryptoContext.Namespace = "unknown"
+	assert.ErrorIs(t, provider.RotateKey(identifier), errors.ErrUnsupported)
+
+	assert.Equal(t, 1.0, testutil.ToFloat64(metrics.operations.WithLabelValues("GenerateKey", "ecdsa-p256", "tenant", "success")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(metrics.operations.WithLabelValues("Sign", "ecdsa-p256", "tenant", "success")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(metrics.operations.WithLabelValues("Sign", "ecdsa-p256", "tenant", "CKR_DEVICE_ERROR")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(metrics.operations.WithLabelValues("RotateKey", "", "tenant", "unsupported")))
+	assert.Equal(t, 3, testutil.CollectAndCount(metrics.operationDuration))
+	assert.Equal(t, 2.0, testutil.ToFloat64(metrics.calls.WithLabelValues("FindKeyPair", "success")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(metrics.calls.WithLabelValues("Sign", "CKR_DEVICE_ERROR")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(metrics.calls.WithLabelValues("Sign", "success")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(metrics.operations.WithLabelValues("RotateKey", "", "other", "unsupported")))
+	assert.Equal(t, map[string]bool{"tenant": true}, metrics.namespaces)
+	assert.Equal(t, 0.0, testutil.ToFloat64(metrics.callsInFlight))
+}
+
+func TestPrometheusMetrics_Reconnects(t *testing.T) {
+	metrics, err := NewPrometheusMetrics(prometheus.NewRegistry())
+	assert.Nil(t, err)
+	provider, err := New(Options{
+		Backend: DevBackend,
+		Metrics: metrics,
+		Faults:  []Fault{{Methods: []string{"Sign"}, Times: 1, Error: pkcs11.Error(pkcs11.CKR_OBJECT_HANDLE_INVALID)}},
+	})
+	assert.Nil(t, err)
+	identifier := types.CryptoIdentifier{KeyId: testId}
+	assert.Nil(t, provider.GenerateKey(types.CryptoKeyParameter{Identifier: identifier, KeyType: types.Ecdsap256}))
+	signer, err := provider.Signer(identifier)
+	assert.Nil(t, err)
+	digest := sha256.Sum256([]byte("data"))
+	_, err = signer.Sign(rand.Reader, digest[:], crypto.SHA256)
+	assert.Nil(t, err)
+
+	assert.Equal(t, 1.0, testutil.ToFloat64(metrics.connects))
+	assert.Equal(t, 1.0, testutil.ToFloat64(metrics.calls.WithLabelValues("Sign", "CKR_OBJECT_HANDLE_INVALID")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(metrics.calls.WithLabelValues("Sign", "success")))
+}
+
+func TestPrometheusMetrics_Register(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	first, err := NewPrometheusMetrics(registry)
+	assert.Nil(t, err)
+	second, err := NewPrometheusMetrics(registry)
+	assert.Nil(t, err)
+	first.Connected()
+	second.Connected()
+	second.MaxSessions(8)
+	assert.Equal(t, 2.0, testutil.ToFloat64(first.connects))
+	assert.Equal(t, 8.0, testutil.ToFloat64(first.sessionsMax))
+
+	registry = prometheus.NewRegistry()
+	assert.Nil(t, registry.Register(prometheus.NewGauge(prometheus.GaugeOpts{Name: "hsm_calls_in_flight"})))
+	_, err = NewPrometheusMetrics(registry)
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,141 @@
+package hsm
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/eclipse-xfsc/crypto-provider-core/types"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// PrometheusMetrics exposes the Metrics of the provider to Prometheus:
+//
+//   - hsm_operations_total and hsm_operation_duration_seconds for the operations of HSMCryptoProvider
+//   - hsm_calls_total and hsm_call_duration_seconds for the calls to the partition
+//   - hsm_calls_in_flight for the running calls and hsm_sessions_max for the size of the session pool
+//   - hsm_partition_connects_total for the connections to the partition
+type PrometheusMetrics struct {
+	operations        *prometheus.CounterVec
+	operationDuration *prometheus.HistogramVec
+	calls             *prometheus.CounterVec
+	callDuration      *prometheus.HistogramVec
+	callsInFlight     prometheus.Gauge
+	sessionsMax       prometheus.Gauge
+	connects          prometheus.Counter
+	namespaces        map[string]bool
+}
+
+// otherNamespace is the namespace label of the operations of namespaces which are not reported.
+const otherNamespace = "other"
+
+// NewPrometheusMetrics registers the metrics with the registerer, e.g. prometheus.DefaultRegisterer.
+// Metrics already registered by another provider are shared. Operations are labeled with the namespaces
+// given, the ones of all other namespaces with "other", which bounds the number of series.
+func NewPrometheusMetrics(registerer prometheus.Registerer, namespaces ...string) (*PrometheusMetrics, error) {
+	m := &PrometheusMetrics{
+		namespaces: map[string]bool{},
+		operations: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "hsm_operations_total",
+			Help: "Operations of the crypto provider by outcome, \"success\" or the error class.",
+		}, []string{"operation", "key_type", "namespace", "outcome"}),
+		operationDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "hsm_operation_duration_seconds",
+			Help:    "Duration of the operations of the crypto provider.",
+			Buckets: prometheus.ExponentialBuckets(0.001, 2, 14),
+		}, []string{"operation", "key_type"}),
+		calls: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "hsm_calls_total",
+			Help: "Calls to the HSM partition by outcome, \"success\" or the error class.",
+		}, []string{"method", "outcome"}),
+		callDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "hsm_call_duration_seconds",
+			Help:    "Duration of the calls to the HSM partition.",
+			Buckets: prometheus.ExponentialBuckets(0.001, 2, 14),
+		}, []string{"method"}),
+		callsInFlight: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "hsm_calls_in_flight",
+			Help: "Running calls to the HSM partition.",
+		}),
+		sessionsMax: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "hsm_sessions_max",
+			Help: "Size of the session pool.",
+		}),
+		connects: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "hsm_partition_connects_total",
+			Help: "Connections to the HSM partition.",
+		}),
+	}
+	for _, namespace := range namespaces {
+		if namespace = strings.TrimSpace(namespace); namespace != "" {
+			m.namespaces[namespace] = true
+		}
+	}
+	var err error
+	if m.operations, err = register(registerer, m.operations); err != nil {
+		return nil, err
+	}
+	if m.operationDuration, err = register(registerer, m.operationDuration); err != nil {
+		return nil, err
+	}
+	if m.calls, err = register(registerer, m.calls); err != nil {
+		return nil, err
+	}
+	if m.callDuration, err = register(registerer, m.callDuration); err != nil {
+		return nil, err
+	}
+	if m.callsInFlight, err = register(registerer, m.callsInFlight); err != nil {
+		return nil, err
+	}
+	if m.sessionsMax, err = register(registerer, m.sessionsMax); err != nil {
+		return nil, err
+	}
+	if m.connects, err = register(registerer, m.connects); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// register returns the collector registered before if there is one.
+func register[C prometheus.Collector](registerer prometheus.Registerer, collector C) (C, error) {
+	err := registerer.Register(collector)
+	var registered prometheus.AlreadyRegisteredError
+	if errors.As(err, &registered) {
+		if existing, ok := registered.ExistingCollector.(C); ok {
+			return existing, nil
+		}
+	}
+	return collector, err
+}
+
+func outcome(errorClass string) string {
+	if errorClass == "" {
+		return "success"
+	}
+	return errorClass
+}
+
+func (m *PrometheusMetrics) Operation(operation string, keyType types.KeyType, namespace string, errorClass string, duration time.Duration) {
+	if !m.namespaces[namespace] {
+		namespace = otherNamespace
+	}
+	m.operations.WithLabelValues(operation, string(keyType), namespace, outcome(errorClass)).Inc()
+	m.operationDuration.WithLabelValues(operation, string(keyType)).Observe(duration.Seconds())
+}
+
+func (m *PrometheusMetrics) Call(method string, errorClass string, duration time.Duration) {
+	m.calls.WithLabelValues(method, outcome(errorClass)).Inc()
+	m.callDuration.WithLabelValues(method).Observe(duration.Seconds())
+}
+
+func (m *PrometheusMetrics) CallsInFlight(delta int) {
+	m.callsInFlight.Add(float64(delta))
+}
+
+func (m *PrometheusMetrics) MaxSessions(sessions int) {
+	m.sessionsMax.Set(float64(sessions))
+}
+
+func (m *PrometheusMetrics) Connected() {
+	m.connects.Inc()
+}
@@ -0,0 +1,299 @@
+package hsm
+
+import (
+	"crypto"
+	"crypto/cipher"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/ThalesIgnite/crypto11"
+	"github.com/miekg/pkcs11"
+)
+
+// interaction is one recorded call, stored as JSON line.
+type interaction struct {
+	Method   string            `json:"method"`
+	Args     []json.RawMessage `json:"args,omitempty"`
+	Results  []json.RawMessage `json:"results,omitempty"`
+	Error    *recordedError    `json:"error,omitempty"`
+	Time     time.Time         `json:"time"`
+	Duration time.Duration     `json:"duration"`
+}
+
+type recordedError struct {
+	Message string `json:"message"`
+	// Code of PKCS#11 errors, replayed as pkcs11.Error.
+	Code uint `json:"code,omitempty"`
+}
+
+// recordedKey identifies key objects across the calls of a recording.
+type recordedKey struct {
+	Handle    int    `json:"handle"`
+	Public    []byte `json:"public,omitempty"`
+	Decrypter bool   `json:"decrypter,omitempty"`
+}
+
+// recordedAEAD is the result of NewGCM.
+type recordedAEAD struct {
+	NonceSize int `json:"nonceSize"`
+	Overhead  int `json:"overhead"`
+}
+
+type recordedOptions struct {
+	Hash       crypto.Hash `json:"hash,omitempty"`
+	SaltLength *int        `json:"saltLength,omitempty"`
+	OAEP       bool        `json:"oaep,omitempty"`
+	Label      []byte      `json:"label,omitempty"`
+}
+
+const redacted = "redacted"
+
+// sensitiveAttributes are never written to recordings.
+var sensitiveAttributes = map[crypto11.AttributeType]bool{
+	crypto11.CkaValue:           true,
+	crypto11.CkaPrivateExponent: true,
+	crypto11.CkaPrime1:          true,
+	crypto11.CkaPrime2:          true,
+	crypto11.CkaExponent1:       true,
+	crypto11.CkaExponent2:       true,
+	crypto11.CkaCoefficient:     true,
+}
+
+var recordedCiphers = map[string]*crypto11.SymmetricCipher{
+	"AES":         crypto11.CipherAES,
+	"DES3":        crypto11.CipherDES3,
+	"generic":     crypto11.CipherGeneric,
+	"HMAC-SHA1":   crypto11.CipherHMACSHA1,
+	"HMAC-SHA224": crypto11.CipherHMACSHA224,
+	"HMAC-SHA256": crypto11.CipherHMACSHA256,
+	"HMAC-SHA384": crypto11.CipherHMACSHA384,
+	"HMAC-SHA512": crypto11.CipherHMACSHA512,
+}
+
+// keyHandle is implemented by the keys of recordings.
+type keyHandle interface {
+	keyHandle() int
+}
+
+// RecordingContext is a ContextType decorator which writes all calls with their arguments and results
+// to a recording, which ReplayContext serves in tests. Secret attribute values, plaintexts and decrypted
+// data are redacted, data read from the random reader is not recorded. Nonces and IVs of secret keys are
+// not recorded either, as they are usually random.
+type RecordingContext struct {
+	*InterceptorContext
+	mutex   sync.Mutex
+	encoder *json.Encoder
+	err     error
+}
+
+// NewRecordingContext decorates the context and writes the recording to w.
+func NewRecordingContext(api ContextType, w io.Writer) *RecordingContext {
+	r := &RecordingContext{encoder: json.NewEncoder(w)}
+	r.InterceptorContext = NewInterceptorContext(api, r.intercept)
+	return r
+}
+
+// Err returns the first error writing the recording.
+func (r *RecordingContext) Err() error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return r.err
+}
+
+// intercept records the call. The arguments are encoded before the call, which may change them.
+func (r *RecordingContext) intercept(call *Call, invoke func() error) error {
+	args, recorded := recordedArgs(call)
+	if !recorded {
+		return invoke()
+	}
+	start := time.Now()
+	err := invoke()
+	recording := interaction{Method: call.Method, Args: args, Time: start.UTC(), Duration: time.Since(start), Error: toRecordedError(err)}
+	if err == nil {
+		recording.Results = encodeValues(recordedResults(call))
+	}
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if encodeErr := r.encoder.Encode(recording); encodeErr != nil && r.err == nil {
+		r.err = encodeErr
+	}
+	return err
+}
+
+// recordedArgs encodes the arguments of recorded calls. Operations of keys start with the handle of the key,
+// plaintexts are recorded by their length.
+func recordedArgs(call *Call) ([]json.RawMessage, bool) {
+	if call.Key == nil {
+		return encodeValues(call.Args), call.Method != "GenerateRandom"
+	}
+	handle := 0
+	if key, ok := call.Key.(keyHandle); ok {
+		handle = key.keyHandle()
+	}
+	switch call.Method {
+	case "Sign", "Decrypt":
+		return encodeValues([]interface{}{handle, call.Args[0], encodeOptions(call.Args[1])}), true
+	case "Delete", "NewGCM", "NewCBCEncrypter", "NewCBCDecrypter":
+		return encodeValues([]interface{}{handle}), true
+	case "Seal":
+		return encodeValues([]interface{}{handle, len(call.Args[1].([]byte)), call.Args[2]}), true
+	case "Open":
+		return encodeValues([]interface{}{handle, call.Args[1], call.Args[2]}), true
+	case "CBCEncrypt":
+		return encodeValues([]interface{}{handle, len(call.Args[0].([]byte))}), true
+	case "CBCDecrypt":
+		return encodeValues([]interface{}{handle, call.Args[0]}), true
+	}
+	return nil, false
+}
+
+// recordedResults returns the results of the call to record, only the length of decrypted data is recorded
+// and the random reader and block modes not at all.
+func recordedResults(call *Call) []interface{} {
+	switch call.Method {
+	case "Decrypt", "Open", "CBCDecrypt":
+		return []interface{}{len(call.Results[0].([]byte))}
+	case "NewGCM":
+		aead := call.Results[0].(cipher.AEAD)
+		return []interface{}{recordedAEAD{NonceSize: aead.NonceSize(), Overhead: aead.Overhead()}}
+	case "NewRandomReader", "NewCBCEncrypter", "NewCBCDecrypter":
+		return nil
+	}
+	return call.Results
+}
+
+func toRecordedError(err error) *recordedError {
+	if err == nil {
+		return nil
+	}
+	recorded := &recordedError{Message: err.Error()}
+	var p11Err pkcs11.Error
+	if errors.As(err, &p11Err) {
+		recorded.Code = uint(p11Err)
+	}
+	return recorded
+}
+
+func (e *recordedError) err() error {
+	if e == nil {
+		return nil
+	}
+	if e.Code != 0 {
+		return pkcs11.Error(e.Code)
+	}
+	return errors.New(e.Message)
+}
+
+func encodeValues(values []interface{}) []json.RawMessage {
+	encoded := make([]json.RawMessage, len(values))
+	for i, value := range values {
+		encoded[i] = encodeValue(value)
+	}
+	return encoded
+}
+
+// encodeValue encodes arguments and results. Keys are encoded by their handle in the recording.
+func encodeValue(value interface{}) json.RawMessage {
+	var v interface{} = value
+	switch value := value.(type) {
+	case elliptic.Curve:
+		v = value.Params().Name
+	case crypto11.AttributeSet:
+		attributes := map[string]string{}
+		for attributeType, attribute := range value {
+			attributes[fmt.Sprintf("0x%x", attributeType)] = encodeAttributeValue(attribute)
+		}
+		v = attributes
+	case *crypto11.Attribute:
+		if value != nil {
+			v = encodeAttributeValue(value)
+		}
+	case *x509.Certificate:
+		if value != nil {
+			v = value.Raw
+		}
+	case *big.Int:
+		if value != nil {
+			v = value.String()
+		}
+	case *crypto11.SymmetricCipher:
+		v = cipherName(value)
+	case SecretKey:
+		key := recordedKey{}
+		if handle, ok := value.(keyHandle); ok {
+			key.Handle = handle.keyHandle()
+		}
+		v = key
+	case []SecretKey:
+		keys := make([]json.RawMessage, len(value))
+		for i, key := range value {
+			keys[i] = encodeValue(key)
+		}
+		v = keys
+	case []crypto11.Signer:
+		keys := make([]json.RawMessage, len(value))
+		for i, key := range value {
+			keys[i] = encodeValue(key)
+		}
+		v = keys
+	case crypto11.Signer:
+		v = encodeKeyPair(value)
+	case io.Reader:
+		v = nil
+	}
+	encoded, err := json.Marshal(v)
+	if err != nil {
+		encoded, _ = json.Marshal(fmt.Sprintf("%T", value))
+	}
+	return encoded
+}
+
+func cipherName(cipher *crypto11.SymmetricCipher) string {
+	for name, known := range recordedCiphers {
+		if known == cipher {
+			return name
+		}
+	}
+	return "unknown"
+}
+
+func encodeAttributeValue(attribute *crypto11.Attribute) string {
+	if sensitiveAttributes[attribute.Type] {
+		return redacted
+	}
+	return base64.StdEncoding.EncodeToString(attribute.Value)
+}
+
+func encodeKeyPair(signer crypto11.Signer) recordedKey {
+	key := recordedKey{}
+	if handle, ok := signer.(keyHandle); ok {
+		key.Handle = handle.keyHandle()
+	}
+	key.Public, _ = x509.MarshalPKIXPublicKey(signer.Public())
+	_, key.Decrypter = signer.(crypto.Decrypter)
+	return key
+}
+
+// encodeOptions encodes crypto.SignerOpts and crypto.DecrypterOpts.
+func encodeOptions(options interface{}) *recordedOptions {
+	switch options := options.(type) {
+	case *rsa.PSSOptions:
+		return &recordedOptions{Hash: options.Hash, SaltLength: &options.SaltLength}
+	case *rsa.OAEPOptions:
+		return &recordedOptions{Hash: options.Hash, OAEP: true, Label: options.Label}
+	case crypto.SignerOpts:
+		if options != nil {
+			return &recordedOptions{Hash: options.HashFunc()}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,154 @@
+package hsm
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/base64"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/ThalesIgnite/crypto11"
+	"github.com/eclipse-xfsc/crypto-provider-core/types"
+	"github.com/miekg/pkcs11"
+	"github.com/stretchr/testify/assert"
+)
+
+var _ ContextType = &RecordingContext{}
+var _ ContextType = &ReplayContext{}
+
+// recordedFlow runs the provider operations which are recorded and replayed.
+func recordedFlow(t *testing.T, provider HSMCryptoProvider) {
+	ec := types.CryptoIdentifier{KeyId: testId}
+	rsa := types.CryptoIdentifier{KeyId: testId + "-rsa"}
+	assert.Nil(t, provider.GenerateKey(types.CryptoKeyParameter{Identifier: ec, KeyType: types.Ecdsap256}))
+	assert.Nil(t, provider.GenerateKey(types.CryptoKeyParameter{Identifier: rsa, KeyType: types.Rsa2048}))
+	assert.Nil(t, provider.GenerateKey(types.CryptoKeyParameter{Identifier: types.CryptoIdentifier{KeyId: "aes"}, KeyType: types.Aes256GCM}))
+
+	key, err := provider.GetKey(ec)
+	assert.Nil(t, err)
+	assert.Equal(t, types.Ecdsap256, key.KeyType)
+	signature, err := provider.Sign(ec, make([]byte, 32))
+	assert.Nil(t, err)
+	assert.NotEmpty(t, signature)
+	keys, err := provider.GetKeys(types.CryptoFilter{Filter: *regexp.MustCompile("")})
+	assert.Nil(t, err)
+	assert.Len(t, keys.Keys, 2)
+	aes := types.CryptoIdentifier{KeyId: "aes"}
+	ciphertext, err := provider.Encrypt(aes, []byte("secret plaintext"))
+	assert.Nil(t, err)
+	plaintext, err := provider.Decrypt(aes, ciphertext)
+	assert.Nil(t, err)
+	// replayed as zeros of the recorded length
+	assert.Len(t, plaintext, len("secret plaintext"))
+	random, err := provider.GenerateRandom(types.CryptoContext{}, 16)
+	assert.Nil(t, err)
+	assert.Len(t, random, 16)
+	assert.Nil(t, provider.DeleteKey(rsa))
+	_, err = provider.GetKey(rsa)
+	assert.NotNil(t, err)
+}
+
+func TestRecordingContext_Replay(t *testing.T) {
+	var recording bytes.Buffer
+	memory := NewMemoryContext()
+	recorder := NewRecordingContext(memory, &recording)
+	recordedFlow(t, HSMCryptoProvider{controller: &hsmController{api: recorder, rand: rand.Reader}})
+	assert.Nil(t, recorder.Err())
+	assert.NotContains(t, recording.String(), "PRIVATE")
+	assert.NotContains(t, recording.String(), base64.StdEncoding.EncodeToString([]byte("secret plaintext")))
+	assert.Contains(t, recording.String(), `"method":"Seal"`)
+	assert.Contains(t, recording.String(), `"method":"Open"`)
+
+	replay, err := NewReplayContext(strings.NewReader(recording.String()))
+	assert.Nil(t, err)
+	recordedFlow(t, HSMCryptoProvider{controller: &hsmController{api: replay, rand: rand.Reader}})
+	assert.Nil(t, replay.Err())
+	assert.Equal(t, 0, replay.Unused())
+}
+
+func TestRecordingContext_Redaction(t *testing.T) {
+	var recording bytes.Buffer
+	memory := NewMemoryContext()
+	recorder := NewRecordingContext(memory, &recording)
+	key, err := recorder.GenerateSecretKey([]byte(testId), 256, crypto11.CipherAES)
+	assert.Nil(t, err)
+	memory.secretKeys[0].attributes[crypto11.CkaValue] = pkcs11.NewAttribute(crypto11.CkaValue, []byte("secret"))
+	_, err = recorder.GetAttribute(key, crypto11.CkaValue)
+	assert.Nil(t, err)
+	assert.Contains(t, recording.String(), `"results":["redacted"]`)
+	assert.NotContains(t, recording.String(), base64.StdEncoding.EncodeToString([]byte("secret")))
+	assert.Nil(t, key.Delete())
+
+	replay, err := NewReplayContext(&recording)
+	assert.Nil(t, err)
+	_, err = replay.GetAttribute(unwrap(key), crypto11.CkaValue)
+	assert.EqualError(t, err, `unexpected call GetAttribute({"handle":0}, 17)`)
+	replayed, err := replay.GenerateSecretKey([]byte(testId), 256, crypto11.CipherAES)
+	assert.Nil(t, err)
+	value, err := replay.GetAttribute(replayed, crypto11.CkaValue)
+	assert.Nil(t, err)
+	assert.Empty(t, value.Value)
+	assert.NotNil(t, replay.Err())
+	_, err = replayed.NewGCM()
+	assert.NotNil(t, err)
+	assert.Nil(t, replayed.Delete())
+}
+
+func TestRecordingContext_CBC(t *testing.T) {
+	var recording bytes.Buffer
+	recorder := NewRecordingContext(NewMemoryContext(), &recording)
+	key, err := recorder.GenerateSecretKey([]byte(testId), 256, crypto11.CipherAES)
+	assert.Nil(t, err)
+	iv := make([]byte, 16)
+	plaintext := []byte("two blocks of plaintext for CBC!")
+	encrypter, err := key.NewCBCEncrypter(iv)
+	assert.Nil(t, err)
+	ciphertext := make([]byte, len(plaintext))
+	encrypter.CryptBlocks(ciphertext, plaintext)
+	decrypter, err := key.NewCBCDecrypter(iv)
+	assert.Nil(t, err)
+	decrypted := make([]byte, len(ciphertext))
+	decrypter.CryptBlocks(decrypted, ciphertext)
+	assert.Equal(t, plaintext, decrypted)
+	assert.Nil(t, recorder.Err())
+	assert.NotContains(t, recording.String(), base64.StdEncoding.EncodeToString(plaintext))
+
+	replay, err := NewReplayContext(&recording)
+	assert.Nil(t, err)
+	replayed, err := replay.GenerateSecretKey([]byte(testId), 256, crypto11.CipherAES)
+	assert.Nil(t, err)
+	encrypter, err = replayed.NewCBCEncrypter(iv)
+	assert.Nil(t, err)
+	replayedCiphertext := make([]byte, len(plaintext))
+	encrypter.CryptBlocks(replayedCiphertext, plaintext)
+	assert.Equal(t, ciphertext, replayedCiphertext)
+	decrypter, err = replayed.NewCBCDecrypter(iv)
+	assert.Nil(t, err)
+	decrypter.CryptBlocks(decrypted, ciphertext)
+	assert.Equal(t, make([]byte, len(plaintext)), decrypted)
+	assert.Panics(t, func() { decrypter.CryptBlocks(decrypted, ciphertext) })
+	assert.NotNil(t, replay.Err())
+	assert.Equal(t, 0, replay.Unused())
+}
+
+func TestReplayContext_Errors(t *testing.T) {
+	var recording bytes.Buffer
+	faults := NewFaultContext(NewMemoryContext(), Fault{Methods: []string{"FindKeyPair"}, Error: pkcs11.Error(pkcs11.CKR_DEVICE_ERROR)})
+	_, err := NewRecordingContext(faults, &recording).FindKeyPair([]byte(testId), nil)
+	assert.Equal(t, pkcs11.Error(pkcs11.CKR_DEVICE_ERROR), err)
+
+	replay, err := NewReplayContext(&recording)
+	assert.Nil(t, err)
+	_, err = replay.FindKeyPair([]byte("other"), nil)
+	assert.NotNil(t, err)
+	_, err = replay.FindKeyPair([]byte(testId), nil)
+	assert.Equal(t, pkcs11.Error(pkcs11.CKR_DEVICE_ERROR), err)
+	// recorded calls are replayed once
+	_, err = replay.FindKeyPair([]byte(testId), nil)
+	assert.NotNil(t, err)
+	assert.Len(t, strings.Split(replay.Err().Error(), "\n"), 2)
+
+	_, err = NewReplayContext(strings.NewReader("{"))
+	assert.NotNil(t, err)
+}
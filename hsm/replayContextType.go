@@ -0,0 +1,551 @@
+package hsm
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/ThalesIgnite/crypto11"
+	"github.com/miekg/pkcs11"
+)
+
+// ReplayContext serves a recording of RecordingContext as ContextType. Every call must match an
+// unused recorded call with the same method and arguments, calls which were not recorded fail and are
+// reported by Err. Redacted attributes are replayed without value, decrypted data as zeros of the
+// recorded length and random data is read from crypto/rand. Nonces and IVs are not matched, single
+// blocks can not be encrypted or decrypted with secret keys.
+type ReplayContext struct {
+	mutex        sync.Mutex
+	interactions []interaction
+	used         []bool
+	unexpected   []error
+}
+
+type replayKeyPair struct {
+	replay *ReplayContext
+	handle int
+	public crypto.PublicKey
+}
+
+type replayRSAKeyPair struct {
+	replayKeyPair
+}
+
+type replaySecretKey struct {
+	replay *ReplayContext
+	handle int
+}
+
+type replayAEAD struct {
+	key replaySecretKey
+	recordedAEAD
+}
+
+type replayBlockMode struct {
+	key     replaySecretKey
+	encrypt bool
+}
+
+var errNotRecorded = errors.New("single block operations of secret keys are not recorded")
+
+// NewReplayContext reads a recording of RecordingContext.
+func NewReplayContext(r io.Reader) (*ReplayContext, error) {
+	replay := &ReplayContext{}
+	decoder := json.NewDecoder(r)
+	for {
+		var call interaction
+		if err := decoder.Decode(&call); err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, fmt.Errorf("failed reading recording: %w", err)
+		}
+		replay.interactions = append(replay.interactions, call)
+	}
+	replay.used = make([]bool, len(replay.interactions))
+	return replay, nil
+}
+
+// Err returns the calls which were not recorded.
+func (r *ReplayContext) Err() error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return errors.Join(r.unexpected...)
+}
+
+// Unused returns the number of recorded calls which were not replayed.
+func (r *ReplayContext) Unused() int {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	unused := 0
+	for _, used := range r.used {
+		if !used {
+			unused++
+		}
+	}
+	return unused
+}
+
+// call replays the first unused recorded call of the method with equal arguments.
+func (r *ReplayContext) call(method string, args ...interface{}) ([]json.RawMessage, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	encoded := encodeValues(args)
+	for i, call := range r.interactions {
+		if r.used[i] || call.Method != method || !equalValues(call.Args, encoded) {
+			continue
+		}
+		r.used[i] = true
+		if call.Error != nil {
+			return nil, call.Error.err()
+		}
+		return call.Results, nil
+	}
+	arguments := make([]string, len(encoded))
+	for i, arg := range encoded {
+		arguments[i] = string(arg)
+	}
+	err := fmt.Errorf("unexpected call %s(%s)", method, strings.Join(arguments, ", "))
+	r.unexpected = append(r.unexpected, err)
+	return nil, err
+}
+
+func equalValues(recorded, actual []json.RawMessage) bool {
+	if len(recorded) != len(actual) {
+		return false
+	}
+	for i := range recorded {
+		var compact bytes.Buffer
+		if json.Compact(&compact, recorded[i]) != nil || !bytes.Equal(compact.Bytes(), actual[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+func result(results []json.RawMessage) json.RawMessage {
+	if len(results) == 0 {
+		return json.RawMessage("null")
+	}
+	return results[0]
+}
+
+func (r *ReplayContext) decodeKeyPair(raw json.RawMessage) (crypto11.Signer, error) {
+	var key *recordedKey
+	if err := json.Unmarshal(raw, &key); err != nil || key == nil {
+		return nil, err
+	}
+	public, err := x509.ParsePKIXPublicKey(key.Public)
+	if err != nil {
+		return nil, fmt.Errorf("failed replaying public key: %w", err)
+	}
+	keyPair := replayKeyPair{replay: r, handle: key.Handle, public: public}
+	if key.Decrypter {
+		return replayRSAKeyPair{keyPair}, nil
+	}
+	return keyPair, nil
+}
+
+func (r *ReplayContext) decodeKeyPairs(raw json.RawMessage) ([]crypto11.Signer, error) {
+	var keys []json.RawMessage
+	if err := json.Unmarshal(raw, &keys); err != nil || keys == nil {
+		return nil, err
+	}
+	signers := make([]crypto11.Signer, len(keys))
+	for i, key := range keys {
+		signer, err := r.decodeKeyPair(key)
+		if err != nil {
+			return nil, err
+		}
+		signers[i] = signer
+	}
+	return signers, nil
+}
+
+func (r *ReplayContext) decodeSecretKey(raw json.RawMessage) (SecretKey, error) {
+	var key *recordedKey
+	if err := json.Unmarshal(raw, &key); err != nil || key == nil {
+		return nil, err
+	}
+	return replaySecretKey{replay: r, handle: key.Handle}, nil
+}
+
+func (r *ReplayContext) decodeSecretKeys(raw json.RawMessage) ([]SecretKey, error) {
+	var keys []json.RawMessage
+	if err := json.Unmarshal(raw, &keys); err != nil || keys == nil {
+		return nil, err
+	}
+	secretKeys := make([]SecretKey, len(keys))
+	for i, key := range keys {
+		secretKey, err := r.decodeSecretKey(key)
+		if err != nil {
+			return nil, err
+		}
+		secretKeys[i] = secretKey
+	}
+	return secretKeys, nil
+}
+
+func decodeAttribute(attributeType crypto11.AttributeType, value string) (*crypto11.Attribute, error) {
+	if value == redacted {
+		return pkcs11.NewAttribute(attributeType, nil), nil
+	}
+	decoded, err := base64.StdEncoding.DecodeString(value)
+	if err != nil {
+		return nil, fmt.Errorf("failed replaying attribute: %w", err)
+	}
+	return pkcs11.NewAttribute(attributeType, decoded), nil
+}
+
+func decodeAttributes(raw json.RawMessage) (crypto11.AttributeSet, error) {
+	var attributes map[string]string
+	if err := json.Unmarshal(raw, &attributes); err != nil || attributes == nil {
+		return nil, err
+	}
+	set := crypto11.NewAttributeSet()
+	for key, value := range attributes {
+		attributeType, err := strconv.ParseUint(key, 0, 64)
+		if err != nil {
+			return nil, fmt.Errorf("failed replaying attribute type: %w", err)
+		}
+		if set[crypto11.AttributeType(attributeType)], err = decodeAttribute(crypto11.AttributeType(attributeType), value); err != nil {
+			return nil, err
+		}
+	}
+	return set, nil
+}
+
+func (k replayKeyPair) keyHandle() int {
+	return k.handle
+}
+
+func (k replayKeyPair) Public() crypto.PublicKey {
+	return k.public
+}
+
+func (k replayKeyPair) Sign(_ io.Reader, digest []byte, opts crypto.SignerOpts) ([]byte, error) {
+	results, err := k.replay.call("Sign", k.handle, digest, encodeOptions(opts))
+	if err != nil {
+		return nil, err
+	}
+	var signature []byte
+	err = json.Unmarshal(result(results), &signature)
+	return signature, err
+}
+
+func (k replayKeyPair) Delete() error {
+	_, err := k.replay.call("Delete", k.handle)
+	return err
+}
+
+func (k replayRSAKeyPair) Decrypt(_ io.Reader, msg []byte, opts crypto.DecrypterOpts) ([]byte, error) {
+	results, err := k.replay.call("Decrypt", k.handle, msg, encodeOptions(opts))
+	if err != nil {
+		return nil, err
+	}
+	var length int
+	if err := json.Unmarshal(result(results), &length); err != nil {
+		return nil, err
+	}
+	return make([]byte, length), nil
+}
+
+func (k replaySecretKey) keyHandle() int {
+	return k.handle
+}
+
+func (k replaySecretKey) BlockSize() int {
+	return aes.BlockSize
+}
+
+func (k replaySecretKey) Encrypt(_, _ []byte) {
+	panic(errNotRecorded)
+}
+
+func (k replaySecretKey) Decrypt(_, _ []byte) {
+	panic(errNotRecorded)
+}
+
+func (k replaySecretKey) NewGCM() (cipher.AEAD, error) {
+	results, err := k.replay.call("NewGCM", k.handle)
+	if err != nil {
+		return nil, err
+	}
+	aead := replayAEAD{key: k}
+	err = json.Unmarshal(result(results), &aead.recordedAEAD)
+	return aead, err
+}
+
+func (k replaySecretKey) NewCBCEncrypter(_ []byte) (cipher.BlockMode, error) {
+	if _, err := k.replay.call("NewCBCEncrypter", k.handle); err != nil {
+		return nil, err
+	}
+	return replayBlockMode{key: k, encrypt: true}, nil
+}
+
+func (k replaySecretKey) NewCBCDecrypter(_ []byte) (cipher.BlockMode, error) {
+	if _, err := k.replay.call("NewCBCDecrypter", k.handle); err != nil {
+		return nil, err
+	}
+	return replayBlockMode{key: k}, nil
+}
+
+func (k replaySecretKey) Delete() error {
+	_, err := k.replay.call("Delete", k.handle)
+	return err
+}
+
+func (a replayAEAD) NonceSize() int {
+	return a.recordedAEAD.NonceSize
+}
+
+func (a replayAEAD) Overhead() int {
+	return a.recordedAEAD.Overhead
+}
+
+// Seal returns the recorded ciphertext and, like Seal of crypto11, panics if the call was not recorded.
+func (a replayAEAD) Seal(dst, _, plaintext, additionalData []byte) []byte {
+	results, err := a.key.replay.call("Seal", a.key.handle, len(plaintext), additionalData)
+	if err != nil {
+		panic(err)
+	}
+	var ciphertext []byte
+	if err := json.Unmarshal(result(results), &ciphertext); err != nil {
+		panic(err)
+	}
+	return append(dst, ciphertext...)
+}
+
+func (a replayAEAD) Open(dst, _, ciphertext, additionalData []byte) ([]byte, error) {
+	results, err := a.key.replay.call("Open", a.key.handle, ciphertext, additionalData)
+	if err != nil {
+		return nil, err
+	}
+	var length int
+	if err := json.Unmarshal(result(results), &length); err != nil {
+		return nil, err
+	}
+	return append(dst, make([]byte, length)...), nil
+}
+
+func (m replayBlockMode) BlockSize() int {
+	return aes.BlockSize
+}
+
+// CryptBlocks writes the recorded ciphertext or zeros for decrypted data to dst and panics if the call
+// was not recorded.
+func (m replayBlockMode) CryptBlocks(dst, src []byte) {
+	if !m.encrypt {
+		if _, err := m.key.replay.call("CBCDecrypt", m.key.handle, src); err != nil {
+			panic(err)
+		}
+		clear(dst[:len(src)])
+		return
+	}
+	results, err := m.key.replay.call("CBCEncrypt", m.key.handle, len(src))
+	if err != nil {
+		panic(err)
+	}
+	var ciphertext []byte
+	if err := json.Unmarshal(result(results), &ciphertext); err != nil {
+		panic(err)
+	}
+	copy(dst, ciphertext)
+}
+
+func (r *ReplayContext) keyPair(method string, args ...interface{}) (crypto11.Signer, error) {
+	results, err := r.call(method, args...)
+	if err != nil {
+		return nil, err
+	}
+	return r.decodeKeyPair(result(results))
+}
+
+func (r *ReplayContext) rsaKeyPair(method string, args ...interface{}) (crypto11.SignerDecrypter, error) {
+	signer, err := r.keyPair(method, args...)
+	if err != nil || signer == nil {
+		return nil, err
+	}
+	keyPair, ok := signer.(crypto11.SignerDecrypter)
+	if !ok {
+		return nil, fmt.Errorf("recorded key pair of %s is no RSA key pair", method)
+	}
+	return keyPair, nil
+}
+
+func (r *ReplayContext) keyPairs(method string, args ...interface{}) ([]crypto11.Signer, error) {
+	results, err := r.call(method, args...)
+	if err != nil {
+		return nil, err
+	}
+	return r.decodeKeyPairs(result(results))
+}
+
+func (r *ReplayContext) secretKey(method string, args ...interface{}) (SecretKey, error) {
+	results, err := r.call(method, args...)
+	if err != nil {
+		return nil, err
+	}
+	return r.decodeSecretKey(result(results))
+}
+
+func (r *ReplayContext) secretKeyList(method string, args ...interface{}) ([]SecretKey, error) {
+	results, err := r.call(method, args...)
+	if err != nil {
+		return nil, err
+	}
+	return r.decodeSecretKeys(result(results))
+}
+
+func (r *ReplayContext) attributes(method string, args ...interface{}) (crypto11.AttributeSet, error) {
+	results, err := r.call(method, args...)
+	if err != nil {
+		return nil, err
+	}
+	return decodeAttributes(result(results))
+}
+
+func (r *ReplayContext) attribute(method string, key interface{}, attributeType crypto11.AttributeType) (*crypto11.Attribute, error) {
+	results, err := r.call(method, key, attributeType)
+	if err != nil {
+		return nil, err
+	}
+	var value *string
+	if err := json.Unmarshal(result(results), &value); err != nil || value == nil {
+		return nil, err
+	}
+	return decodeAttribute(attributeType, *value)
+}
+
+func (r *ReplayContext) GenerateRSAKeyPair(id []byte, bits int) (crypto11.SignerDecrypter, error) {
+	return r.rsaKeyPair("GenerateRSAKeyPair", id, bits)
+}
+
+func (r *ReplayContext) GenerateRSAKeyPairWithLabel(id, label []byte, bits int) (crypto11.SignerDecrypter, error) {
+	return r.rsaKeyPair("GenerateRSAKeyPairWithLabel", id, label, bits)
+}
+
+func (r *ReplayContext) GenerateRSAKeyPairWithAttributes(public, private crypto11.AttributeSet, bits int) (crypto11.SignerDecrypter, error) {
+	return r.rsaKeyPair("GenerateRSAKeyPairWithAttributes", public.Copy(), private.Copy(), bits)
+}
+
+func (r *ReplayContext) FindKeyPair(id []byte, label []byte) (crypto11.Signer, error) {
+	return r.keyPair("FindKeyPair", id, label)
+}
+
+func (r *ReplayContext) FindKeyPairs(id []byte, label []byte) ([]crypto11.Signer, error) {
+	return r.keyPairs("FindKeyPairs", id, label)
+}
+
+func (r *ReplayContext) FindKeyPairWithAttributes(attributes crypto11.AttributeSet) (crypto11.Signer, error) {
+	return r.keyPair("FindKeyPairWithAttributes", attributes)
+}
+
+func (r *ReplayContext) FindKeyPairsWithAttributes(attributes crypto11.AttributeSet) ([]crypto11.Signer, error) {
+	return r.keyPairs("FindKeyPairsWithAttributes", attributes)
+}
+
+func (r *ReplayContext) FindAllKeyPairs() ([]crypto11.Signer, error) {
+	return r.keyPairs("FindAllKeyPairs")
+}
+
+func (r *ReplayContext) FindKey(id []byte, label []byte) (SecretKey, error) {
+	return r.secretKey("FindKey", id, label)
+}
+
+func (r *ReplayContext) FindKeys(id []byte, label []byte) ([]SecretKey, error) {
+	return r.secretKeyList("FindKeys", id, label)
+}
+
+func (r *ReplayContext) FindKeyWithAttributes(attributes crypto11.AttributeSet) (SecretKey, error) {
+	return r.secretKey("FindKeyWithAttributes", attributes)
+}
+
+func (r *ReplayContext) FindKeysWithAttributes(attributes crypto11.AttributeSet) ([]SecretKey, error) {
+	return r.secretKeyList("FindKeysWithAttributes", attributes)
+}
+
+func (r *ReplayContext) FindAllKeys() ([]SecretKey, error) {
+	return r.secretKeyList("FindAllKeys")
+}
+
+func (r *ReplayContext) GetAttributes(key interface{}, attributes []crypto11.AttributeType) (crypto11.AttributeSet, error) {
+	return r.attributes("GetAttributes", key, attributes)
+}
+
+func (r *ReplayContext) GetAttribute(key interface{}, attribute crypto11.AttributeType) (*crypto11.Attribute, error) {
+	return r.attribute("GetAttribute", key, attribute)
+}
+
+func (r *ReplayContext) GetPubAttributes(key interface{}, attributes []crypto11.AttributeType) (crypto11.AttributeSet, error) {
+	return r.attributes("GetPubAttributes", key, attributes)
+}
+
+func (r *ReplayContext) GetPubAttribute(key interface{}, attribute crypto11.AttributeType) (*crypto11.Attribute, error) {
+	return r.attribute("GetPubAttribute", key, attribute)
+}
+
+func (r *ReplayContext) GenerateECDSAKeyPair(id []byte, curve elliptic.Curve) (crypto11.Signer, error) {
+	return r.keyPair("GenerateECDSAKeyPair", id, curve)
+}
+
+func (r *ReplayContext) GenerateECDSAKeyPairWithLabel(id, label []byte, curve elliptic.Curve) (crypto11.Signer, error) {
+	return r.keyPair("GenerateECDSAKeyPairWithLabel", id, label, curve)
+}
+
+func (r *ReplayContext) GenerateECDSAKeyPairWithAttributes(public, private crypto11.AttributeSet, curve elliptic.Curve) (crypto11.Signer, error) {
+	return r.keyPair("GenerateECDSAKeyPairWithAttributes", public.Copy(), private.Copy(), curve)
+}
+
+func (r *ReplayContext) GenerateSecretKey(id []byte, bits int, cipher *crypto11.SymmetricCipher) (SecretKey, error) {
+	return r.secretKey("GenerateSecretKey", id, bits, cipher)
+}
+
+func (r *ReplayContext) GenerateSecretKeyWithLabel(id, label []byte, bits int, cipher *crypto11.SymmetricCipher) (SecretKey, error) {
+	return r.secretKey("GenerateSecretKeyWithLabel", id, label, bits, cipher)
+}
+
+func (r *ReplayContext) GenerateSecretKeyWithAttributes(template crypto11.AttributeSet, bits int, cipher *crypto11.SymmetricCipher) (SecretKey, error) {
+	return r.secretKey("GenerateSecretKeyWithAttributes", template.Copy(), bits, cipher)
+}
+
+func (r *ReplayContext) NewRandomReader() (io.Reader, error) {
+	if _, err := r.call("NewRandomReader"); err != nil {
+		return nil, err
+	}
+	return rand.Reader, nil
+}
+
+func (r *ReplayContext) ImportCertificateWithLabel(id []byte, label []byte, certificate *x509.Certificate) error {
+	_, err := r.call("ImportCertificateWithLabel", id, label, certificate)
+	return err
+}
+
+func (r *ReplayContext) FindCertificate(id []byte, label []byte, serial *big.Int) (*x509.Certificate, error) {
+	results, err := r.call("FindCertificate", id, label, serial)
+	if err != nil {
+		return nil, err
+	}
+	var raw []byte
+	if err := json.Unmarshal(result(results), &raw); err != nil || raw == nil {
+		return nil, err
+	}
+	return x509.ParseCertificate(raw)
+}
+
+func (r *ReplayContext) DeleteCertificate(id []byte, label []byte, serial *big.Int) error {
+	_, err := r.call("DeleteCertificate", id, label, serial)
+	return err
+}
@@ -0,0 +1,99 @@
+package hsm
+
+import (
+	"crypto/cipher"
+	"fmt"
+
+	"github.com/ThalesIgnite/crypto11"
+)
+
+// SecretKey is a symmetric key of the partition. *crypto11.SecretKey implements it, attributes are read
+// with ContextType.GetAttribute and GetAttributes like those of key pairs.
+type SecretKey interface {
+	// BlockSize, Encrypt and Decrypt operate on single blocks, see cipher.Block.
+	cipher.Block
+	// NewGCM returns the key in Galois Counter Mode.
+	NewGCM() (cipher.AEAD, error)
+	// NewCBCEncrypter returns a cipher.BlockMode which encrypts in cipher block chaining mode.
+	NewCBCEncrypter(iv []byte) (cipher.BlockMode, error)
+	// NewCBCDecrypter returns a cipher.BlockMode which decrypts in cipher block chaining mode.
+	NewCBCDecrypter(iv []byte) (cipher.BlockMode, error)
+	// Delete destroys the key.
+	Delete() error
+}
+
+// crypto11Context adapts the secret keys of crypto11.Context to ContextType.
+type crypto11Context struct {
+	*crypto11.Context
+}
+
+func secretKey(key *crypto11.SecretKey, err error) (SecretKey, error) {
+	if key == nil {
+		return nil, err
+	}
+	return key, err
+}
+
+func secretKeys(keys []*crypto11.SecretKey, err error) ([]SecretKey, error) {
+	if keys == nil {
+		return nil, err
+	}
+	secretKeys := make([]SecretKey, len(keys))
+	for i, key := range keys {
+		secretKeys[i] = key
+	}
+	return secretKeys, err
+}
+
+func (c crypto11Context) FindKey(id []byte, label []byte) (SecretKey, error) {
+	return secretKey(c.Context.FindKey(id, label))
+}
+
+func (c crypto11Context) FindKeys(id []byte, label []byte) ([]SecretKey, error) {
+	return secretKeys(c.Context.FindKeys(id, label))
+}
+
+func (c crypto11Context) FindKeyWithAttributes(attributes crypto11.AttributeSet) (SecretKey, error) {
+	return secretKey(c.Context.FindKeyWithAttributes(attributes))
+}
+
+func (c crypto11Context) FindKeysWithAttributes(attributes crypto11.AttributeSet) ([]SecretKey, error) {
+	return secretKeys(c.Context.FindKeysWithAttributes(attributes))
+}
+
+func (c crypto11Context) FindAllKeys() ([]SecretKey, error) {
+	return secretKeys(c.Context.FindAllKeys())
+}
+
+func (c crypto11Context) GenerateSecretKey(id []byte, bits int, cipher *crypto11.SymmetricCipher) (SecretKey, error) {
+	return secretKey(c.Context.GenerateSecretKey(id, bits, cipher))
+}
+
+func (c crypto11Context) GenerateSecretKeyWithLabel(id, label []byte, bits int, cipher *crypto11.SymmetricCipher) (SecretKey, error) {
+	return secretKey(c.Context.GenerateSecretKeyWithLabel(id, label, bits, cipher))
+}
+
+func (c crypto11Context) GenerateSecretKeyWithAttributes(template crypto11.AttributeSet, bits int, cipher *crypto11.SymmetricCipher) (SecretKey, error) {
+	return secretKey(c.Context.GenerateSecretKeyWithAttributes(template, bits, cipher))
+}
+
+// seal encrypts with the AEAD of a secret key. The AEAD of crypto11 panics if the HSM rejects the
+// operation, as cipher.AEAD has no error result.
+func seal(aead cipher.AEAD, nonce, plaintext, additionalData []byte) (ciphertext []byte, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("encryption failed: %v", r)
+		}
+	}()
+	return aead.Seal(nil, nonce, plaintext, additionalData), nil
+}
+
+// open decrypts with the AEAD of a secret key and, like seal, turns panics into errors.
+func open(aead cipher.AEAD, nonce, ciphertext, additionalData []byte) (plaintext []byte, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("decryption failed: %v", r)
+		}
+	}()
+	return aead.Open(nil, nonce, ciphertext, additionalData)
+}
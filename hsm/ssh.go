@@ -0,0 +1,124 @@
+package hsm
+
+import (
+	"crypto/rsa"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/eclipse-xfsc/crypto-provider-core/types"
+	"golang.org/x/crypto/ssh"
+)
+
+// SSHCertificateOptions describes an OpenSSH certificate issued by an HSM CA key.
+type SSHCertificateOptions struct {
+	// CertType is ssh.UserCert or ssh.HostCert.
+	CertType uint32
+	// KeyId identifies the certificate in the logs of the SSH server.
+	KeyId      string
+	Principals []string
+	// Validity starts five minutes in the past to tolerate clock skew, zero means forever.
+	Validity        time.Duration
+	CriticalOptions map[string]string
+	// Extensions of user certificates default to the ones ssh-keygen sets if nil.
+	Extensions map[string]string
+}
+
+var defaultSSHUserExtensions = map[string]string{
+	"permit-X11-forwarding":   "",
+	"permit-agent-forwarding": "",
+	"permit-port-forwarding":  "",
+	"permit-pty":              "",
+	"permit-user-rc":          "",
+}
+
+// SSHSigner returns the HSM key as ssh.Signer. RSA keys sign with rsa-sha2-512 or rsa-sha2-256, never
+// with the SHA-1 based ssh-rsa.
+func (p HSMCryptoProvider) SSHSigner(parameter types.CryptoIdentifier) (_ ssh.Signer, err error) {
+	_, op := p.observe("SSHSigner", parameter.CryptoContext, parameter.KeyId)
+	defer op.end(&err)
+	return p.sshSigner(parameter)
+}
+
+func (p HSMCryptoProvider) sshSigner(parameter types.CryptoIdentifier) (ssh.Signer, error) {
+	signer, err := p.getSigner(parameter)
+	if err != nil {
+		return nil, err
+	}
+	if signer == nil {
+		return nil, keyNotFound(parameter.KeyId)
+	}
+	sshSigner, err := ssh.NewSignerFromSigner(signer)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := signer.Public().(*rsa.PublicKey); !ok {
+		return sshSigner, nil
+	}
+	algorithmSigner, ok := sshSigner.(ssh.AlgorithmSigner)
+	if !ok {
+		return nil, fmt.Errorf("ssh signer of key %s does not support signature algorithms", parameter.KeyId)
+	}
+	return ssh.NewSignerWithAlgorithms(algorithmSigner, []string{ssh.KeyAlgoRSASHA512, ssh.KeyAlgoRSASHA256})
+}
+
+// SSHAuthorizedKey returns the public key of the HSM key in authorized_keys format, e.g. for
+// TrustedUserCAKeys or @cert-authority entries.
+func (p HSMCryptoProvider) SSHAuthorizedKey(parameter types.CryptoIdentifier) (_ []byte, err error) {
+	p, op := p.observe("SSHAuthorizedKey", parameter.CryptoContext, parameter.KeyId)
+	defer op.end(&err)
+	signer, err := p.sshSigner(parameter)
+	if err != nil {
+		return nil, err
+	}
+	line := strings.TrimSuffix(string(ssh.MarshalAuthorizedKey(signer.PublicKey())), "\n")
+	return []byte(line + " " + parameter.KeyId + "\n"), nil
+}
+
+// IssueSSHCertificate certifies the public key with the HSM key as SSH CA.
+func (p HSMCryptoProvider) IssueSSHCertificate(ca types.CryptoIdentifier, publicKey ssh.PublicKey, options SSHCertificateOptions) (_ *ssh.Certificate, err error) {
+	p, op := p.observe("IssueSSHCertificate", ca.CryptoContext, ca.KeyId)
+	defer op.end(&err)
+	if options.CertType != ssh.UserCert && options.CertType != ssh.HostCert {
+		return nil, fmt.Errorf("invalid certificate type %d", options.CertType)
+	}
+	if len(options.Principals) == 0 {
+		return nil, errors.New("missing principals")
+	}
+	signer, err := p.sshSigner(ca)
+	if err != nil {
+		return nil, err
+	}
+	serial := make([]byte, 8)
+	if _, err = io.ReadFull(p.controller.rand, serial); err != nil {
+		return nil, err
+	}
+	extensions := options.Extensions
+	if extensions == nil && options.CertType == ssh.UserCert {
+		extensions = defaultSSHUserExtensions
+	}
+	certificate := &ssh.Certificate{
+		Key:             publicKey,
+		Serial:          binary.BigEndian.Uint64(serial),
+		CertType:        options.CertType,
+		KeyId:           options.KeyId,
+		ValidPrincipals: options.Principals,
+		ValidBefore:     ssh.CertTimeInfinity,
+		Permissions: ssh.Permissions{
+			CriticalOptions: options.CriticalOptions,
+			Extensions:      extensions,
+		},
+	}
+	if options.Validity > 0 {
+		now := time.Now()
+		certificate.ValidAfter = uint64(now.Add(-5 * time.Minute).Unix())
+		certificate.ValidBefore = uint64(now.Add(options.Validity).Unix())
+	}
+	if err = certificate.SignCert(p.controller.rand, signer); err != nil {
+		return nil, err
+	}
+	return certificate, nil
+}
@@ -0,0 +1,107 @@
+package hsm
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/eclipse-xfsc/crypto-provider-core/types"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/ssh"
+)
+
+func TestSSHAuthorizedKey(t *testing.T) {
+	key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	var mockApi = new(ContextTypeMock)
+	mockApi.On("FindKeyPair", []byte(testId), []byte(nil)).Return(&SoftSignerMock{key}, nil)
+	provider := getTestHSMCryptoProvider(mockApi)
+
+	line, err := provider.SSHAuthorizedKey(types.CryptoIdentifier{KeyId: testId})
+	assert.Nil(t, err)
+	pub, comment, _, _, err := ssh.ParseAuthorizedKey(line)
+	assert.Nil(t, err)
+	assert.Equal(t, testId, comment)
+	expected, _ := ssh.NewPublicKey(&key.PublicKey)
+	assert.True(t, bytes.Equal(expected.Marshal(), pub.Marshal()))
+}
+
+func TestIssueSSHCertificate_User(t *testing.T) {
+	key, _ := rsa.GenerateKey(rand.Reader, 2048)
+	var mockApi = new(ContextTypeMock)
+	mockApi.On("FindKeyPair", []byte(testId), []byte(nil)).Return(&SoftSignerMock{key}, nil)
+	provider := getTestHSMCryptoProvider(mockApi)
+	caSigner, err := provider.SSHSigner(types.CryptoIdentifier{KeyId: testId})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{ssh.KeyAlgoRSASHA512, ssh.KeyAlgoRSASHA256}, caSigner.(ssh.MultiAlgorithmSigner).Algorithms())
+	_, err = caSigner.(ssh.AlgorithmSigner).SignWithAlgorithm(rand.Reader, []byte("data"), ssh.KeyAlgoRSA)
+	assert.NotNil(t, err)
+
+	userKey, _, _ := ed25519.GenerateKey(rand.Reader)
+	userPub, _ := ssh.NewPublicKey(userKey)
+	certificate, err := provider.IssueSSHCertificate(types.CryptoIdentifier{KeyId: testId}, userPub, SSHCertificateOptions{
+		CertType:        ssh.UserCert,
+		KeyId:           "alice",
+		Principals:      []string{"alice"},
+		Validity:        time.Hour,
+		CriticalOptions: map[string]string{"source-address": "10.0.0.0/8"},
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, ssh.KeyAlgoRSASHA512, certificate.Signature.Format)
+	assert.Contains(t, certificate.Extensions, "permit-pty")
+
+	checker := ssh.CertChecker{IsUserAuthority: func(auth ssh.PublicKey) bool {
+		return bytes.Equal(auth.Marshal(), caSigner.PublicKey().Marshal())
+	}}
+	permissions, err := checker.Authenticate(connMetadata{user: "alice", addr: &net.TCPAddr{IP: net.IPv4(10, 1, 2, 3)}}, certificate)
+	assert.Nil(t, err)
+	assert.Equal(t, "10.0.0.0/8", permissions.CriticalOptions["source-address"])
+	_, err = checker.Authenticate(connMetadata{user: "bob", addr: &net.TCPAddr{IP: net.IPv4(10, 1, 2, 3)}}, certificate)
+	assert.NotNil(t, err)
+}
+
+func TestIssueSSHCertificate_Host(t *testing.T) {
+	key, _ := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	var mockApi = new(ContextTypeMock)
+	mockApi.On("FindKeyPair", []byte(testId), []byte(nil)).Return(&SoftSignerMock{key}, nil)
+	provider := getTestHSMCryptoProvider(mockApi)
+	caPub, _ := ssh.NewPublicKey(&key.PublicKey)
+
+	hostKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	hostPub, _ := ssh.NewPublicKey(&hostKey.PublicKey)
+	certificate, err := provider.IssueSSHCertificate(types.CryptoIdentifier{KeyId: testId}, hostPub, SSHCertificateOptions{
+		CertType:   ssh.HostCert,
+		Principals: []string{"host.example.com"},
+	})
+	assert.Nil(t, err)
+	assert.Empty(t, certificate.Extensions)
+	assert.Equal(t, uint64(ssh.CertTimeInfinity), certificate.ValidBefore)
+
+	checker := ssh.CertChecker{IsHostAuthority: func(auth ssh.PublicKey, _ string) bool {
+		return bytes.Equal(auth.Marshal(), caPub.Marshal())
+	}}
+	assert.Nil(t, checker.CheckHostKey("host.example.com:22", &net.TCPAddr{}, certificate))
+	assert.NotNil(t, checker.CheckHostKey("other.example.com:22", &net.TCPAddr{}, certificate))
+
+	_, err = provider.IssueSSHCertificate(types.CryptoIdentifier{KeyId: testId}, hostPub, SSHCertificateOptions{CertType: ssh.HostCert})
+	assert.NotNil(t, err)
+}
+
+type connMetadata struct {
+	ssh.ConnMetadata
+	user string
+	addr net.Addr
+}
+
+func (c connMetadata) User() string {
+	return c.user
+}
+
+func (c connMetadata) RemoteAddr() net.Addr {
+	return c.addr
+}
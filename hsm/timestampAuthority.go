@@ -0,0 +1,265 @@
+package hsm
+
+import (
+	"crypto"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/ThalesIgnite/crypto11"
+	"github.com/digitorus/pkcs7"
+	"github.com/digitorus/timestamp"
+	"github.com/eclipse-xfsc/crypto-provider-core/types"
+)
+
+var (
+	oidContentTypeTSTInfo       = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 16, 1, 4}
+	oidAttributeSigningCertV2   = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 16, 2, 47}
+	timestampDigestAlgorithmOID = map[crypto.Hash]asn1.ObjectIdentifier{
+		crypto.SHA256: pkcs7.OIDDigestAlgorithmSHA256,
+		crypto.SHA384: pkcs7.OIDDigestAlgorithmSHA384,
+		crypto.SHA512: pkcs7.OIDDigestAlgorithmSHA512,
+	}
+)
+
+const maxTimestampRequestSize = 1 << 16
+
+// TimestampAuthorityOptions configures a RFC 3161 timestamp authority.
+type TimestampAuthorityOptions struct {
+	// Policy is the TSA policy OID put into every token. Requests for other policies are rejected.
+	Policy asn1.ObjectIdentifier
+	// Accuracy of the time source, omitted from the tokens if zero.
+	Accuracy time.Duration
+	// Hash is the digest algorithm of the token signature, SHA-256 if not set.
+	Hash crypto.Hash
+}
+
+// TimestampAuthority issues RFC 3161 timestamp tokens signed with an HSM key. The TSA certificate
+// is the leaf of the certificate chain stored with the key and must be valid for time stamping.
+type TimestampAuthority struct {
+	provider    HSMCryptoProvider
+	key         types.CryptoIdentifier
+	signer      crypto11.Signer
+	certificate *x509.Certificate
+	chain       []*x509.Certificate
+	options     TimestampAuthorityOptions
+	now         func() time.Time
+
+	mu         sync.Mutex
+	lastSerial *big.Int
+}
+
+type tstInfo struct {
+	Version        int
+	Policy         asn1.ObjectIdentifier
+	MessageImprint messageImprint
+	SerialNumber   *big.Int
+	GenTime        time.Time        `asn1:"generalized"`
+	Accuracy       accuracy         `asn1:"optional"`
+	Ordering       bool             `asn1:"optional,default:false"`
+	Nonce          *big.Int         `asn1:"optional"`
+	TSA            asn1.RawValue    `asn1:"optional,tag:0"`
+	Extensions     []pkix.Extension `asn1:"optional,tag:1"`
+}
+
+type messageImprint struct {
+	HashAlgorithm pkix.AlgorithmIdentifier
+	HashedMessage []byte
+}
+
+type accuracy struct {
+	Seconds int64 `asn1:"optional"`
+	Millis  int64 `asn1:"optional,tag:0"`
+	Micros  int64 `asn1:"optional,tag:1"`
+}
+
+type essCertIDv2 struct {
+	CertHash []byte
+}
+
+type signingCertificateV2 struct {
+	Certs []essCertIDv2
+}
+
+type timestampResponse struct {
+	Status         pkiStatusInfo
+	TimeStampToken asn1.RawValue `asn1:"optional"`
+}
+
+type pkiStatusInfo struct {
+	Status int
+}
+
+// NewTimestampAuthority creates a timestamp authority signing with the HSM key.
+func (p HSMCryptoProvider) NewTimestampAuthority(key types.CryptoIdentifier, options TimestampAuthorityOptions) (_ *TimestampAuthority, err error) {
+	_, op := p.observe("NewTimestampAuthority", key.CryptoContext, key.KeyId)
+	defer op.end(&err)
+	if len(options.Policy) == 0 {
+		return nil, errors.New("missing TSA policy")
+	}
+	if options.Hash == 0 {
+		options.Hash = crypto.SHA256
+	}
+	if _, ok := timestampDigestAlgorithmOID[options.Hash]; !ok {
+		return nil, fmt.Errorf("unsupported hash %s", options.Hash)
+	}
+	signer, err := p.getSigner(key)
+	if err != nil {
+		return nil, err
+	}
+	if signer == nil {
+		return nil, keyNotFound(key.KeyId)
+	}
+	chain, err := p.certificateChain(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(chain) == 0 {
+		return nil, fmt.Errorf("no TSA certificate stored for key %s", key.KeyId)
+	}
+	if !slices.Equal(chain[0].ExtKeyUsage, []x509.ExtKeyUsage{x509.ExtKeyUsageTimeStamping}) {
+		return nil, fmt.Errorf("certificate of key %s is not valid for time stamping only", key.KeyId)
+	}
+	return &TimestampAuthority{
+		provider:    p,
+		key:         key,
+		signer:      signer,
+		certificate: chain[0],
+		chain:       chain[1:],
+		options:     options,
+		now:         time.Now,
+		lastSerial:  new(big.Int),
+	}, nil
+}
+
+// nextSerial returns strictly increasing serial numbers. They are based on the clock, so they keep
+// increasing across restarts as long as the clock is not set back.
+func (tsa *TimestampAuthority) nextSerial(now time.Time) *big.Int {
+	tsa.mu.Lock()
+	defer tsa.mu.Unlock()
+	serial := big.NewInt(now.UnixNano())
+	if serial.Cmp(tsa.lastSerial) <= 0 {
+		serial.Add(tsa.lastSerial, big.NewInt(1))
+	}
+	tsa.lastSerial = serial
+	return new(big.Int).Set(serial)
+}
+
+// Respond answers a DER encoded TimeStampReq with a DER encoded TimeStampResp. Invalid requests are
+// answered with a rejection, an error is only returned if no response could be produced.
+func (tsa *TimestampAuthority) Respond(request []byte) (_ []byte, err error) {
+	_, op := tsa.provider.observe("TimestampAuthority.Respond", tsa.key.CryptoContext, tsa.key.KeyId)
+	defer op.end(&err)
+	req, err := timestamp.ParseRequest(request)
+	if err != nil {
+		return timestamp.CreateErrorResponse(timestamp.Rejection, timestamp.BadDataFormat)
+	}
+	if _, ok := timestampDigestAlgorithmOID[req.HashAlgorithm]; !ok || len(req.HashedMessage) != req.HashAlgorithm.Size() {
+		return timestamp.CreateErrorResponse(timestamp.Rejection, timestamp.BadAlgorithm)
+	}
+	if len(req.TSAPolicyOID) > 0 && !req.TSAPolicyOID.Equal(tsa.options.Policy) {
+		return timestamp.CreateErrorResponse(timestamp.Rejection, timestamp.UnacceptedPolicy)
+	}
+	if len(req.Extensions) > 0 {
+		return timestamp.CreateErrorResponse(timestamp.Rejection, timestamp.UnacceptedExtension)
+	}
+	token, err := tsa.token(req)
+	if err != nil {
+		return timestamp.CreateErrorResponse(timestamp.Rejection, timestamp.SystemFailure)
+	}
+	return asn1.Marshal(timestampResponse{
+		Status:         pkiStatusInfo{Status: int(timestamp.Granted)},
+		TimeStampToken: asn1.RawValue{FullBytes: token},
+	})
+}
+
+func (tsa *TimestampAuthority) token(req *timestamp.Request) ([]byte, error) {
+	now := tsa.now().UTC()
+	tsaName, err := asn1.Marshal(asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 4, IsCompound: true, Bytes: tsa.certificate.RawSubject})
+	if err != nil {
+		return nil, err
+	}
+	info := tstInfo{
+		Version: 1,
+		Policy:  tsa.options.Policy,
+		MessageImprint: messageImprint{
+			HashAlgorithm: pkix.AlgorithmIdentifier{Algorithm: timestampDigestAlgorithmOID[req.HashAlgorithm], Parameters: asn1.NullRawValue},
+			HashedMessage: req.HashedMessage,
+		},
+		SerialNumber: tsa.nextSerial(now),
+		GenTime:      now,
+		Nonce:        req.Nonce,
+		TSA:          asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 0, IsCompound: true, Bytes: tsaName},
+	}
+	if tsa.options.Accuracy > 0 {
+		info.Accuracy = accuracy{
+			Seconds: int64(tsa.options.Accuracy / time.Second),
+			Millis:  int64(tsa.options.Accuracy % time.Second / time.Millisecond),
+			Micros:  int64(tsa.options.Accuracy % time.Millisecond / time.Microsecond),
+		}
+	}
+	content, err := asn1.Marshal(info)
+	if err != nil {
+		return nil, err
+	}
+
+	certHash := sha256.Sum256(tsa.certificate.Raw)
+	signingCertificate, err := asn1.Marshal(signingCertificateV2{Certs: []essCertIDv2{{CertHash: certHash[:]}}})
+	if err != nil {
+		return nil, err
+	}
+	signedData, err := pkcs7.NewSignedData(content)
+	if err != nil {
+		return nil, err
+	}
+	signedData.SetContentType(oidContentTypeTSTInfo)
+	signedData.SetDigestAlgorithm(timestampDigestAlgorithmOID[tsa.options.Hash])
+	signedData.GetSignedData().Version = 3
+	config := pkcs7.SignerInfoConfig{
+		ExtraSignedAttributes: []pkcs7.Attribute{{Type: oidAttributeSigningCertV2, Value: asn1.RawValue{FullBytes: signingCertificate}}},
+		SkipCertificates:      !req.Certificates,
+	}
+	if req.Certificates && len(tsa.chain) > 0 {
+		err = signedData.AddSignerChain(tsa.certificate, tsa.signer, tsa.chain, config)
+	} else {
+		err = signedData.AddSigner(tsa.certificate, tsa.signer, config)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return signedData.Finish()
+}
+
+// ServeHTTP implements the HTTP transport of RFC 3161 section 3.4.
+func (tsa *TimestampAuthority) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if r.Header.Get("Content-Type") != "application/timestamp-query" {
+		http.Error(w, "unsupported content type", http.StatusUnsupportedMediaType)
+		return
+	}
+	request, err := io.ReadAll(io.LimitReader(r.Body, maxTimestampRequestSize))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	response, err := tsa.Respond(request)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/timestamp-reply")
+	_, _ = w.Write(response)
+}
@@ -0,0 +1,93 @@
+package hsm
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"io"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/digitorus/timestamp"
+	"github.com/eclipse-xfsc/crypto-provider-core/types"
+	"github.com/stretchr/testify/assert"
+)
+
+var testTSAPolicy = asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 99999, 1}
+
+func getTestTimestampAuthority(t *testing.T, extKeyUsage x509.ExtKeyUsage) (*TimestampAuthority, error) {
+	key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	var mockApi = new(ContextTypeMock)
+	mockApi.On("FindKeyPair", []byte(testId), []byte(nil)).Return(&SoftSignerMock{key}, nil)
+	provider := getTestHSMCryptoProvider(mockApi)
+	der, err := provider.CreateSelfSignedCertificate(types.CryptoIdentifier{KeyId: testId}, CertificateRequestOptions{
+		Subject:     pkix.Name{CommonName: "tsa"},
+		KeyUsage:    x509.KeyUsageDigitalSignature,
+		ExtKeyUsage: []x509.ExtKeyUsage{extKeyUsage},
+	})
+	assert.Nil(t, err)
+	certificate, _ := x509.ParseCertificate(der)
+	mockApi.On("FindCertificate", []byte(testId), certificateLabel(testId, 0), (*big.Int)(nil)).Return(certificate, nil)
+	mockApi.WithoutCertificates()
+	return provider.NewTimestampAuthority(types.CryptoIdentifier{KeyId: testId}, TimestampAuthorityOptions{Policy: testTSAPolicy, Accuracy: 1500 * time.Millisecond})
+}
+
+func TestTimestampAuthority_Respond(t *testing.T) {
+	tsa, err := getTestTimestampAuthority(t, x509.ExtKeyUsageTimeStamping)
+	assert.Nil(t, err)
+	server := httptest.NewServer(tsa)
+	defer server.Close()
+
+	var serial *big.Int
+	for i := 0; i < 2; i++ {
+		request, err := timestamp.CreateRequest(bytes.NewReader([]byte("document")), &timestamp.RequestOptions{Hash: crypto.SHA256, Certificates: true})
+		assert.Nil(t, err)
+		resp, err := http.Post(server.URL, "application/timestamp-query", bytes.NewReader(request))
+		assert.Nil(t, err)
+		assert.Equal(t, "application/timestamp-reply", resp.Header.Get("Content-Type"))
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+
+		ts, err := timestamp.ParseResponse(body)
+		assert.Nil(t, err)
+		digest := sha256.Sum256([]byte("document"))
+		assert.Equal(t, digest[:], ts.HashedMessage)
+		assert.True(t, ts.Policy.Equal(testTSAPolicy))
+		assert.Equal(t, 1500*time.Millisecond, ts.Accuracy)
+		assert.Equal(t, "tsa", ts.Certificates[0].Subject.CommonName)
+		if serial != nil {
+			assert.Equal(t, 1, ts.SerialNumber.Cmp(serial))
+		}
+		serial = ts.SerialNumber
+	}
+}
+
+func TestTimestampAuthority_Reject(t *testing.T) {
+	tsa, err := getTestTimestampAuthority(t, x509.ExtKeyUsageTimeStamping)
+	assert.Nil(t, err)
+
+	request, _ := timestamp.CreateRequest(bytes.NewReader([]byte("document")), &timestamp.RequestOptions{Hash: crypto.SHA256, TSAPolicyOID: asn1.ObjectIdentifier{1, 2, 3}})
+	body, err := tsa.Respond(request)
+	assert.Nil(t, err)
+	_, err = timestamp.ParseResponse(body)
+	assert.NotNil(t, err)
+
+	body, err = tsa.Respond([]byte("garbage"))
+	assert.Nil(t, err)
+	_, err = timestamp.ParseResponse(body)
+	assert.NotNil(t, err)
+}
+
+func TestNewTimestampAuthority_WrongExtKeyUsage(t *testing.T) {
+	_, err := getTestTimestampAuthority(t, x509.ExtKeyUsageServerAuth)
+	assert.NotNil(t, err)
+}
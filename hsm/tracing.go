@@ -0,0 +1,26 @@
+package hsm
+
+import (
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+// tracerName is the instrumentation scope of the spans.
+const tracerName = "github.com/eclipse-xfsc/crypto-provider-luna-cloud-hsm-plugin/hsm"
+
+// newTracer returns the tracer of the provider, or of the global provider of the process if provider
+// is nil, so host applications can inject theirs with otel.SetTracerProvider.
+func newTracer(provider trace.TracerProvider) trace.Tracer {
+	if provider == nil {
+		provider = otel.GetTracerProvider()
+	}
+	return provider.Tracer(tracerName)
+}
+
+func (c *hsmController) trace() trace.Tracer {
+	if c.tracer == nil {
+		return noop.NewTracerProvider().Tracer(tracerName)
+	}
+	return c.tracer
+}
@@ -0,0 +1,30 @@
+package hsm
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// newTracingContext decorates the context with a span for every call to the partition, as child of the
+// span of the operation of the provider in ctx.
+func newTracingContext(api ContextType, ctx context.Context, tracer trace.Tracer) ContextType {
+	return NewInterceptorContext(api, func(call *Call, invoke func() error) error {
+		_, span := tracer.Start(ctx, "pkcs11."+call.Method, trace.WithSpanKind(trace.SpanKindClient))
+		err := invoke()
+		endSpan(span, err)
+		return err
+	})
+}
+
+// endSpan records the error class and the message of err, which never contain key material.
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.SetAttributes(attribute.String("hsm.error_class", errorClass(err)))
+		span.RecordError(err)
+		span.SetStatus(codes.Error, errorClass(err))
+	}
+	span.End()
+}
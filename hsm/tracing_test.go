@@ -0,0 +1,83 @@
+package hsm
+
+import (
+	"context"
+	"testing"
+
+	"github.com/eclipse-xfsc/crypto-provider-core/types"
+	"github.com/miekg/pkcs11"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func spanAttributes(span sdktrace.ReadOnlySpan) map[attribute.Key]string {
+	attributes := map[attribute.Key]string{}
+	for _, attr := range span.Attributes() {
+		attributes[attr.Key] = attr.Value.Emit()
+	}
+	return attributes
+}
+
+func TestTracing_Spans(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	tracerProvider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	provider, err := New(Options{
+		Backend:        DevBackend,
+		TracerProvider: tracerProvider,
+		Faults:         []Fault{{Methods: []string{"Sign"}, Times: 1, Error: pkcs11.Error(pkcs11.CKR_DEVICE_ERROR)}},
+	})
+	assert.Nil(t, err)
+	ctx, parent := tracerProvider.Tracer("test").Start(context.Background(), "request")
+	identifier := types.CryptoIdentifier{KeyId: testId, CryptoContext: types.CryptoContext{Namespace: "tenant", Context: ctx}}
+
+	assert.Nil(t, provider.GenerateKey(types.CryptoKeyParameter{Identifier: identifier, KeyType: types.Ecdsap256}))
+	_, err = provider.Sign(identifier, []byte("data"))
+	assert.NotNil(t, err)
+	recorder.Reset()
+	_, err = provider.Sign(identifier, []byte("data"))
+	assert.Nil(t, err)
+	parent.End()
+
+	spans := recorder.Ended()
+	assert.Len(t, spans, 4)
+	names := map[string]sdktrace.ReadOnlySpan{}
+	for _, span := range spans {
+		names[span.Name()] = span
+		assert.Equal(t, parent.SpanContext().TraceID(), span.SpanContext().TraceID())
+		for _, value := range spanAttributes(span) {
+			assert.NotContains(t, value, "data")
+		}
+	}
+	sign := names["hsm.Sign"]
+	assert.Equal(t, parent.SpanContext().SpanID(), sign.Parent().SpanID())
+	assert.Equal(t, "tenant", spanAttributes(sign)["hsm.namespace"])
+	assert.Equal(t, "ecdsa-p256", spanAttributes(sign)["hsm.key_type"])
+	assert.Equal(t, codes.Unset, sign.Status().Code)
+	assert.Equal(t, sign.SpanContext().SpanID(), names["pkcs11.FindKeyPair"].Parent().SpanID())
+	assert.Equal(t, sign.SpanContext().SpanID(), names["pkcs11.Sign"].Parent().SpanID())
+}
+
+func TestTracing_Errors(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	provider, err := New(Options{
+		Backend:        DevBackend,
+		TracerProvider: sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)),
+		Faults:         []Fault{{Methods: []string{"FindKeyPair"}, Error: pkcs11.Error(pkcs11.CKR_DEVICE_ERROR)}},
+	})
+	assert.Nil(t, err)
+
+	_, err = provider.Sign(types.CryptoIdentifier{KeyId: testId}, []byte("data"))
+	assert.NotNil(t, err)
+
+	spans := recorder.Ended()
+	assert.Len(t, spans, 2)
+	for _, span := range spans {
+		assert.Equal(t, codes.Error, span.Status().Code)
+		assert.Equal(t, "CKR_DEVICE_ERROR", span.Status().Description)
+		assert.Equal(t, "CKR_DEVICE_ERROR", spanAttributes(span)["hsm.error_class"])
+		assert.Len(t, span.Events(), 1)
+	}
+}
@@ -1,12 +1,14 @@
-package main
+package hsm
 
 import (
 	"crypto"
 	"io"
+	"log/slog"
 	"strings"
 
 	"github.com/ThalesIgnite/crypto11"
 	"github.com/eclipse-xfsc/crypto-provider-core/types"
+	"go.opentelemetry.io/otel/trace"
 )
 
 type hsmController struct {
@@ -14,6 +16,11 @@ type hsmController struct {
 	api           ContextType
 	signerOptions crypto.SignerOpts
 	rand          io.Reader
+	derive        ecdhDeriver
+	keyFormat     KeyFormat
+	logger        *slog.Logger
+	metrics       Metrics
+	tracer        trace.Tracer
 }
 
 type HSMCryptoProvider struct {
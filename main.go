@@ -1,8 +1,13 @@
 package main
 
 import (
-	"github.com/ThalesIgnite/crypto11"
+	"io"
+	"os"
+	"strings"
+
 	"github.com/eclipse-xfsc/crypto-provider-core/types"
+	"github.com/eclipse-xfsc/crypto-provider-luna-cloud-hsm-plugin/hsm"
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/spf13/viper"
 )
 
@@ -11,16 +16,40 @@ var Plugin types.CryptoProviderModule = plugin{} //export Plugin Symbol, dont
 type plugin struct{}
 
 func (p plugin) GetCryptoProvider() types.CryptoProvider {
-	config := crypto11.Config{
+	viper.SetDefault("HSM_KEY_EXPORT_FORMAT", string(hsm.DefaultKeyFormat))
+	faults, err := hsm.ParseFaults(viper.GetString("HSM_FAULTS"))
+	if err != nil {
+		panic(err)
+	}
+	logLevel, err := hsm.ParseLogLevel(viper.GetString("HSM_LOG_LEVEL"))
+	if err != nil {
+		panic(err)
+	}
+	var recording io.Writer
+	if path := viper.GetString("HSM_RECORD_FILE"); path != "" {
+		if recording, err = os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600); err != nil {
+			panic(err)
+		}
+	}
+	var metrics hsm.Metrics
+	if viper.GetBool("HSM_METRICS_ENABLED") {
+		if metrics, err = hsm.NewPrometheusMetrics(prometheus.DefaultRegisterer, strings.Split(viper.GetString("HSM_METRICS_NAMESPACES"), ",")...); err != nil {
+			panic(err)
+		}
+	}
+	provider, err := hsm.New(hsm.Options{
+		Backend:    hsm.Backend(viper.GetString("HSM_BACKEND")),
 		Path:       viper.GetString("CRYPTO_EXECUTABLE_PATH"),
 		TokenLabel: viper.GetString("HSM_PARTITION_LABEL"),
 		Pin:        viper.GetString("HSM_PARTITION_PASSWORD"),
-	}
-	def := hsmController{config: &config, signerOptions: nil}
-	controller, err := def.withApiAndRandomReader()
+		KeyFormat:  hsm.KeyFormat(viper.GetString("HSM_KEY_EXPORT_FORMAT")),
+		Faults:     faults,
+		Recording:  recording,
+		LogLevel:   logLevel,
+		Metrics:    metrics,
+	})
 	if err != nil {
 		panic(err)
 	}
-	provider := HSMCryptoProvider{controller: controller}
 	return provider
 }
@@ -0,0 +1,376 @@
+//go:build integration
+
+// Integration tests against a SoftHSM2 token, run with
+//
+//	go test -tags integration .
+//
+// softhsm2-util must be on the PATH, the library is searched in the usual locations or taken from SOFTHSM2_LIB.
+package main
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/eclipse-xfsc/crypto-provider-core/types"
+	"github.com/eclipse-xfsc/crypto-provider-luna-cloud-hsm-plugin/conformance"
+	"github.com/eclipse-xfsc/crypto-provider-luna-cloud-hsm-plugin/hsm"
+	"github.com/lestrrat-go/jwx/v2/jwa"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var softHSMLibraries = []string{
+	"/usr/lib/softhsm/libsofthsm2.so",
+	"/usr/lib/x86_64-linux-gnu/softhsm/libsofthsm2.so",
+	"/usr/lib/aarch64-linux-gnu/softhsm/libsofthsm2.so",
+	"/usr/local/lib/softhsm/libsofthsm2.so",
+	"/opt/homebrew/lib/softhsm/libsofthsm2.so",
+}
+
+// provider is configured once, SoftHSM2 reads its token directory when the library is initialized.
+var provider hsm.HSMCryptoProvider
+
+func TestMain(m *testing.M) {
+	dir, err := os.MkdirTemp("", "softhsm")
+	if err != nil {
+		panic(err)
+	}
+	err = provisionToken(dir)
+	if err == nil {
+		provider, err = getProvider()
+	}
+	code := 1
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "SoftHSM2 setup failed:", err)
+	} else {
+		code = m.Run()
+	}
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
+
+// provisionToken creates a token "plugin-test" with user PIN 1234 in dir and configures the plugin for it
+// with the viper keys of GetCryptoProvider.
+func provisionToken(dir string) error {
+	library := os.Getenv("SOFTHSM2_LIB")
+	for _, candidate := range softHSMLibraries {
+		if _, err := os.Stat(candidate); library == "" && err == nil {
+			library = candidate
+		}
+	}
+	if library == "" {
+		return errors.New("libsofthsm2.so not found, set SOFTHSM2_LIB")
+	}
+	tokens := filepath.Join(dir, "tokens")
+	if err := os.Mkdir(tokens, 0700); err != nil {
+		return err
+	}
+	config := filepath.Join(dir, "softhsm2.conf")
+	if err := os.WriteFile(config, []byte("directories.tokendir = "+tokens+"\nobjectstore.backend = file\n"), 0600); err != nil {
+		return err
+	}
+	if err := os.Setenv("SOFTHSM2_CONF", config); err != nil {
+		return err
+	}
+	output, err := exec.Command("softhsm2-util", "--init-token", "--free", "--label", "plugin-test", "--pin", "1234", "--so-pin", "5678").CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("softhsm2-util: %w: %s", err, output)
+	}
+	viper.Set("CRYPTO_EXECUTABLE_PATH", library)
+	viper.Set("HSM_PARTITION_LABEL", "plugin-test")
+	viper.Set("HSM_PARTITION_PASSWORD", "1234")
+	return nil
+}
+
+func getProvider() (p hsm.HSMCryptoProvider, err error) {
+	// GetCryptoProvider panics if the token can not be used
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%v", r)
+		}
+	}()
+	return Plugin.GetCryptoProvider().(hsm.HSMCryptoProvider), nil
+}
+
+func generate(t *testing.T, id string, keyType types.KeyType) types.CryptoIdentifier {
+	identifier := types.CryptoIdentifier{KeyId: id}
+	assert.Nil(t, provider.GenerateKey(types.CryptoKeyParameter{Identifier: identifier, KeyType: keyType}))
+	t.Cleanup(func() { _ = provider.DeleteKey(identifier) })
+	return identifier
+}
+
+func publicKey(t *testing.T, identifier types.CryptoIdentifier) crypto.PublicKey {
+	key, err := provider.GetKey(identifier)
+	assert.Nil(t, err)
+	block, _ := pem.Decode(key.Key)
+	require.NotNil(t, block)
+	public, err := x509.ParsePKIXPublicKey(block.Bytes)
+	assert.Nil(t, err)
+	return public
+}
+
+func TestIntegration_Context(t *testing.T) {
+	context := types.CryptoContext{Namespace: hsm.HsmNamespace}
+	assert.Nil(t, provider.CreateCryptoContext(context))
+	existing, err := provider.IsCryptoContextExisting(context)
+	assert.Nil(t, err)
+	assert.True(t, existing)
+	namespaces, err := provider.GetNamespaces(context)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{hsm.HsmNamespace}, namespaces)
+	assert.Nil(t, provider.DestroyCryptoContext(context))
+}
+
+func TestIntegration_RandomAndHash(t *testing.T) {
+	random, err := provider.GenerateRandom(types.CryptoContext{}, 32)
+	assert.Nil(t, err)
+	assert.Len(t, random, 32)
+
+	hash, err := provider.Hash(types.CryptoHashParameter{HashAlgorithm: types.Sha2256}, []byte("data"))
+	assert.Nil(t, err)
+	expected := sha256.Sum256([]byte("data"))
+	assert.Equal(t, expected[:], hash)
+	_, err = provider.Hash(types.CryptoHashParameter{HashAlgorithm: types.Sha2512}, []byte("data"))
+	assert.ErrorIs(t, err, errors.ErrUnsupported)
+	assert.Equal(t, []types.HashAlgorithm{types.Sha2256}, provider.GetSupportedHashAlgs())
+}
+
+func TestIntegration_Keys(t *testing.T) {
+	ec := generate(t, "integration-ec", types.Ecdsap384)
+	generate(t, "integration-rsa", types.Rsa2048)
+	aes := generate(t, "integration-aes", types.Aes256GCM)
+	err := provider.GenerateKey(types.CryptoKeyParameter{Identifier: types.CryptoIdentifier{KeyId: "integration-ed"}, KeyType: types.Ed25519})
+	assert.ErrorIs(t, err, errors.ErrUnsupported)
+
+	key, err := provider.GetKey(ec)
+	assert.Nil(t, err)
+	assert.Equal(t, types.Ecdsap384, key.KeyType)
+	existing, err := provider.IsKeyExisting(ec)
+	assert.Nil(t, err)
+	assert.True(t, existing)
+
+	keys, err := provider.GetKeys(types.CryptoFilter{Filter: *regexp.MustCompile("^integration-")})
+	assert.Nil(t, err)
+	assert.Len(t, keys.Keys, 2)
+	keys, err = provider.GetKeys(types.CryptoFilter{Filter: *regexp.MustCompile("-rsa$")})
+	assert.Nil(t, err)
+	assert.Len(t, keys.Keys, 1)
+	assert.Equal(t, types.Rsa2048, keys.Keys[0].KeyType)
+
+	assert.ErrorIs(t, provider.RotateKey(ec), errors.ErrUnsupported)
+
+	assert.Nil(t, provider.DeleteKey(ec))
+	_, err = provider.GetKey(ec)
+	assert.NotNil(t, err)
+	assert.Nil(t, provider.DeleteKey(aes))
+	assert.NotNil(t, provider.DeleteKey(aes))
+}
+
+func TestIntegration_ECDSA(t *testing.T) {
+	identifier := generate(t, "integration-ecdsa", types.Ecdsap256)
+	public := publicKey(t, identifier).(*ecdsa.PublicKey)
+	digest := sha256.Sum256([]byte("data"))
+
+	signature, err := provider.Sign(identifier, []byte("data"))
+	assert.Nil(t, err)
+	assert.True(t, ecdsa.VerifyASN1(public, digest[:], signature))
+
+	signer, err := provider.Signer(identifier)
+	assert.Nil(t, err)
+	signature, err = signer.Sign(rand.Reader, digest[:], crypto.SHA256)
+	assert.Nil(t, err)
+	valid, err := provider.Verify(identifier, []byte("data"), signature)
+	assert.Nil(t, err)
+	assert.True(t, valid)
+	valid, _ = provider.Verify(identifier, []byte("other"), signature)
+	assert.False(t, valid)
+}
+
+func TestIntegration_RSA(t *testing.T) {
+	identifier := generate(t, "integration-rsa-pss", types.Rsa2048)
+	public := publicKey(t, identifier).(*rsa.PublicKey)
+	digest := sha256.Sum256([]byte("data"))
+
+	signer, err := provider.Signer(identifier)
+	assert.Nil(t, err)
+	signature, err := signer.Sign(rand.Reader, digest[:], &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash, Hash: crypto.SHA256})
+	assert.Nil(t, err)
+	valid, err := provider.Verify(identifier, []byte("data"), signature)
+	assert.Nil(t, err)
+	assert.True(t, valid)
+
+	decrypter, err := provider.Decrypter(identifier)
+	assert.Nil(t, err)
+	ciphertext, _ := rsa.EncryptPKCS1v15(rand.Reader, public, []byte("secret"))
+	plaintext, err := decrypter.Decrypt(rand.Reader, ciphertext, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, []byte("secret"), plaintext)
+}
+
+func TestIntegration_JWE(t *testing.T) {
+	ec := generate(t, "integration-jwe-ec", types.Ecdsap256)
+	rsaKey := generate(t, "integration-jwe-rsa", types.Rsa2048)
+
+	for _, test := range []struct {
+		identifier types.CryptoIdentifier
+		alg        jwa.KeyEncryptionAlgorithm
+	}{
+		{ec, jwa.ECDH_ES},
+		{ec, jwa.ECDH_ES_A256KW},
+		{rsaKey, jwa.RSA_OAEP_256},
+	} {
+		t.Run(test.alg.String(), func(t *testing.T) {
+			// ECDH-ES derives on the token, which needs CKA_DERIVE on the private key
+			encrypted, err := provider.EncryptJWE(test.identifier, []byte("presentation"), hsm.JWEOptions{KeyAlgorithm: test.alg})
+			require.Nil(t, err)
+			decrypted, err := provider.DecryptJWE(test.identifier, encrypted, hsm.JWEOptions{})
+			assert.Nil(t, err)
+			assert.Equal(t, []byte("presentation"), decrypted)
+		})
+	}
+}
+
+func TestIntegration_AES(t *testing.T) {
+	identifier := generate(t, "integration-gcm", types.Aes256GCM)
+
+	aead, err := provider.AEAD(identifier)
+	assert.Nil(t, err)
+	nonce := make([]byte, aead.NonceSize())
+	_, _ = rand.Read(nonce)
+	sealed := aead.Seal(nil, nonce, []byte("secret"), []byte("aad"))
+	opened, err := aead.Open(nil, nonce, sealed, []byte("aad"))
+	assert.Nil(t, err)
+	assert.Equal(t, []byte("secret"), opened)
+	_, err = aead.Open(nil, nonce, sealed, []byte("other"))
+	assert.NotNil(t, err)
+
+	ciphertext, err := provider.Encrypt(identifier, []byte("secret"))
+	require.Nil(t, err)
+	plaintext, err := provider.Decrypt(identifier, ciphertext)
+	assert.Nil(t, err)
+	assert.Equal(t, []byte("secret"), plaintext)
+	ciphertext[len(ciphertext)-1] ^= 1
+	_, err = provider.Decrypt(identifier, ciphertext)
+	assert.NotNil(t, err)
+}
+
+func TestIntegration_COSE(t *testing.T) {
+	ec := generate(t, "integration-cose-ec", types.Ecdsap256)
+	aes := generate(t, "integration-cose-aes", types.Aes256GCM)
+
+	signed, err := provider.SignCOSE(ec, []byte("mdoc"), hsm.COSEOptions{ExternalAAD: []byte("aad")})
+	require.Nil(t, err)
+	payload, err := provider.VerifyCOSE(ec, signed, nil, hsm.COSEOptions{ExternalAAD: []byte("aad")})
+	assert.Nil(t, err)
+	assert.Equal(t, []byte("mdoc"), payload)
+	_, err = provider.VerifyCOSE(ec, signed, nil, hsm.COSEOptions{})
+	assert.NotNil(t, err)
+
+	encrypted, err := provider.EncryptCOSE(aes, []byte("secret"), hsm.COSEOptions{ExternalAAD: []byte("aad")})
+	require.Nil(t, err)
+	decrypted, err := provider.DecryptCOSE(aes, encrypted, hsm.COSEOptions{ExternalAAD: []byte("aad")})
+	assert.Nil(t, err)
+	assert.Equal(t, []byte("secret"), decrypted)
+}
+
+// issueChain returns a chain of a certificate of the key of the identifier, issued by a software root.
+func issueChain(t *testing.T, identifier types.CryptoIdentifier) []*x509.Certificate {
+	rootKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.Nil(t, err)
+	root := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "integration root"},
+		NotBefore:             time.Now().Add(-time.Minute),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, root, root, rootKey.Public(), rootKey)
+	require.Nil(t, err)
+	root, _ = x509.ParseCertificate(der)
+	leaf := &x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		Subject:      pkix.Name{CommonName: identifier.KeyId},
+		NotBefore:    time.Now().Add(-time.Minute),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+	}
+	der, err = x509.CreateCertificate(rand.Reader, leaf, root, publicKey(t, identifier), rootKey)
+	require.Nil(t, err)
+	leaf, _ = x509.ParseCertificate(der)
+	return []*x509.Certificate{leaf, root}
+}
+
+func TestIntegration_CertificateChain(t *testing.T) {
+	identifier := generate(t, "integration-chain", types.Ecdsap256)
+	chain := issueChain(t, identifier)
+
+	require.Nil(t, provider.ImportCertificateChain(identifier, chain))
+	stored, err := provider.GetCertificateChain(identifier)
+	assert.Nil(t, err)
+	assert.Equal(t, chain, stored)
+	key, err := provider.GetKey(identifier)
+	assert.Nil(t, err)
+	block, _ := pem.Decode(key.Key)
+	require.NotNil(t, block)
+	assert.Equal(t, "CERTIFICATE", block.Type)
+	assert.Equal(t, chain[0].Raw, block.Bytes)
+
+	// importing replaces the chain
+	require.Nil(t, provider.ImportCertificateChain(identifier, chain[:1]))
+	stored, err = provider.GetCertificateChain(identifier)
+	assert.Nil(t, err)
+	assert.Len(t, stored, 1)
+}
+
+func TestIntegration_CMS(t *testing.T) {
+	identifier := generate(t, "integration-cms", types.Ecdsap256)
+	chain := issueChain(t, identifier)
+	require.Nil(t, provider.ImportCertificateChain(identifier, chain))
+	roots := x509.NewCertPool()
+	roots.AddCert(chain[1])
+
+	signature, err := provider.SignCMS(identifier, []byte("document"), hsm.CMSOptions{})
+	require.Nil(t, err)
+	content, err := provider.VerifyCMS(types.CryptoIdentifier{}, signature, hsm.CMSVerifyOptions{Roots: roots})
+	assert.Nil(t, err)
+	assert.Equal(t, []byte("document"), content)
+
+	signature, err = provider.SignCMS(identifier, []byte("document"), hsm.CMSOptions{Detached: true})
+	require.Nil(t, err)
+	_, err = provider.VerifyCMS(types.CryptoIdentifier{}, signature, hsm.CMSVerifyOptions{Content: []byte("tampered"), Roots: roots})
+	assert.NotNil(t, err)
+}
+
+func TestIntegration_DeleteKey_WithCertificateChain(t *testing.T) {
+	identifier := generate(t, "integration-delete-chain", types.Ecdsap256)
+	require.Nil(t, provider.ImportCertificateChain(identifier, issueChain(t, identifier)))
+
+	assert.Nil(t, provider.DeleteKey(identifier))
+	chain, err := provider.GetCertificateChain(identifier)
+	assert.Nil(t, err)
+	assert.Empty(t, chain)
+	assert.ErrorIs(t, provider.DeleteKey(identifier), hsm.ErrKeyNotFound)
+}
+
+func TestIntegration_Conformance(t *testing.T) {
+	conformance.Run(t, provider, conformance.Options{})
+}
@@ -0,0 +1,191 @@
+package remote
+
+import (
+	"context"
+	"fmt"
+	"math"
+
+	"github.com/eclipse-xfsc/crypto-provider-core/types"
+	"google.golang.org/grpc"
+)
+
+// Client implements types.CryptoProvider with a remote CryptoProvider gRPC service. Calls use the
+// context.Context of the crypto context and propagate its trace context, the logger of the crypto
+// context is not transported.
+type Client struct {
+	conn   *grpc.ClientConn
+	client CryptoProviderClient
+}
+
+// Dial connects to the server at the target, e.g. "unix:///run/hsm/provider.sock" or "hsm:8443".
+// Pass grpc.WithTransportCredentials with ClientTLSConfig for TCP targets.
+func Dial(target string, options ...grpc.DialOption) (*Client, error) {
+	conn, err := grpc.NewClient(target, append(options, grpc.WithChainUnaryInterceptor(injectTraceContext))...)
+	if err != nil {
+		return nil, err
+	}
+	return &Client{conn: conn, client: NewCryptoProviderClient(conn)}, nil
+}
+
+// Close closes the connection to the server.
+func (c *Client) Close() error {
+	return c.conn.Close()
+}
+
+func callContext(c types.CryptoContext) context.Context {
+	if c.Context == nil {
+		return context.Background()
+	}
+	return c.Context
+}
+
+func (c *Client) CreateCryptoContext(context types.CryptoContext) error {
+	_, err := c.client.CreateCryptoContext(callContext(context), toContext(context))
+	return fromStatus(err)
+}
+
+func (c *Client) DestroyCryptoContext(context types.CryptoContext) error {
+	_, err := c.client.DestroyCryptoContext(callContext(context), toContext(context))
+	return fromStatus(err)
+}
+
+func (c *Client) IsCryptoContextExisting(context types.CryptoContext) (bool, error) {
+	response, err := c.client.IsCryptoContextExisting(callContext(context), toContext(context))
+	if err != nil {
+		return false, fromStatus(err)
+	}
+	return response.GetValue(), nil
+}
+
+func (c *Client) GetNamespaces(context types.CryptoContext) ([]string, error) {
+	response, err := c.client.GetNamespaces(callContext(context), toContext(context))
+	if err != nil {
+		return nil, fromStatus(err)
+	}
+	return response.GetNamespaces(), nil
+}
+
+func (c *Client) GenerateRandom(context types.CryptoContext, number int) ([]byte, error) {
+	if number < 0 || number > math.MaxInt32 {
+		return nil, fmt.Errorf("invalid number of random bytes %d", number)
+	}
+	response, err := c.client.GenerateRandom(callContext(context), &GenerateRandomRequest{Context: toContext(context), Number: int32(number)})
+	if err != nil {
+		return nil, fromStatus(err)
+	}
+	return response.GetValue(), nil
+}
+
+func (c *Client) Hash(parameter types.CryptoHashParameter, msg []byte) ([]byte, error) {
+	request := &HashRequest{Identifier: toIdentifier(parameter.Identifier), HashAlgorithm: string(parameter.HashAlgorithm), Msg: msg}
+	response, err := c.client.Hash(callContext(parameter.Identifier.CryptoContext), request)
+	if err != nil {
+		return nil, fromStatus(err)
+	}
+	return response.GetValue(), nil
+}
+
+func (c *Client) Encrypt(parameter types.CryptoIdentifier, data []byte) ([]byte, error) {
+	response, err := c.client.Encrypt(callContext(parameter.CryptoContext), &DataRequest{Identifier: toIdentifier(parameter), Data: data})
+	if err != nil {
+		return nil, fromStatus(err)
+	}
+	return response.GetValue(), nil
+}
+
+func (c *Client) Decrypt(parameter types.CryptoIdentifier, data []byte) ([]byte, error) {
+	response, err := c.client.Decrypt(callContext(parameter.CryptoContext), &DataRequest{Identifier: toIdentifier(parameter), Data: data})
+	if err != nil {
+		return nil, fromStatus(err)
+	}
+	return response.GetValue(), nil
+}
+
+func (c *Client) Sign(parameter types.CryptoIdentifier, data []byte) ([]byte, error) {
+	response, err := c.client.Sign(callContext(parameter.CryptoContext), &DataRequest{Identifier: toIdentifier(parameter), Data: data})
+	if err != nil {
+		return nil, fromStatus(err)
+	}
+	return response.GetValue(), nil
+}
+
+func (c *Client) GetKeys(parameter types.CryptoFilter) (*types.CryptoKeySet, error) {
+	ctx := callContext(parameter.CryptoContext)
+	request := &Filter{Id: parameter.Id, Filter: parameter.Filter.String(), Context: toContext(parameter.CryptoContext)}
+	response, err := c.client.GetKeys(ctx, request)
+	if err != nil {
+		return nil, fromStatus(err)
+	}
+	set := &types.CryptoKeySet{}
+	for _, key := range response.GetKeys() {
+		set.Keys = append(set.Keys, *fromKey(ctx, key))
+	}
+	return set, nil
+}
+
+func (c *Client) GetKey(parameter types.CryptoIdentifier) (*types.CryptoKey, error) {
+	ctx := callContext(parameter.CryptoContext)
+	response, err := c.client.GetKey(ctx, toIdentifier(parameter))
+	if err != nil {
+		return nil, fromStatus(err)
+	}
+	return fromKey(ctx, response), nil
+}
+
+func (c *Client) Verify(parameter types.CryptoIdentifier, data []byte, signature []byte) (bool, error) {
+	request := &VerifyRequest{Identifier: toIdentifier(parameter), Data: data, Signature: signature}
+	response, err := c.client.Verify(callContext(parameter.CryptoContext), request)
+	if err != nil {
+		return false, fromStatus(err)
+	}
+	return response.GetValue(), nil
+}
+
+func (c *Client) GenerateKey(parameter types.CryptoKeyParameter) error {
+	_, err := c.client.GenerateKey(callContext(parameter.Identifier.CryptoContext), toKeyParameter(parameter))
+	return fromStatus(err)
+}
+
+func (c *Client) IsKeyExisting(parameter types.CryptoIdentifier) (bool, error) {
+	response, err := c.client.IsKeyExisting(callContext(parameter.CryptoContext), toIdentifier(parameter))
+	if err != nil {
+		return false, fromStatus(err)
+	}
+	return response.GetValue(), nil
+}
+
+func (c *Client) DeleteKey(parameter types.CryptoIdentifier) error {
+	_, err := c.client.DeleteKey(callContext(parameter.CryptoContext), toIdentifier(parameter))
+	return fromStatus(err)
+}
+
+func (c *Client) RotateKey(parameter types.CryptoIdentifier) error {
+	_, err := c.client.RotateKey(callContext(parameter.CryptoContext), toIdentifier(parameter))
+	return fromStatus(err)
+}
+
+// GetSupportedKeysAlgs returns nil if the server is not reachable.
+func (c *Client) GetSupportedKeysAlgs() []types.KeyType {
+	response, err := c.client.GetSupportedKeysAlgs(context.Background(), &Empty{})
+	if err != nil {
+		return nil
+	}
+	var keyTypes []types.KeyType
+	for _, algorithm := range response.GetAlgorithms() {
+		keyTypes = append(keyTypes, types.KeyType(algorithm))
+	}
+	return keyTypes
+}
+
+// GetSupportedHashAlgs returns nil if the server is not reachable.
+func (c *Client) GetSupportedHashAlgs() []types.HashAlgorithm {
+	response, err := c.client.GetSupportedHashAlgs(context.Background(), &Empty{})
+	if err != nil {
+		return nil
+	}
+	var hashAlgorithms []types.HashAlgorithm
+	for _, algorithm := range response.GetAlgorithms() {
+		hashAlgorithms = append(hashAlgorithms, types.HashAlgorithm(algorithm))
+	}
+	return hashAlgorithms
+}
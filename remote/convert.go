@@ -0,0 +1,109 @@
+package remote
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"regexp"
+
+	"github.com/eclipse-xfsc/crypto-provider-core/types"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func toContext(c types.CryptoContext) *Context {
+	return &Context{Namespace: c.Namespace, Group: c.Group, Engine: c.Engine}
+}
+
+func fromContext(ctx context.Context, c *Context) types.CryptoContext {
+	return types.CryptoContext{Namespace: c.GetNamespace(), Group: c.GetGroup(), Engine: c.GetEngine(), Context: ctx}
+}
+
+func toIdentifier(i types.CryptoIdentifier) *Identifier {
+	return &Identifier{KeyId: i.KeyId, Context: toContext(i.CryptoContext)}
+}
+
+func fromIdentifier(ctx context.Context, i *Identifier) types.CryptoIdentifier {
+	return types.CryptoIdentifier{KeyId: i.GetKeyId(), CryptoContext: fromContext(ctx, i.GetContext())}
+}
+
+func toKeyParameter(p types.CryptoKeyParameter) *KeyParameter {
+	return &KeyParameter{Identifier: toIdentifier(p.Identifier), KeyType: string(p.KeyType), Params: p.Params}
+}
+
+func fromKeyParameter(ctx context.Context, p *KeyParameter) types.CryptoKeyParameter {
+	return types.CryptoKeyParameter{Identifier: fromIdentifier(ctx, p.GetIdentifier()), KeyType: types.KeyType(p.GetKeyType()), Params: p.GetParams()}
+}
+
+func toKey(k *types.CryptoKey) *Key {
+	return &Key{Key: k.Key, Version: k.Version, Parameter: toKeyParameter(k.CryptoKeyParameter)}
+}
+
+func fromKey(ctx context.Context, k *Key) *types.CryptoKey {
+	return &types.CryptoKey{Key: k.GetKey(), Version: k.GetVersion(), CryptoKeyParameter: fromKeyParameter(ctx, k.GetParameter())}
+}
+
+func fromFilter(ctx context.Context, f *Filter) (types.CryptoFilter, error) {
+	filter := types.CryptoFilter{Id: f.GetId(), CryptoContext: fromContext(ctx, f.GetContext())}
+	regex, err := regexp.Compile(f.GetFilter())
+	if err != nil {
+		return filter, status.Error(codes.InvalidArgument, err.Error())
+	}
+	filter.Filter = *regex
+	return filter, nil
+}
+
+// toStatus transports errors of the provider as gRPC status. Context errors, errors.ErrUnsupported and
+// fs.ErrNotExist, which missing keys match, keep their type.
+func toStatus(err error) error {
+	if err == nil {
+		return nil
+	}
+	if _, ok := status.FromError(err); ok {
+		return err
+	}
+	var contextError *types.CryptoContextError
+	switch {
+	case errors.As(err, &contextError):
+		return status.Error(codes.FailedPrecondition, err.Error())
+	case errors.Is(err, errors.ErrUnsupported):
+		return status.Error(codes.Unimplemented, err.Error())
+	case errors.Is(err, fs.ErrNotExist):
+		return status.Error(codes.NotFound, err.Error())
+	}
+	return status.Error(codes.Unknown, err.Error())
+}
+
+// statusError restores the message of a status and the error it was created for.
+type statusError struct {
+	message string
+	target  error
+}
+
+func (e statusError) Error() string {
+	return e.message
+}
+
+func (e statusError) Is(target error) bool {
+	return target == e.target
+}
+
+// fromStatus restores the errors of the provider from the gRPC status.
+func fromStatus(err error) error {
+	s, ok := status.FromError(err)
+	if !ok {
+		return err
+	}
+	switch s.Code() {
+	case codes.FailedPrecondition:
+		return &types.CryptoContextError{Err: errors.New(s.Message())}
+	case codes.Unimplemented:
+		return statusError{message: s.Message(), target: errors.ErrUnsupported}
+	case codes.NotFound:
+		return statusError{message: s.Message(), target: fs.ErrNotExist}
+	case codes.Unknown:
+		return errors.New(s.Message())
+	default:
+		return err
+	}
+}
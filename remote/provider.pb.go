@@ -0,0 +1,985 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.12
+// 	protoc        v5.29.3
+// source: provider.proto
+
+package remote
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type Empty struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Empty) Reset() {
+	*x = Empty{}
+	mi := &file_provider_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Empty) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Empty) ProtoMessage() {}
+
+func (x *Empty) ProtoReflect() protoreflect.Message {
+	mi := &file_provider_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Empty.ProtoReflect.Descriptor instead.
+func (*Empty) Descriptor() ([]byte, []int) {
+	return file_provider_proto_rawDescGZIP(), []int{0}
+}
+
+// Context is a types.CryptoContext without the process local logger and context.Context.
+type Context struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Namespace     string                 `protobuf:"bytes,1,opt,name=namespace,proto3" json:"namespace,omitempty"`
+	Group         string                 `protobuf:"bytes,2,opt,name=group,proto3" json:"group,omitempty"`
+	Engine        string                 `protobuf:"bytes,3,opt,name=engine,proto3" json:"engine,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Context) Reset() {
+	*x = Context{}
+	mi := &file_provider_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Context) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Context) ProtoMessage() {}
+
+func (x *Context) ProtoReflect() protoreflect.Message {
+	mi := &file_provider_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Context.ProtoReflect.Descriptor instead.
+func (*Context) Descriptor() ([]byte, []int) {
+	return file_provider_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *Context) GetNamespace() string {
+	if x != nil {
+		return x.Namespace
+	}
+	return ""
+}
+
+func (x *Context) GetGroup() string {
+	if x != nil {
+		return x.Group
+	}
+	return ""
+}
+
+func (x *Context) GetEngine() string {
+	if x != nil {
+		return x.Engine
+	}
+	return ""
+}
+
+type Identifier struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	KeyId         string                 `protobuf:"bytes,1,opt,name=key_id,json=keyId,proto3" json:"key_id,omitempty"`
+	Context       *Context               `protobuf:"bytes,2,opt,name=context,proto3" json:"context,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Identifier) Reset() {
+	*x = Identifier{}
+	mi := &file_provider_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Identifier) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Identifier) ProtoMessage() {}
+
+func (x *Identifier) ProtoReflect() protoreflect.Message {
+	mi := &file_provider_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Identifier.ProtoReflect.Descriptor instead.
+func (*Identifier) Descriptor() ([]byte, []int) {
+	return file_provider_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *Identifier) GetKeyId() string {
+	if x != nil {
+		return x.KeyId
+	}
+	return ""
+}
+
+func (x *Identifier) GetContext() *Context {
+	if x != nil {
+		return x.Context
+	}
+	return nil
+}
+
+type Filter struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Id    string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	// filter is the regular expression of types.CryptoFilter.
+	Filter        string   `protobuf:"bytes,2,opt,name=filter,proto3" json:"filter,omitempty"`
+	Context       *Context `protobuf:"bytes,3,opt,name=context,proto3" json:"context,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Filter) Reset() {
+	*x = Filter{}
+	mi := &file_provider_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Filter) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Filter) ProtoMessage() {}
+
+func (x *Filter) ProtoReflect() protoreflect.Message {
+	mi := &file_provider_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Filter.ProtoReflect.Descriptor instead.
+func (*Filter) Descriptor() ([]byte, []int) {
+	return file_provider_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *Filter) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *Filter) GetFilter() string {
+	if x != nil {
+		return x.Filter
+	}
+	return ""
+}
+
+func (x *Filter) GetContext() *Context {
+	if x != nil {
+		return x.Context
+	}
+	return nil
+}
+
+type KeyParameter struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Identifier    *Identifier            `protobuf:"bytes,1,opt,name=identifier,proto3" json:"identifier,omitempty"`
+	KeyType       string                 `protobuf:"bytes,2,opt,name=key_type,json=keyType,proto3" json:"key_type,omitempty"`
+	Params        []byte                 `protobuf:"bytes,3,opt,name=params,proto3" json:"params,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *KeyParameter) Reset() {
+	*x = KeyParameter{}
+	mi := &file_provider_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *KeyParameter) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*KeyParameter) ProtoMessage() {}
+
+func (x *KeyParameter) ProtoReflect() protoreflect.Message {
+	mi := &file_provider_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use KeyParameter.ProtoReflect.Descriptor instead.
+func (*KeyParameter) Descriptor() ([]byte, []int) {
+	return file_provider_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *KeyParameter) GetIdentifier() *Identifier {
+	if x != nil {
+		return x.Identifier
+	}
+	return nil
+}
+
+func (x *KeyParameter) GetKeyType() string {
+	if x != nil {
+		return x.KeyType
+	}
+	return ""
+}
+
+func (x *KeyParameter) GetParams() []byte {
+	if x != nil {
+		return x.Params
+	}
+	return nil
+}
+
+type Key struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Key           []byte                 `protobuf:"bytes,1,opt,name=key,proto3" json:"key,omitempty"`
+	Version       string                 `protobuf:"bytes,2,opt,name=version,proto3" json:"version,omitempty"`
+	Parameter     *KeyParameter          `protobuf:"bytes,3,opt,name=parameter,proto3" json:"parameter,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Key) Reset() {
+	*x = Key{}
+	mi := &file_provider_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Key) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Key) ProtoMessage() {}
+
+func (x *Key) ProtoReflect() protoreflect.Message {
+	mi := &file_provider_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Key.ProtoReflect.Descriptor instead.
+func (*Key) Descriptor() ([]byte, []int) {
+	return file_provider_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *Key) GetKey() []byte {
+	if x != nil {
+		return x.Key
+	}
+	return nil
+}
+
+func (x *Key) GetVersion() string {
+	if x != nil {
+		return x.Version
+	}
+	return ""
+}
+
+func (x *Key) GetParameter() *KeyParameter {
+	if x != nil {
+		return x.Parameter
+	}
+	return nil
+}
+
+type KeySet struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Keys          []*Key                 `protobuf:"bytes,1,rep,name=keys,proto3" json:"keys,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *KeySet) Reset() {
+	*x = KeySet{}
+	mi := &file_provider_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *KeySet) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*KeySet) ProtoMessage() {}
+
+func (x *KeySet) ProtoReflect() protoreflect.Message {
+	mi := &file_provider_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use KeySet.ProtoReflect.Descriptor instead.
+func (*KeySet) Descriptor() ([]byte, []int) {
+	return file_provider_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *KeySet) GetKeys() []*Key {
+	if x != nil {
+		return x.Keys
+	}
+	return nil
+}
+
+type GenerateRandomRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Context       *Context               `protobuf:"bytes,1,opt,name=context,proto3" json:"context,omitempty"`
+	Number        int32                  `protobuf:"varint,2,opt,name=number,proto3" json:"number,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GenerateRandomRequest) Reset() {
+	*x = GenerateRandomRequest{}
+	mi := &file_provider_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GenerateRandomRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GenerateRandomRequest) ProtoMessage() {}
+
+func (x *GenerateRandomRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_provider_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GenerateRandomRequest.ProtoReflect.Descriptor instead.
+func (*GenerateRandomRequest) Descriptor() ([]byte, []int) {
+	return file_provider_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *GenerateRandomRequest) GetContext() *Context {
+	if x != nil {
+		return x.Context
+	}
+	return nil
+}
+
+func (x *GenerateRandomRequest) GetNumber() int32 {
+	if x != nil {
+		return x.Number
+	}
+	return 0
+}
+
+type HashRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Identifier    *Identifier            `protobuf:"bytes,1,opt,name=identifier,proto3" json:"identifier,omitempty"`
+	HashAlgorithm string                 `protobuf:"bytes,2,opt,name=hash_algorithm,json=hashAlgorithm,proto3" json:"hash_algorithm,omitempty"`
+	Msg           []byte                 `protobuf:"bytes,3,opt,name=msg,proto3" json:"msg,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *HashRequest) Reset() {
+	*x = HashRequest{}
+	mi := &file_provider_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *HashRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*HashRequest) ProtoMessage() {}
+
+func (x *HashRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_provider_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use HashRequest.ProtoReflect.Descriptor instead.
+func (*HashRequest) Descriptor() ([]byte, []int) {
+	return file_provider_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *HashRequest) GetIdentifier() *Identifier {
+	if x != nil {
+		return x.Identifier
+	}
+	return nil
+}
+
+func (x *HashRequest) GetHashAlgorithm() string {
+	if x != nil {
+		return x.HashAlgorithm
+	}
+	return ""
+}
+
+func (x *HashRequest) GetMsg() []byte {
+	if x != nil {
+		return x.Msg
+	}
+	return nil
+}
+
+type DataRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Identifier    *Identifier            `protobuf:"bytes,1,opt,name=identifier,proto3" json:"identifier,omitempty"`
+	Data          []byte                 `protobuf:"bytes,2,opt,name=data,proto3" json:"data,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DataRequest) Reset() {
+	*x = DataRequest{}
+	mi := &file_provider_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DataRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DataRequest) ProtoMessage() {}
+
+func (x *DataRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_provider_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DataRequest.ProtoReflect.Descriptor instead.
+func (*DataRequest) Descriptor() ([]byte, []int) {
+	return file_provider_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *DataRequest) GetIdentifier() *Identifier {
+	if x != nil {
+		return x.Identifier
+	}
+	return nil
+}
+
+func (x *DataRequest) GetData() []byte {
+	if x != nil {
+		return x.Data
+	}
+	return nil
+}
+
+type VerifyRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Identifier    *Identifier            `protobuf:"bytes,1,opt,name=identifier,proto3" json:"identifier,omitempty"`
+	Data          []byte                 `protobuf:"bytes,2,opt,name=data,proto3" json:"data,omitempty"`
+	Signature     []byte                 `protobuf:"bytes,3,opt,name=signature,proto3" json:"signature,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *VerifyRequest) Reset() {
+	*x = VerifyRequest{}
+	mi := &file_provider_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *VerifyRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*VerifyRequest) ProtoMessage() {}
+
+func (x *VerifyRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_provider_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use VerifyRequest.ProtoReflect.Descriptor instead.
+func (*VerifyRequest) Descriptor() ([]byte, []int) {
+	return file_provider_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *VerifyRequest) GetIdentifier() *Identifier {
+	if x != nil {
+		return x.Identifier
+	}
+	return nil
+}
+
+func (x *VerifyRequest) GetData() []byte {
+	if x != nil {
+		return x.Data
+	}
+	return nil
+}
+
+func (x *VerifyRequest) GetSignature() []byte {
+	if x != nil {
+		return x.Signature
+	}
+	return nil
+}
+
+type BoolResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Value         bool                   `protobuf:"varint,1,opt,name=value,proto3" json:"value,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *BoolResponse) Reset() {
+	*x = BoolResponse{}
+	mi := &file_provider_proto_msgTypes[11]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *BoolResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BoolResponse) ProtoMessage() {}
+
+func (x *BoolResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_provider_proto_msgTypes[11]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BoolResponse.ProtoReflect.Descriptor instead.
+func (*BoolResponse) Descriptor() ([]byte, []int) {
+	return file_provider_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *BoolResponse) GetValue() bool {
+	if x != nil {
+		return x.Value
+	}
+	return false
+}
+
+type BytesResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Value         []byte                 `protobuf:"bytes,1,opt,name=value,proto3" json:"value,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *BytesResponse) Reset() {
+	*x = BytesResponse{}
+	mi := &file_provider_proto_msgTypes[12]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *BytesResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BytesResponse) ProtoMessage() {}
+
+func (x *BytesResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_provider_proto_msgTypes[12]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BytesResponse.ProtoReflect.Descriptor instead.
+func (*BytesResponse) Descriptor() ([]byte, []int) {
+	return file_provider_proto_rawDescGZIP(), []int{12}
+}
+
+func (x *BytesResponse) GetValue() []byte {
+	if x != nil {
+		return x.Value
+	}
+	return nil
+}
+
+type NamespacesResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Namespaces    []string               `protobuf:"bytes,1,rep,name=namespaces,proto3" json:"namespaces,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *NamespacesResponse) Reset() {
+	*x = NamespacesResponse{}
+	mi := &file_provider_proto_msgTypes[13]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *NamespacesResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*NamespacesResponse) ProtoMessage() {}
+
+func (x *NamespacesResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_provider_proto_msgTypes[13]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use NamespacesResponse.ProtoReflect.Descriptor instead.
+func (*NamespacesResponse) Descriptor() ([]byte, []int) {
+	return file_provider_proto_rawDescGZIP(), []int{13}
+}
+
+func (x *NamespacesResponse) GetNamespaces() []string {
+	if x != nil {
+		return x.Namespaces
+	}
+	return nil
+}
+
+type AlgorithmsResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Algorithms    []string               `protobuf:"bytes,1,rep,name=algorithms,proto3" json:"algorithms,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *AlgorithmsResponse) Reset() {
+	*x = AlgorithmsResponse{}
+	mi := &file_provider_proto_msgTypes[14]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *AlgorithmsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AlgorithmsResponse) ProtoMessage() {}
+
+func (x *AlgorithmsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_provider_proto_msgTypes[14]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AlgorithmsResponse.ProtoReflect.Descriptor instead.
+func (*AlgorithmsResponse) Descriptor() ([]byte, []int) {
+	return file_provider_proto_rawDescGZIP(), []int{14}
+}
+
+func (x *AlgorithmsResponse) GetAlgorithms() []string {
+	if x != nil {
+		return x.Algorithms
+	}
+	return nil
+}
+
+var File_provider_proto protoreflect.FileDescriptor
+
+const file_provider_proto_rawDesc = "" +
+	"\n" +
+	"\x0eprovider.proto\x12\x11cryptoprovider.v1\"\a\n" +
+	"\x05Empty\"U\n" +
+	"\aContext\x12\x1c\n" +
+	"\tnamespace\x18\x01 \x01(\tR\tnamespace\x12\x14\n" +
+	"\x05group\x18\x02 \x01(\tR\x05group\x12\x16\n" +
+	"\x06engine\x18\x03 \x01(\tR\x06engine\"Y\n" +
+	"\n" +
+	"Identifier\x12\x15\n" +
+	"\x06key_id\x18\x01 \x01(\tR\x05keyId\x124\n" +
+	"\acontext\x18\x02 \x01(\v2\x1a.cryptoprovider.v1.ContextR\acontext\"f\n" +
+	"\x06Filter\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12\x16\n" +
+	"\x06filter\x18\x02 \x01(\tR\x06filter\x124\n" +
+	"\acontext\x18\x03 \x01(\v2\x1a.cryptoprovider.v1.ContextR\acontext\"\x80\x01\n" +
+	"\fKeyParameter\x12=\n" +
+	"\n" +
+	"identifier\x18\x01 \x01(\v2\x1d.cryptoprovider.v1.IdentifierR\n" +
+	"identifier\x12\x19\n" +
+	"\bkey_type\x18\x02 \x01(\tR\akeyType\x12\x16\n" +
+	"\x06params\x18\x03 \x01(\fR\x06params\"p\n" +
+	"\x03Key\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\fR\x03key\x12\x18\n" +
+	"\aversion\x18\x02 \x01(\tR\aversion\x12=\n" +
+	"\tparameter\x18\x03 \x01(\v2\x1f.cryptoprovider.v1.KeyParameterR\tparameter\"4\n" +
+	"\x06KeySet\x12*\n" +
+	"\x04keys\x18\x01 \x03(\v2\x16.cryptoprovider.v1.KeyR\x04keys\"e\n" +
+	"\x15GenerateRandomRequest\x124\n" +
+	"\acontext\x18\x01 \x01(\v2\x1a.cryptoprovider.v1.ContextR\acontext\x12\x16\n" +
+	"\x06number\x18\x02 \x01(\x05R\x06number\"\x85\x01\n" +
+	"\vHashRequest\x12=\n" +
+	"\n" +
+	"identifier\x18\x01 \x01(\v2\x1d.cryptoprovider.v1.IdentifierR\n" +
+	"identifier\x12%\n" +
+	"\x0ehash_algorithm\x18\x02 \x01(\tR\rhashAlgorithm\x12\x10\n" +
+	"\x03msg\x18\x03 \x01(\fR\x03msg\"`\n" +
+	"\vDataRequest\x12=\n" +
+	"\n" +
+	"identifier\x18\x01 \x01(\v2\x1d.cryptoprovider.v1.IdentifierR\n" +
+	"identifier\x12\x12\n" +
+	"\x04data\x18\x02 \x01(\fR\x04data\"\x80\x01\n" +
+	"\rVerifyRequest\x12=\n" +
+	"\n" +
+	"identifier\x18\x01 \x01(\v2\x1d.cryptoprovider.v1.IdentifierR\n" +
+	"identifier\x12\x12\n" +
+	"\x04data\x18\x02 \x01(\fR\x04data\x12\x1c\n" +
+	"\tsignature\x18\x03 \x01(\fR\tsignature\"$\n" +
+	"\fBoolResponse\x12\x14\n" +
+	"\x05value\x18\x01 \x01(\bR\x05value\"%\n" +
+	"\rBytesResponse\x12\x14\n" +
+	"\x05value\x18\x01 \x01(\fR\x05value\"4\n" +
+	"\x12NamespacesResponse\x12\x1e\n" +
+	"\n" +
+	"namespaces\x18\x01 \x03(\tR\n" +
+	"namespaces\"4\n" +
+	"\x12AlgorithmsResponse\x12\x1e\n" +
+	"\n" +
+	"algorithms\x18\x01 \x03(\tR\n" +
+	"algorithms2\x8b\v\n" +
+	"\x0eCryptoProvider\x12K\n" +
+	"\x13CreateCryptoContext\x12\x1a.cryptoprovider.v1.Context\x1a\x18.cryptoprovider.v1.Empty\x12L\n" +
+	"\x14DestroyCryptoContext\x12\x1a.cryptoprovider.v1.Context\x1a\x18.cryptoprovider.v1.Empty\x12V\n" +
+	"\x17IsCryptoContextExisting\x12\x1a.cryptoprovider.v1.Context\x1a\x1f.cryptoprovider.v1.BoolResponse\x12R\n" +
+	"\rGetNamespaces\x12\x1a.cryptoprovider.v1.Context\x1a%.cryptoprovider.v1.NamespacesResponse\x12\\\n" +
+	"\x0eGenerateRandom\x12(.cryptoprovider.v1.GenerateRandomRequest\x1a .cryptoprovider.v1.BytesResponse\x12H\n" +
+	"\x04Hash\x12\x1e.cryptoprovider.v1.HashRequest\x1a .cryptoprovider.v1.BytesResponse\x12K\n" +
+	"\aEncrypt\x12\x1e.cryptoprovider.v1.DataRequest\x1a .cryptoprovider.v1.BytesResponse\x12K\n" +
+	"\aDecrypt\x12\x1e.cryptoprovider.v1.DataRequest\x1a .cryptoprovider.v1.BytesResponse\x12H\n" +
+	"\x04Sign\x12\x1e.cryptoprovider.v1.DataRequest\x1a .cryptoprovider.v1.BytesResponse\x12?\n" +
+	"\aGetKeys\x12\x19.cryptoprovider.v1.Filter\x1a\x19.cryptoprovider.v1.KeySet\x12?\n" +
+	"\x06GetKey\x12\x1d.cryptoprovider.v1.Identifier\x1a\x16.cryptoprovider.v1.Key\x12K\n" +
+	"\x06Verify\x12 .cryptoprovider.v1.VerifyRequest\x1a\x1f.cryptoprovider.v1.BoolResponse\x12H\n" +
+	"\vGenerateKey\x12\x1f.cryptoprovider.v1.KeyParameter\x1a\x18.cryptoprovider.v1.Empty\x12O\n" +
+	"\rIsKeyExisting\x12\x1d.cryptoprovider.v1.Identifier\x1a\x1f.cryptoprovider.v1.BoolResponse\x12D\n" +
+	"\tDeleteKey\x12\x1d.cryptoprovider.v1.Identifier\x1a\x18.cryptoprovider.v1.Empty\x12D\n" +
+	"\tRotateKey\x12\x1d.cryptoprovider.v1.Identifier\x1a\x18.cryptoprovider.v1.Empty\x12W\n" +
+	"\x14GetSupportedKeysAlgs\x12\x18.cryptoprovider.v1.Empty\x1a%.cryptoprovider.v1.AlgorithmsResponse\x12W\n" +
+	"\x14GetSupportedHashAlgs\x12\x18.cryptoprovider.v1.Empty\x1a%.cryptoprovider.v1.AlgorithmsResponseBFZDgithub.com/eclipse-xfsc/crypto-provider-luna-cloud-hsm-plugin/remoteb\x06proto3"
+
+var (
+	file_provider_proto_rawDescOnce sync.Once
+	file_provider_proto_rawDescData []byte
+)
+
+func file_provider_proto_rawDescGZIP() []byte {
+	file_provider_proto_rawDescOnce.Do(func() {
+		file_provider_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_provider_proto_rawDesc), len(file_provider_proto_rawDesc)))
+	})
+	return file_provider_proto_rawDescData
+}
+
+var file_provider_proto_msgTypes = make([]protoimpl.MessageInfo, 15)
+var file_provider_proto_goTypes = []any{
+	(*Empty)(nil),                 // 0: cryptoprovider.v1.Empty
+	(*Context)(nil),               // 1: cryptoprovider.v1.Context
+	(*Identifier)(nil),            // 2: cryptoprovider.v1.Identifier
+	(*Filter)(nil),                // 3: cryptoprovider.v1.Filter
+	(*KeyParameter)(nil),          // 4: cryptoprovider.v1.KeyParameter
+	(*Key)(nil),                   // 5: cryptoprovider.v1.Key
+	(*KeySet)(nil),                // 6: cryptoprovider.v1.KeySet
+	(*GenerateRandomRequest)(nil), // 7: cryptoprovider.v1.GenerateRandomRequest
+	(*HashRequest)(nil),           // 8: cryptoprovider.v1.HashRequest
+	(*DataRequest)(nil),           // 9: cryptoprovider.v1.DataRequest
+	(*VerifyRequest)(nil),         // 10: cryptoprovider.v1.VerifyRequest
+	(*BoolResponse)(nil),          // 11: cryptoprovider.v1.BoolResponse
+	(*BytesResponse)(nil),         // 12: cryptoprovider.v1.BytesResponse
+	(*NamespacesResponse)(nil),    // 13: cryptoprovider.v1.NamespacesResponse
+	(*AlgorithmsResponse)(nil),    // 14: cryptoprovider.v1.AlgorithmsResponse
+}
+var file_provider_proto_depIdxs = []int32{
+	1,  // 0: cryptoprovider.v1.Identifier.context:type_name -> cryptoprovider.v1.Context
+	1,  // 1: cryptoprovider.v1.Filter.context:type_name -> cryptoprovider.v1.Context
+	2,  // 2: cryptoprovider.v1.KeyParameter.identifier:type_name -> cryptoprovider.v1.Identifier
+	4,  // 3: cryptoprovider.v1.Key.parameter:type_name -> cryptoprovider.v1.KeyParameter
+	5,  // 4: cryptoprovider.v1.KeySet.keys:type_name -> cryptoprovider.v1.Key
+	1,  // 5: cryptoprovider.v1.GenerateRandomRequest.context:type_name -> cryptoprovider.v1.Context
+	2,  // 6: cryptoprovider.v1.HashRequest.identifier:type_name -> cryptoprovider.v1.Identifier
+	2,  // 7: cryptoprovider.v1.DataRequest.identifier:type_name -> cryptoprovider.v1.Identifier
+	2,  // 8: cryptoprovider.v1.VerifyRequest.identifier:type_name -> cryptoprovider.v1.Identifier
+	1,  // 9: cryptoprovider.v1.CryptoProvider.CreateCryptoContext:input_type -> cryptoprovider.v1.Context
+	1,  // 10: cryptoprovider.v1.CryptoProvider.DestroyCryptoContext:input_type -> cryptoprovider.v1.Context
+	1,  // 11: cryptoprovider.v1.CryptoProvider.IsCryptoContextExisting:input_type -> cryptoprovider.v1.Context
+	1,  // 12: cryptoprovider.v1.CryptoProvider.GetNamespaces:input_type -> cryptoprovider.v1.Context
+	7,  // 13: cryptoprovider.v1.CryptoProvider.GenerateRandom:input_type -> cryptoprovider.v1.GenerateRandomRequest
+	8,  // 14: cryptoprovider.v1.CryptoProvider.Hash:input_type -> cryptoprovider.v1.HashRequest
+	9,  // 15: cryptoprovider.v1.CryptoProvider.Encrypt:input_type -> cryptoprovider.v1.DataRequest
+	9,  // 16: cryptoprovider.v1.CryptoProvider.Decrypt:input_type -> cryptoprovider.v1.DataRequest
+	9,  // 17: cryptoprovider.v1.CryptoProvider.Sign:input_type -> cryptoprovider.v1.DataRequest
+	3,  // 18: cryptoprovider.v1.CryptoProvider.GetKeys:input_type -> cryptoprovider.v1.Filter
+	2,  // 19: cryptoprovider.v1.CryptoProvider.GetKey:input_type -> cryptoprovider.v1.Identifier
+	10, // 20: cryptoprovider.v1.CryptoProvider.Verify:input_type -> cryptoprovider.v1.VerifyRequest
+	4,  // 21: cryptoprovider.v1.CryptoProvider.GenerateKey:input_type -> cryptoprovider.v1.KeyParameter
+	2,  // 22: cryptoprovider.v1.CryptoProvider.IsKeyExisting:input_type -> cryptoprovider.v1.Identifier
+	2,  // 23: cryptoprovider.v1.CryptoProvider.DeleteKey:input_type -> cryptoprovider.v1.Identifier
+	2,  // 24: cryptoprovider.v1.CryptoProvider.RotateKey:input_type -> cryptoprovider.v1.Identifier
+	0,  // 25: cryptoprovider.v1.CryptoProvider.GetSupportedKeysAlgs:input_type -> cryptoprovider.v1.Empty
+	0,  // 26: cryptoprovider.v1.CryptoProvider.GetSupportedHashAlgs:input_type -> cryptoprovider.v1.Empty
+	0,  // 27: cryptoprovider.v1.CryptoProvider.CreateCryptoContext:output_type -> cryptoprovider.v1.Empty
+	0,  // 28: cryptoprovider.v1.CryptoProvider.DestroyCryptoContext:output_type -> cryptoprovider.v1.Empty
+	11, // 29: cryptoprovider.v1.CryptoProvider.IsCryptoContextExisting:output_type -> cryptoprovider.v1.BoolResponse
+	13, // 30: cryptoprovider.v1.CryptoProvider.GetNamespaces:output_type -> cryptoprovider.v1.NamespacesResponse
+	12, // 31: cryptoprovider.v1.CryptoProvider.GenerateRandom:output_type -> cryptoprovider.v1.BytesResponse
+	12, // 32: cryptoprovider.v1.CryptoProvider.Hash:output_type -> cryptoprovider.v1.BytesResponse
+	12, // 33: cryptoprovider.v1.CryptoProvider.Encrypt:output_type -> cryptoprovider.v1.BytesResponse
+	12, // 34: cryptoprovider.v1.CryptoProvider.Decrypt:output_type -> cryptoprovider.v1.BytesResponse
+	12, // 35: cryptoprovider.v1.CryptoProvider.Sign:output_type -> cryptoprovider.v1.BytesResponse
+	6,  // 36: cryptoprovider.v1.CryptoProvider.GetKeys:output_type -> cryptoprovider.v1.KeySet
+	5,  // 37: cryptoprovider.v1.CryptoProvider.GetKey:output_type -> cryptoprovider.v1.Key
+	11, // 38: cryptoprovider.v1.CryptoProvider.Verify:output_type -> cryptoprovider.v1.BoolResponse
+	0,  // 39: cryptoprovider.v1.CryptoProvider.GenerateKey:output_type -> cryptoprovider.v1.Empty
+	11, // 40: cryptoprovider.v1.CryptoProvider.IsKeyExisting:output_type -> cryptoprovider.v1.BoolResponse
+	0,  // 41: cryptoprovider.v1.CryptoProvider.DeleteKey:output_type -> cryptoprovider.v1.Empty
+	0,  // 42: cryptoprovider.v1.CryptoProvider.RotateKey:output_type -> cryptoprovider.v1.Empty
+	14, // 43: cryptoprovider.v1.CryptoProvider.GetSupportedKeysAlgs:output_type -> cryptoprovider.v1.AlgorithmsResponse
+	14, // 44: cryptoprovider.v1.CryptoProvider.GetSupportedHashAlgs:output_type -> cryptoprovider.v1.AlgorithmsResponse
+	27, // [27:45] is the sub-list for method output_type
+	9,  // [9:27] is the sub-list for method input_type
+	9,  // [9:9] is the sub-list for extension type_name
+	9,  // [9:9] is the sub-list for extension extendee
+	0,  // [0:9] is the sub-list for field type_name
+}
+
+func init() { file_provider_proto_init() }
+func file_provider_proto_init() {
+	if File_provider_proto != nil {
+		return
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_provider_proto_rawDesc), len(file_provider_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   15,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_provider_proto_goTypes,
+		DependencyIndexes: file_provider_proto_depIdxs,
+		MessageInfos:      file_provider_proto_msgTypes,
+	}.Build()
+	File_provider_proto = out.File
+	file_provider_proto_goTypes = nil
+	file_provider_proto_depIdxs = nil
+}
@@ -0,0 +1,771 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.5.1
+// - protoc             v5.29.3
+// source: provider.proto
+
+package remote
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	CryptoProvider_CreateCryptoContext_FullMethodName     = "/cryptoprovider.v1.CryptoProvider/CreateCryptoContext"
+	CryptoProvider_DestroyCryptoContext_FullMethodName    = "/cryptoprovider.v1.CryptoProvider/DestroyCryptoContext"
+	CryptoProvider_IsCryptoContextExisting_FullMethodName = "/cryptoprovider.v1.CryptoProvider/IsCryptoContextExisting"
+	CryptoProvider_GetNamespaces_FullMethodName           = "/cryptoprovider.v1.CryptoProvider/GetNamespaces"
+	CryptoProvider_GenerateRandom_FullMethodName          = "/cryptoprovider.v1.CryptoProvider/GenerateRandom"
+	CryptoProvider_Hash_FullMethodName                    = "/cryptoprovider.v1.CryptoProvider/Hash"
+	CryptoProvider_Encrypt_FullMethodName                 = "/cryptoprovider.v1.CryptoProvider/Encrypt"
+	CryptoProvider_Decrypt_FullMethodName                 = "/cryptoprovider.v1.CryptoProvider/Decrypt"
+	CryptoProvider_Sign_FullMethodName                    = "/cryptoprovider.v1.CryptoProvider/Sign"
+	CryptoProvider_GetKeys_FullMethodName                 = "/cryptoprovider.v1.CryptoProvider/GetKeys"
+	CryptoProvider_GetKey_FullMethodName                  = "/cryptoprovider.v1.CryptoProvider/GetKey"
+	CryptoProvider_Verify_FullMethodName                  = "/cryptoprovider.v1.CryptoProvider/Verify"
+	CryptoProvider_GenerateKey_FullMethodName             = "/cryptoprovider.v1.CryptoProvider/GenerateKey"
+	CryptoProvider_IsKeyExisting_FullMethodName           = "/cryptoprovider.v1.CryptoProvider/IsKeyExisting"
+	CryptoProvider_DeleteKey_FullMethodName               = "/cryptoprovider.v1.CryptoProvider/DeleteKey"
+	CryptoProvider_RotateKey_FullMethodName               = "/cryptoprovider.v1.CryptoProvider/RotateKey"
+	CryptoProvider_GetSupportedKeysAlgs_FullMethodName    = "/cryptoprovider.v1.CryptoProvider/GetSupportedKeysAlgs"
+	CryptoProvider_GetSupportedHashAlgs_FullMethodName    = "/cryptoprovider.v1.CryptoProvider/GetSupportedHashAlgs"
+)
+
+// CryptoProviderClient is the client API for CryptoProvider service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// CryptoProvider is the types.CryptoProvider interface of crypto-provider-core.
+type CryptoProviderClient interface {
+	CreateCryptoContext(ctx context.Context, in *Context, opts ...grpc.CallOption) (*Empty, error)
+	DestroyCryptoContext(ctx context.Context, in *Context, opts ...grpc.CallOption) (*Empty, error)
+	IsCryptoContextExisting(ctx context.Context, in *Context, opts ...grpc.CallOption) (*BoolResponse, error)
+	GetNamespaces(ctx context.Context, in *Context, opts ...grpc.CallOption) (*NamespacesResponse, error)
+	GenerateRandom(ctx context.Context, in *GenerateRandomRequest, opts ...grpc.CallOption) (*BytesResponse, error)
+	Hash(ctx context.Context, in *HashRequest, opts ...grpc.CallOption) (*BytesResponse, error)
+	Encrypt(ctx context.Context, in *DataRequest, opts ...grpc.CallOption) (*BytesResponse, error)
+	Decrypt(ctx context.Context, in *DataRequest, opts ...grpc.CallOption) (*BytesResponse, error)
+	Sign(ctx context.Context, in *DataRequest, opts ...grpc.CallOption) (*BytesResponse, error)
+	GetKeys(ctx context.Context, in *Filter, opts ...grpc.CallOption) (*KeySet, error)
+	GetKey(ctx context.Context, in *Identifier, opts ...grpc.CallOption) (*Key, error)
+	Verify(ctx context.Context, in *VerifyRequest, opts ...grpc.CallOption) (*BoolResponse, error)
+	GenerateKey(ctx context.Context, in *KeyParameter, opts ...grpc.CallOption) (*Empty, error)
+	IsKeyExisting(ctx context.Context, in *Identifier, opts ...grpc.CallOption) (*BoolResponse, error)
+	DeleteKey(ctx context.Context, in *Identifier, opts ...grpc.CallOption) (*Empty, error)
+	RotateKey(ctx context.Context, in *Identifier, opts ...grpc.CallOption) (*Empty, error)
+	GetSupportedKeysAlgs(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*AlgorithmsResponse, error)
+	GetSupportedHashAlgs(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*AlgorithmsResponse, error)
+}
+
+type cryptoProviderClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewCryptoProviderClient(cc grpc.ClientConnInterface) CryptoProviderClient {
+	return &cryptoProviderClient{cc}
+}
+
+func (c *cryptoProviderClient) CreateCryptoContext(ctx context.Context, in *Context, opts ...grpc.CallOption) (*Empty, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Empty)
+	err := c.cc.Invoke(ctx, CryptoProvider_CreateCryptoContext_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *cryptoProviderClient) DestroyCryptoContext(ctx context.Context, in *Context, opts ...grpc.CallOption) (*Empty, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Empty)
+	err := c.cc.Invoke(ctx, CryptoProvider_DestroyCryptoContext_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *cryptoProviderClient) IsCryptoContextExisting(ctx context.Context, in *Context, opts ...grpc.CallOption) (*BoolResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(BoolResponse)
+	err := c.cc.Invoke(ctx, CryptoProvider_IsCryptoContextExisting_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *cryptoProviderClient) GetNamespaces(ctx context.Context, in *Context, opts ...grpc.CallOption) (*NamespacesResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(NamespacesResponse)
+	err := c.cc.Invoke(ctx, CryptoProvider_GetNamespaces_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *cryptoProviderClient) GenerateRandom(ctx context.Context, in *GenerateRandomRequest, opts ...grpc.CallOption) (*BytesResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(BytesResponse)
+	err := c.cc.Invoke(ctx, CryptoProvider_GenerateRandom_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *cryptoProviderClient) Hash(ctx context.Context, in *HashRequest, opts ...grpc.CallOption) (*BytesResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(BytesResponse)
+	err := c.cc.Invoke(ctx, CryptoProvider_Hash_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *cryptoProviderClient) Encrypt(ctx context.Context, in *DataRequest, opts ...grpc.CallOption) (*BytesResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(BytesResponse)
+	err := c.cc.Invoke(ctx, CryptoProvider_Encrypt_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *cryptoProviderClient) Decrypt(ctx context.Context, in *DataRequest, opts ...grpc.CallOption) (*BytesResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(BytesResponse)
+	err := c.cc.Invoke(ctx, CryptoProvider_Decrypt_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *cryptoProviderClient) Sign(ctx context.Context, in *DataRequest, opts ...grpc.CallOption) (*BytesResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(BytesResponse)
+	err := c.cc.Invoke(ctx, CryptoProvider_Sign_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *cryptoProviderClient) GetKeys(ctx context.Context, in *Filter, opts ...grpc.CallOption) (*KeySet, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(KeySet)
+	err := c.cc.Invoke(ctx, CryptoProvider_GetKeys_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *cryptoProviderClient) GetKey(ctx context.Context, in *Identifier, opts ...grpc.CallOption) (*Key, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Key)
+	err := c.cc.Invoke(ctx, CryptoProvider_GetKey_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *cryptoProviderClient) Verify(ctx context.Context, in *VerifyRequest, opts ...grpc.CallOption) (*BoolResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(BoolResponse)
+	err := c.cc.Invoke(ctx, CryptoProvider_Verify_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *cryptoProviderClient) GenerateKey(ctx context.Context, in *KeyParameter, opts ...grpc.CallOption) (*Empty, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Empty)
+	err := c.cc.Invoke(ctx, CryptoProvider_GenerateKey_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *cryptoProviderClient) IsKeyExisting(ctx context.Context, in *Identifier, opts ...grpc.CallOption) (*BoolResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(BoolResponse)
+	err := c.cc.Invoke(ctx, CryptoProvider_IsKeyExisting_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *cryptoProviderClient) DeleteKey(ctx context.Context, in *Identifier, opts ...grpc.CallOption) (*Empty, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Empty)
+	err := c.cc.Invoke(ctx, CryptoProvider_DeleteKey_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *cryptoProviderClient) RotateKey(ctx context.Context, in *Identifier, opts ...grpc.CallOption) (*Empty, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Empty)
+	err := c.cc.Invoke(ctx, CryptoProvider_RotateKey_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *cryptoProviderClient) GetSupportedKeysAlgs(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*AlgorithmsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(AlgorithmsResponse)
+	err := c.cc.Invoke(ctx, CryptoProvider_GetSupportedKeysAlgs_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *cryptoProviderClient) GetSupportedHashAlgs(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*AlgorithmsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(AlgorithmsResponse)
+	err := c.cc.Invoke(ctx, CryptoProvider_GetSupportedHashAlgs_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// CryptoProviderServer is the server API for CryptoProvider service.
+// All implementations must embed UnimplementedCryptoProviderServer
+// for forward compatibility.
+//
+// CryptoProvider is the types.CryptoProvider interface of crypto-provider-core.
+type CryptoProviderServer interface {
+	CreateCryptoContext(context.Context, *Context) (*Empty, error)
+	DestroyCryptoContext(context.Context, *Context) (*Empty, error)
+	IsCryptoContextExisting(context.Context, *Context) (*BoolResponse, error)
+	GetNamespaces(context.Context, *Context) (*NamespacesResponse, error)
+	GenerateRandom(context.Context, *GenerateRandomRequest) (*BytesResponse, error)
+	Hash(context.Context, *HashRequest) (*BytesResponse, error)
+	Encrypt(context.Context, *DataRequest) (*BytesResponse, error)
+	Decrypt(context.Context, *DataRequest) (*BytesResponse, error)
+	Sign(context.Context, *DataRequest) (*BytesResponse, error)
+	GetKeys(context.Context, *Filter) (*KeySet, error)
+	GetKey(context.Context, *Identifier) (*Key, error)
+	Verify(context.Context, *VerifyRequest) (*BoolResponse, error)
+	GenerateKey(context.Context, *KeyParameter) (*Empty, error)
+	IsKeyExisting(context.Context, *Identifier) (*BoolResponse, error)
+	DeleteKey(context.Context, *Identifier) (*Empty, error)
+	RotateKey(context.Context, *Identifier) (*Empty, error)
+	GetSupportedKeysAlgs(context.Context, *Empty) (*AlgorithmsResponse, error)
+	GetSupportedHashAlgs(context.Context, *Empty) (*AlgorithmsResponse, error)
+	mustEmbedUnimplementedCryptoProviderServer()
+}
+
+// UnimplementedCryptoProviderServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedCryptoProviderServer struct{}
+
+func (UnimplementedCryptoProviderServer) CreateCryptoContext(context.Context, *Context) (*Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CreateCryptoContext not implemented")
+}
+func (UnimplementedCryptoProviderServer) DestroyCryptoContext(context.Context, *Context) (*Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DestroyCryptoContext not implemented")
+}
+func (UnimplementedCryptoProviderServer) IsCryptoContextExisting(context.Context, *Context) (*BoolResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method IsCryptoContextExisting not implemented")
+}
+func (UnimplementedCryptoProviderServer) GetNamespaces(context.Context, *Context) (*NamespacesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetNamespaces not implemented")
+}
+func (UnimplementedCryptoProviderServer) GenerateRandom(context.Context, *GenerateRandomRequest) (*BytesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GenerateRandom not implemented")
+}
+func (UnimplementedCryptoProviderServer) Hash(context.Context, *HashRequest) (*BytesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Hash not implemented")
+}
+func (UnimplementedCryptoProviderServer) Encrypt(context.Context, *DataRequest) (*BytesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Encrypt not implemented")
+}
+func (UnimplementedCryptoProviderServer) Decrypt(context.Context, *DataRequest) (*BytesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Decrypt not implemented")
+}
+func (UnimplementedCryptoProviderServer) Sign(context.Context, *DataRequest) (*BytesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Sign not implemented")
+}
+func (UnimplementedCryptoProviderServer) GetKeys(context.Context, *Filter) (*KeySet, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetKeys not implemented")
+}
+func (UnimplementedCryptoProviderServer) GetKey(context.Context, *Identifier) (*Key, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetKey not implemented")
+}
+func (UnimplementedCryptoProviderServer) Verify(context.Context, *VerifyRequest) (*BoolResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Verify not implemented")
+}
+func (UnimplementedCryptoProviderServer) GenerateKey(context.Context, *KeyParameter) (*Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GenerateKey not implemented")
+}
+func (UnimplementedCryptoProviderServer) IsKeyExisting(context.Context, *Identifier) (*BoolResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method IsKeyExisting not implemented")
+}
+func (UnimplementedCryptoProviderServer) DeleteKey(context.Context, *Identifier) (*Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DeleteKey not implemented")
+}
+func (UnimplementedCryptoProviderServer) RotateKey(context.Context, *Identifier) (*Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RotateKey not implemented")
+}
+func (UnimplementedCryptoProviderServer) GetSupportedKeysAlgs(context.Context, *Empty) (*AlgorithmsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetSupportedKeysAlgs not implemented")
+}
+func (UnimplementedCryptoProviderServer) GetSupportedHashAlgs(context.Context, *Empty) (*AlgorithmsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetSupportedHashAlgs not implemented")
+}
+func (UnimplementedCryptoProviderServer) mustEmbedUnimplementedCryptoProviderServer() {}
+func (UnimplementedCryptoProviderServer) testEmbeddedByValue()                        {}
+
+// UnsafeCryptoProviderServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to CryptoProviderServer will
+// result in compilation errors.
+type UnsafeCryptoProviderServer interface {
+	mustEmbedUnimplementedCryptoProviderServer()
+}
+
+func RegisterCryptoProviderServer(s grpc.ServiceRegistrar, srv CryptoProviderServer) {
+	// If the following call pancis, it indicates UnimplementedCryptoProviderServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&CryptoProvider_ServiceDesc, srv)
+}
+
+func _CryptoProvider_CreateCryptoContext_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Context)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CryptoProviderServer).CreateCryptoContext(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: CryptoProvider_CreateCryptoContext_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CryptoProviderServer).CreateCryptoContext(ctx, req.(*Context))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _CryptoProvider_DestroyCryptoContext_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Context)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CryptoProviderServer).DestroyCryptoContext(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: CryptoProvider_DestroyCryptoContext_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CryptoProviderServer).DestroyCryptoContext(ctx, req.(*Context))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _CryptoProvider_IsCryptoContextExisting_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Context)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CryptoProviderServer).IsCryptoContextExisting(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: CryptoProvider_IsCryptoContextExisting_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CryptoProviderServer).IsCryptoContextExisting(ctx, req.(*Context))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _CryptoProvider_GetNamespaces_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Context)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CryptoProviderServer).GetNamespaces(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: CryptoProvider_GetNamespaces_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CryptoProviderServer).GetNamespaces(ctx, req.(*Context))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _CryptoProvider_GenerateRandom_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GenerateRandomRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CryptoProviderServer).GenerateRandom(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: CryptoProvider_GenerateRandom_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CryptoProviderServer).GenerateRandom(ctx, req.(*GenerateRandomRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _CryptoProvider_Hash_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(HashRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CryptoProviderServer).Hash(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: CryptoProvider_Hash_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CryptoProviderServer).Hash(ctx, req.(*HashRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _CryptoProvider_Encrypt_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DataRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CryptoProviderServer).Encrypt(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: CryptoProvider_Encrypt_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CryptoProviderServer).Encrypt(ctx, req.(*DataRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _CryptoProvider_Decrypt_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DataRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CryptoProviderServer).Decrypt(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: CryptoProvider_Decrypt_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CryptoProviderServer).Decrypt(ctx, req.(*DataRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _CryptoProvider_Sign_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DataRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CryptoProviderServer).Sign(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: CryptoProvider_Sign_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CryptoProviderServer).Sign(ctx, req.(*DataRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _CryptoProvider_GetKeys_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Filter)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CryptoProviderServer).GetKeys(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: CryptoProvider_GetKeys_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CryptoProviderServer).GetKeys(ctx, req.(*Filter))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _CryptoProvider_GetKey_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Identifier)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CryptoProviderServer).GetKey(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: CryptoProvider_GetKey_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CryptoProviderServer).GetKey(ctx, req.(*Identifier))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _CryptoProvider_Verify_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(VerifyRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CryptoProviderServer).Verify(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: CryptoProvider_Verify_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CryptoProviderServer).Verify(ctx, req.(*VerifyRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _CryptoProvider_GenerateKey_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(KeyParameter)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CryptoProviderServer).GenerateKey(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: CryptoProvider_GenerateKey_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CryptoProviderServer).GenerateKey(ctx, req.(*KeyParameter))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _CryptoProvider_IsKeyExisting_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Identifier)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CryptoProviderServer).IsKeyExisting(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: CryptoProvider_IsKeyExisting_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CryptoProviderServer).IsKeyExisting(ctx, req.(*Identifier))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _CryptoProvider_DeleteKey_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Identifier)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CryptoProviderServer).DeleteKey(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: CryptoProvider_DeleteKey_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CryptoProviderServer).DeleteKey(ctx, req.(*Identifier))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _CryptoProvider_RotateKey_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Identifier)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CryptoProviderServer).RotateKey(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: CryptoProvider_RotateKey_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CryptoProviderServer).RotateKey(ctx, req.(*Identifier))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _CryptoProvider_GetSupportedKeysAlgs_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Empty)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CryptoProviderServer).GetSupportedKeysAlgs(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: CryptoProvider_GetSupportedKeysAlgs_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CryptoProviderServer).GetSupportedKeysAlgs(ctx, req.(*Empty))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _CryptoProvider_GetSupportedHashAlgs_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Empty)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CryptoProviderServer).GetSupportedHashAlgs(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: CryptoProvider_GetSupportedHashAlgs_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CryptoProviderServer).GetSupportedHashAlgs(ctx, req.(*Empty))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// CryptoProvider_ServiceDesc is the grpc.ServiceDesc for CryptoProvider service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var CryptoProvider_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "cryptoprovider.v1.CryptoProvider",
+	HandlerType: (*CryptoProviderServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "CreateCryptoContext",
+			Handler:    _CryptoProvider_CreateCryptoContext_Handler,
+		},
+		{
+			MethodName: "DestroyCryptoContext",
+			Handler:    _CryptoProvider_DestroyCryptoContext_Handler,
+		},
+		{
+			MethodName: "IsCryptoContextExisting",
+			Handler:    _CryptoProvider_IsCryptoContextExisting_Handler,
+		},
+		{
+			MethodName: "GetNamespaces",
+			Handler:    _CryptoProvider_GetNamespaces_Handler,
+		},
+		{
+			MethodName: "GenerateRandom",
+			Handler:    _CryptoProvider_GenerateRandom_Handler,
+		},
+		{
+			MethodName: "Hash",
+			Handler:    _CryptoProvider_Hash_Handler,
+		},
+		{
+			MethodName: "Encrypt",
+			Handler:    _CryptoProvider_Encrypt_Handler,
+		},
+		{
+			MethodName: "Decrypt",
+			Handler:    _CryptoProvider_Decrypt_Handler,
+		},
+		{
+			MethodName: "Sign",
+			Handler:    _CryptoProvider_Sign_Handler,
+		},
+		{
+			MethodName: "GetKeys",
+			Handler:    _CryptoProvider_GetKeys_Handler,
+		},
+		{
+			MethodName: "GetKey",
+			Handler:    _CryptoProvider_GetKey_Handler,
+		},
+		{
+			MethodName: "Verify",
+			Handler:    _CryptoProvider_Verify_Handler,
+		},
+		{
+			MethodName: "GenerateKey",
+			Handler:    _CryptoProvider_GenerateKey_Handler,
+		},
+		{
+			MethodName: "IsKeyExisting",
+			Handler:    _CryptoProvider_IsKeyExisting_Handler,
+		},
+		{
+			MethodName: "DeleteKey",
+			Handler:    _CryptoProvider_DeleteKey_Handler,
+		},
+		{
+			MethodName: "RotateKey",
+			Handler:    _CryptoProvider_RotateKey_Handler,
+		},
+		{
+			MethodName: "GetSupportedKeysAlgs",
+			Handler:    _CryptoProvider_GetSupportedKeysAlgs_Handler,
+		},
+		{
+			MethodName: "GetSupportedHashAlgs",
+			Handler:    _CryptoProvider_GetSupportedHashAlgs_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "provider.proto",
+}
@@ -0,0 +1,216 @@
+package remote
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"math"
+	"net"
+	"regexp"
+	"testing"
+
+	"github.com/eclipse-xfsc/crypto-provider-core/types"
+	"github.com/eclipse-xfsc/crypto-provider-luna-cloud-hsm-plugin/conformance"
+	"github.com/eclipse-xfsc/crypto-provider-luna-cloud-hsm-plugin/hsm"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// providerStub keeps keys in a map and signs by echoing the data.
+type providerStub struct {
+	types.CryptoProvider
+	keys map[string]types.CryptoKey
+}
+
+func (p providerStub) GetNamespaces(context types.CryptoContext) ([]string, error) {
+	if context.Namespace != "ns" {
+		return nil, &types.CryptoContextError{Err: errors.New("context not initialized")}
+	}
+	return []string{"ns"}, nil
+}
+
+func (p providerStub) GetKey(parameter types.CryptoIdentifier) (*types.CryptoKey, error) {
+	key, ok := p.keys[parameter.KeyId]
+	if !ok {
+		return nil, errors.New("key " + parameter.KeyId + " not found")
+	}
+	return &key, nil
+}
+
+func (p providerStub) GetKeys(parameter types.CryptoFilter) (*types.CryptoKeySet, error) {
+	set := &types.CryptoKeySet{}
+	for id, key := range p.keys {
+		if parameter.Filter.MatchString(id) {
+			set.Keys = append(set.Keys, key)
+		}
+	}
+	return set, nil
+}
+
+func (p providerStub) Sign(parameter types.CryptoIdentifier, data []byte) ([]byte, error) {
+	return append([]byte(parameter.KeyId+":"), data...), nil
+}
+
+func (p providerStub) GetSupportedKeysAlgs() []types.KeyType {
+	return []types.KeyType{types.Ecdsap256}
+}
+
+func getTestClient(t *testing.T) *Client {
+	key := types.CryptoKey{Key: []byte("public key"), Version: "1"}
+	key.Identifier = types.CryptoIdentifier{KeyId: "key1", CryptoContext: types.CryptoContext{Namespace: "ns"}}
+	key.KeyType = types.Ecdsap256
+	return dial(t, providerStub{keys: map[string]types.CryptoKey{"key1": key}})
+}
+
+// dial serves the provider over an in-memory connection.
+func dial(t *testing.T, provider types.CryptoProvider) *Client {
+	listener := bufconn.Listen(1 << 20)
+	server := grpc.NewServer(grpc.ChainUnaryInterceptor(RecoveryInterceptor, TraceContextInterceptor))
+	NewServer(provider).Register(server)
+	go server.Serve(listener)
+	t.Cleanup(server.Stop)
+
+	client, err := Dial("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return listener.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	assert.Nil(t, err)
+	t.Cleanup(func() { client.Close() })
+	return client
+}
+
+func TestClient_ImplementsCryptoProvider(t *testing.T) {
+	var _ types.CryptoProvider = &Client{}
+}
+
+func TestClient_Keys(t *testing.T) {
+	client := getTestClient(t)
+
+	key, err := client.GetKey(types.CryptoIdentifier{KeyId: "key1"})
+	assert.Nil(t, err)
+	assert.Equal(t, []byte("public key"), key.Key)
+	assert.Equal(t, types.Ecdsap256, key.KeyType)
+	assert.Equal(t, "ns", key.Identifier.CryptoContext.Namespace)
+
+	_, err = client.GetKey(types.CryptoIdentifier{KeyId: "key2"})
+	assert.EqualError(t, err, "key key2 not found")
+
+	keys, err := client.GetKeys(types.CryptoFilter{Filter: *regexp.MustCompile("^key")})
+	assert.Nil(t, err)
+	assert.Len(t, keys.Keys, 1)
+	keys, err = client.GetKeys(types.CryptoFilter{Filter: *regexp.MustCompile("^other")})
+	assert.Nil(t, err)
+	assert.Empty(t, keys.Keys)
+}
+
+func TestClient_Operations(t *testing.T) {
+	client := getTestClient(t)
+
+	signature, err := client.Sign(types.CryptoIdentifier{KeyId: "key1"}, []byte("data"))
+	assert.Nil(t, err)
+	assert.Equal(t, []byte("key1:data"), signature)
+
+	namespaces, err := client.GetNamespaces(types.CryptoContext{Namespace: "ns"})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"ns"}, namespaces)
+	_, err = client.GetNamespaces(types.CryptoContext{Namespace: "other"})
+	var contextError *types.CryptoContextError
+	assert.ErrorAs(t, err, &contextError)
+
+	assert.Equal(t, []types.KeyType{types.Ecdsap256}, client.GetSupportedKeysAlgs())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = client.Sign(types.CryptoIdentifier{KeyId: "key1", CryptoContext: types.CryptoContext{Context: ctx}}, []byte("data"))
+	assert.NotNil(t, err)
+}
+
+func TestRecoveryInterceptor(t *testing.T) {
+	client := getTestClient(t)
+
+	// the stub does not implement Verify, so the call panics
+	_, err := client.Verify(types.CryptoIdentifier{KeyId: "key1"}, []byte("data"), []byte("signature"))
+	assert.Equal(t, codes.Internal, status.Code(err))
+
+	signature, err := client.Sign(types.CryptoIdentifier{KeyId: "key1"}, []byte("data"))
+	assert.Nil(t, err)
+	assert.Equal(t, []byte("key1:data"), signature)
+}
+
+// contextStub records the context of the signing call.
+type contextStub struct {
+	providerStub
+	signed *context.Context
+}
+
+func (p contextStub) Sign(parameter types.CryptoIdentifier, data []byte) ([]byte, error) {
+	*p.signed = parameter.CryptoContext.Context
+	return p.providerStub.Sign(parameter, data)
+}
+
+func TestTraceContextInterceptor(t *testing.T) {
+	previous := otel.GetTextMapPropagator()
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	t.Cleanup(func() { otel.SetTextMapPropagator(previous) })
+
+	var signed context.Context
+	client := dial(t, contextStub{signed: &signed})
+	span := trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    trace.TraceID{1, 2, 3},
+		SpanID:     trace.SpanID{4, 5, 6},
+		TraceFlags: trace.FlagsSampled,
+	})
+	ctx := trace.ContextWithSpanContext(context.Background(), span)
+	_, err := client.Sign(types.CryptoIdentifier{KeyId: "key1", CryptoContext: types.CryptoContext{Context: ctx}}, []byte("data"))
+	assert.Nil(t, err)
+
+	remote := trace.SpanContextFromContext(signed)
+	assert.True(t, remote.IsRemote())
+	assert.Equal(t, span.TraceID(), remote.TraceID())
+	assert.Equal(t, span.SpanID(), remote.SpanID())
+}
+
+func TestStatus_Errors(t *testing.T) {
+	for _, target := range []error{errors.ErrUnsupported, fs.ErrNotExist} {
+		err := fromStatus(toStatus(fmt.Errorf("operation failed: %w", target)))
+		assert.ErrorIs(t, err, target)
+		assert.EqualError(t, err, "operation failed: "+target.Error())
+	}
+	assert.Equal(t, codes.NotFound, status.Code(toStatus(fmt.Errorf("key %w", fs.ErrNotExist))))
+	assert.Equal(t, codes.Unimplemented, status.Code(toStatus(errors.ErrUnsupported)))
+}
+
+func TestClient_Conformance(t *testing.T) {
+	provider, err := hsm.New(hsm.Options{Backend: hsm.DevBackend})
+	assert.Nil(t, err)
+	client := dial(t, provider)
+
+	conformance.Run(t, client, conformance.Options{})
+
+	_, err = client.Sign(types.CryptoIdentifier{KeyId: "missing"}, []byte("data"))
+	assert.ErrorIs(t, err, hsm.ErrKeyNotFound)
+	assert.ErrorIs(t, client.RotateKey(types.CryptoIdentifier{KeyId: "missing"}), errors.ErrUnsupported)
+}
+
+func TestServer_GenerateRandom_Bounds(t *testing.T) {
+	provider, err := hsm.New(hsm.Options{Backend: hsm.DevBackend})
+	assert.Nil(t, err)
+	client := dial(t, provider)
+
+	random, err := client.GenerateRandom(types.CryptoContext{}, MaxRandomBytes)
+	assert.Nil(t, err)
+	assert.Len(t, random, MaxRandomBytes)
+	_, err = client.GenerateRandom(types.CryptoContext{}, MaxRandomBytes+1)
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	_, err = client.client.GenerateRandom(context.Background(), &GenerateRandomRequest{Number: -1})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	_, err = client.GenerateRandom(types.CryptoContext{}, math.MaxInt32+1)
+	assert.EqualError(t, err, "invalid number of random bytes 2147483648")
+}
@@ -0,0 +1,156 @@
+// Package remote serves a types.CryptoProvider over gRPC and implements types.CryptoProvider as
+// client of such a server, so the core does not need to load the HSM plugin into its process.
+package remote
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative provider.proto
+
+import (
+	"context"
+	"log/slog"
+	"runtime/debug"
+
+	"github.com/eclipse-xfsc/crypto-provider-core/types"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// MaxRandomBytes is the maximum number of random bytes of a GenerateRandom request.
+const MaxRandomBytes = 64 * 1024
+
+// Server exposes a provider as CryptoProvider gRPC service.
+type Server struct {
+	UnimplementedCryptoProviderServer
+	provider types.CryptoProvider
+}
+
+// NewServer creates the gRPC service of the provider.
+func NewServer(provider types.CryptoProvider) *Server {
+	return &Server{provider: provider}
+}
+
+// Register adds the service to the gRPC server.
+func (s *Server) Register(server grpc.ServiceRegistrar) {
+	RegisterCryptoProviderServer(server, s)
+}
+
+// RecoveryInterceptor turns panics of the provider into codes.Internal, so a single faulty call does not
+// terminate the server. The panic is logged with its stack, the client only learns the method.
+func RecoveryInterceptor(ctx context.Context, request interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (response interface{}, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			slog.ErrorContext(ctx, "panic in gRPC handler", slog.String("method", info.FullMethod), slog.Any("panic", r), slog.String("stack", string(debug.Stack())))
+			err = status.Errorf(codes.Internal, "internal error in %s", info.FullMethod)
+		}
+	}()
+	return handler(ctx, request)
+}
+
+func (s *Server) CreateCryptoContext(ctx context.Context, request *Context) (*Empty, error) {
+	return &Empty{}, toStatus(s.provider.CreateCryptoContext(fromContext(ctx, request)))
+}
+
+func (s *Server) DestroyCryptoContext(ctx context.Context, request *Context) (*Empty, error) {
+	return &Empty{}, toStatus(s.provider.DestroyCryptoContext(fromContext(ctx, request)))
+}
+
+func (s *Server) IsCryptoContextExisting(ctx context.Context, request *Context) (*BoolResponse, error) {
+	exists, err := s.provider.IsCryptoContextExisting(fromContext(ctx, request))
+	return &BoolResponse{Value: exists}, toStatus(err)
+}
+
+func (s *Server) GetNamespaces(ctx context.Context, request *Context) (*NamespacesResponse, error) {
+	namespaces, err := s.provider.GetNamespaces(fromContext(ctx, request))
+	return &NamespacesResponse{Namespaces: namespaces}, toStatus(err)
+}
+
+func (s *Server) GenerateRandom(ctx context.Context, request *GenerateRandomRequest) (*BytesResponse, error) {
+	if request.GetNumber() < 0 || request.GetNumber() > MaxRandomBytes {
+		return nil, status.Errorf(codes.InvalidArgument, "number of random bytes must be between 0 and %d", MaxRandomBytes)
+	}
+	random, err := s.provider.GenerateRandom(fromContext(ctx, request.GetContext()), int(request.GetNumber()))
+	return &BytesResponse{Value: random}, toStatus(err)
+}
+
+func (s *Server) Hash(ctx context.Context, request *HashRequest) (*BytesResponse, error) {
+	parameter := types.CryptoHashParameter{Identifier: fromIdentifier(ctx, request.GetIdentifier()), HashAlgorithm: types.HashAlgorithm(request.GetHashAlgorithm())}
+	hash, err := s.provider.Hash(parameter, request.GetMsg())
+	return &BytesResponse{Value: hash}, toStatus(err)
+}
+
+func (s *Server) Encrypt(ctx context.Context, request *DataRequest) (*BytesResponse, error) {
+	encrypted, err := s.provider.Encrypt(fromIdentifier(ctx, request.GetIdentifier()), request.GetData())
+	return &BytesResponse{Value: encrypted}, toStatus(err)
+}
+
+func (s *Server) Decrypt(ctx context.Context, request *DataRequest) (*BytesResponse, error) {
+	decrypted, err := s.provider.Decrypt(fromIdentifier(ctx, request.GetIdentifier()), request.GetData())
+	return &BytesResponse{Value: decrypted}, toStatus(err)
+}
+
+func (s *Server) Sign(ctx context.Context, request *DataRequest) (*BytesResponse, error) {
+	signature, err := s.provider.Sign(fromIdentifier(ctx, request.GetIdentifier()), request.GetData())
+	return &BytesResponse{Value: signature}, toStatus(err)
+}
+
+func (s *Server) GetKeys(ctx context.Context, request *Filter) (*KeySet, error) {
+	filter, err := fromFilter(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+	keys, err := s.provider.GetKeys(filter)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	set := &KeySet{}
+	for i := range keys.Keys {
+		set.Keys = append(set.Keys, toKey(&keys.Keys[i]))
+	}
+	return set, nil
+}
+
+func (s *Server) GetKey(ctx context.Context, request *Identifier) (*Key, error) {
+	key, err := s.provider.GetKey(fromIdentifier(ctx, request))
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return toKey(key), nil
+}
+
+func (s *Server) Verify(ctx context.Context, request *VerifyRequest) (*BoolResponse, error) {
+	valid, err := s.provider.Verify(fromIdentifier(ctx, request.GetIdentifier()), request.GetData(), request.GetSignature())
+	return &BoolResponse{Value: valid}, toStatus(err)
+}
+
+func (s *Server) GenerateKey(ctx context.Context, request *KeyParameter) (*Empty, error) {
+	return &Empty{}, toStatus(s.provider.GenerateKey(fromKeyParameter(ctx, request)))
+}
+
+func (s *Server) IsKeyExisting(ctx context.Context, request *Identifier) (*BoolResponse, error) {
+	exists, err := s.provider.IsKeyExisting(fromIdentifier(ctx, request))
+	return &BoolResponse{Value: exists}, toStatus(err)
+}
+
+func (s *Server) DeleteKey(ctx context.Context, request *Identifier) (*Empty, error) {
+	return &Empty{}, toStatus(s.provider.DeleteKey(fromIdentifier(ctx, request)))
+}
+
+func (s *Server) RotateKey(ctx context.Context, request *Identifier) (*Empty, error) {
+	return &Empty{}, toStatus(s.provider.RotateKey(fromIdentifier(ctx, request)))
+}
+
+func (s *Server) GetSupportedKeysAlgs(context.Context, *Empty) (*AlgorithmsResponse, error) {
+	response := &AlgorithmsResponse{}
+	for _, keyType := range s.provider.GetSupportedKeysAlgs() {
+		response.Algorithms = append(response.Algorithms, string(keyType))
+	}
+	return response, nil
+}
+
+func (s *Server) GetSupportedHashAlgs(context.Context, *Empty) (*AlgorithmsResponse, error) {
+	response := &AlgorithmsResponse{}
+	for _, hashAlgorithm := range s.provider.GetSupportedHashAlgs() {
+		response.Algorithms = append(response.Algorithms, string(hashAlgorithm))
+	}
+	return response, nil
+}
@@ -0,0 +1,381 @@
+// Package rest serves a types.CryptoProvider as JSON HTTP API for consumers which can not load Go
+// plugins. Binary values are base64 encoded, the API is described in openapi.yaml.
+package rest
+
+import (
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/eclipse-xfsc/crypto-provider-core/types"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+)
+
+//go:embed openapi.yaml
+var openAPI []byte
+
+// Headers selecting the crypto context of a request.
+const (
+	NamespaceHeader = "X-Crypto-Namespace"
+	GroupHeader     = "X-Crypto-Group"
+	EngineHeader    = "X-Crypto-Engine"
+)
+
+const maxRequestSize = 1 << 20
+
+// Options configures the HTTP API.
+type Options struct {
+	// AllowedNamespaces maps the common names of client certificates to the namespaces they may use,
+	// "*" allows all namespaces. Requests are not authorized if nil, e.g. behind a unix socket.
+	AllowedNamespaces map[string][]string
+}
+
+// Server is the http.Handler of the API.
+type Server struct {
+	provider types.CryptoProvider
+	options  Options
+	mux      *http.ServeMux
+}
+
+// Key is the JSON representation of a types.CryptoKey.
+type Key struct {
+	KeyId   string          `json:"keyId"`
+	KeyType types.KeyType   `json:"keyType"`
+	Key     string          `json:"key,omitempty"`
+	Version string          `json:"version,omitempty"`
+	Params  json.RawMessage `json:"params,omitempty"`
+}
+
+type keysResponse struct {
+	Keys []Key `json:"keys"`
+}
+
+type dataRequest struct {
+	Data      []byte `json:"data"`
+	Signature []byte `json:"signature,omitempty"`
+}
+
+type dataResponse struct {
+	Data []byte `json:"data"`
+}
+
+type signatureResponse struct {
+	Signature []byte `json:"signature"`
+}
+
+type verifyResponse struct {
+	Valid bool `json:"valid"`
+}
+
+type randomRequest struct {
+	Length int `json:"length"`
+}
+
+type hashRequest struct {
+	Algorithm types.HashAlgorithm `json:"algorithm"`
+	Data      []byte              `json:"data"`
+}
+
+type hashResponse struct {
+	Hash []byte `json:"hash"`
+}
+
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+type httpError struct {
+	status int
+	err    error
+}
+
+func (e *httpError) Error() string {
+	return e.err.Error()
+}
+
+// NewServer creates the HTTP API of the provider.
+func NewServer(provider types.CryptoProvider, options Options) *Server {
+	s := &Server{provider: provider, options: options, mux: http.NewServeMux()}
+	s.mux.HandleFunc("GET /openapi.yaml", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/yaml")
+		_, _ = w.Write(openAPI)
+	})
+	s.handle("GET /v1/keys", s.getKeys)
+	s.handle("POST /v1/keys", s.generateKey)
+	s.handle("GET /v1/keys/{id}", s.getKey)
+	s.handle("DELETE /v1/keys/{id}", s.deleteKey)
+	s.handle("POST /v1/keys/{id}/sign", s.sign)
+	s.handle("POST /v1/keys/{id}/verify", s.verify)
+	s.handle("POST /v1/keys/{id}/encrypt", s.encrypt)
+	s.handle("POST /v1/keys/{id}/decrypt", s.decrypt)
+	s.handle("POST /v1/random", s.random)
+	s.handle("POST /v1/hash", s.hash)
+	return s
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+// handle registers an endpoint, which gets the authorized crypto context of the request and returns
+// the JSON response body or an error. The context continues the trace of the traceparent header.
+func (s *Server) handle(pattern string, handler func(r *http.Request, context types.CryptoContext) (int, interface{}, error)) {
+	s.mux.HandleFunc(pattern, func(w http.ResponseWriter, r *http.Request) {
+		r.Body = http.MaxBytesReader(w, r.Body, maxRequestSize)
+		context := types.CryptoContext{
+			Namespace: r.Header.Get(NamespaceHeader),
+			Group:     r.Header.Get(GroupHeader),
+			Engine:    r.Header.Get(EngineHeader),
+			Context:   otel.GetTextMapPropagator().Extract(r.Context(), propagation.HeaderCarrier(r.Header)),
+		}
+		status, body, err := http.StatusOK, interface{}(nil), error(nil)
+		if strings.Contains(context.Namespace, "/") {
+			err = &httpError{status: http.StatusBadRequest, err: errors.New("invalid namespace")}
+		} else {
+			err = s.authorize(r, context.Namespace)
+		}
+		if err == nil {
+			status, body, err = handler(r, context)
+		}
+		if err != nil {
+			status, body = errorStatus(err), errorResponse{Error: err.Error()}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		if body != nil {
+			_ = json.NewEncoder(w).Encode(body)
+		}
+	})
+}
+
+func (s *Server) authorize(r *http.Request, namespace string) error {
+	if s.options.AllowedNamespaces == nil {
+		return nil
+	}
+	if r.TLS == nil || len(r.TLS.PeerCertificates) == 0 {
+		return &httpError{status: http.StatusUnauthorized, err: errors.New("client certificate required")}
+	}
+	allowed := s.options.AllowedNamespaces[r.TLS.PeerCertificates[0].Subject.CommonName]
+	if !slices.Contains(allowed, namespace) && !slices.Contains(allowed, "*") {
+		return &httpError{status: http.StatusForbidden, err: fmt.Errorf("namespace %q is not allowed", namespace)}
+	}
+	return nil
+}
+
+func errorStatus(err error) int {
+	var httpErr *httpError
+	var contextError *types.CryptoContextError
+	switch {
+	case errors.As(err, &httpErr):
+		return httpErr.status
+	case errors.As(err, &contextError):
+		return http.StatusPreconditionFailed
+	case errors.Is(err, errors.ErrUnsupported):
+		return http.StatusNotImplemented
+	case errors.Is(err, fs.ErrNotExist):
+		return http.StatusNotFound
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+func decode(r *http.Request, v interface{}) error {
+	if err := json.NewDecoder(r.Body).Decode(v); err != nil {
+		return &httpError{status: http.StatusBadRequest, err: fmt.Errorf("invalid request body: %w", err)}
+	}
+	return nil
+}
+
+// keyPrefix is prepended to the key ids of the namespace, like the KMIP server does, so clients only
+// reach the keys of the namespaces they are allowed to use.
+func keyPrefix(context types.CryptoContext) string {
+	if context.Namespace == "" {
+		return ""
+	}
+	return context.Namespace + "/"
+}
+
+func validKeyId(id string) bool {
+	return id != "" && !strings.Contains(id, "/")
+}
+
+func identifier(r *http.Request, context types.CryptoContext) (types.CryptoIdentifier, error) {
+	id := r.PathValue("id")
+	if !validKeyId(id) {
+		return types.CryptoIdentifier{}, &httpError{status: http.StatusBadRequest, err: errors.New("invalid key id")}
+	}
+	return types.CryptoIdentifier{KeyId: keyPrefix(context) + id, CryptoContext: context}, nil
+}
+
+// toKey returns the key with the id the client knows, or false if the key is not in its namespace.
+func toKey(key types.CryptoKey, context types.CryptoContext) (Key, bool) {
+	id, ok := strings.CutPrefix(key.Identifier.KeyId, keyPrefix(context))
+	if !ok || !validKeyId(id) {
+		return Key{}, false
+	}
+	return Key{KeyId: id, KeyType: key.KeyType, Key: string(key.Key), Version: key.Version, Params: key.Params}, true
+}
+
+func (s *Server) getKeys(r *http.Request, context types.CryptoContext) (int, interface{}, error) {
+	filter, err := regexp.Compile(r.URL.Query().Get("filter"))
+	if err != nil {
+		return 0, nil, &httpError{status: http.StatusBadRequest, err: err}
+	}
+	prefix := regexp.MustCompile("^" + regexp.QuoteMeta(keyPrefix(context)))
+	keys, err := s.provider.GetKeys(types.CryptoFilter{Filter: *prefix, CryptoContext: context})
+	if err != nil {
+		return 0, nil, err
+	}
+	response := keysResponse{Keys: []Key{}}
+	for _, key := range keys.Keys {
+		if key, ok := toKey(key, context); ok && filter.MatchString(key.KeyId) {
+			response.Keys = append(response.Keys, key)
+		}
+	}
+	return http.StatusOK, response, nil
+}
+
+func (s *Server) generateKey(r *http.Request, context types.CryptoContext) (int, interface{}, error) {
+	var request Key
+	if err := decode(r, &request); err != nil {
+		return 0, nil, err
+	}
+	if !validKeyId(request.KeyId) {
+		return 0, nil, &httpError{status: http.StatusBadRequest, err: errors.New("invalid keyId")}
+	}
+	if !types.ValidateMethod(request.KeyType) {
+		return 0, nil, &httpError{status: http.StatusBadRequest, err: fmt.Errorf("unsupported keyType %q", request.KeyType)}
+	}
+	parameter := types.CryptoKeyParameter{
+		Identifier: types.CryptoIdentifier{KeyId: keyPrefix(context) + request.KeyId, CryptoContext: context},
+		KeyType:    request.KeyType,
+		Params:     request.Params,
+	}
+	if err := s.provider.GenerateKey(parameter); err != nil {
+		return 0, nil, err
+	}
+	return http.StatusCreated, Key{KeyId: request.KeyId, KeyType: request.KeyType}, nil
+}
+
+func (s *Server) getKey(r *http.Request, context types.CryptoContext) (int, interface{}, error) {
+	parameter, err := identifier(r, context)
+	if err != nil {
+		return 0, nil, err
+	}
+	key, err := s.provider.GetKey(parameter)
+	if err != nil {
+		return 0, nil, err
+	}
+	response, _ := toKey(*key, context)
+	return http.StatusOK, response, nil
+}
+
+func (s *Server) deleteKey(r *http.Request, context types.CryptoContext) (int, interface{}, error) {
+	parameter, err := identifier(r, context)
+	if err != nil {
+		return 0, nil, err
+	}
+	return http.StatusNoContent, nil, s.provider.DeleteKey(parameter)
+}
+
+func (s *Server) sign(r *http.Request, context types.CryptoContext) (int, interface{}, error) {
+	parameter, err := identifier(r, context)
+	if err != nil {
+		return 0, nil, err
+	}
+	var request dataRequest
+	if err := decode(r, &request); err != nil {
+		return 0, nil, err
+	}
+	signature, err := s.provider.Sign(parameter, request.Data)
+	return http.StatusOK, signatureResponse{Signature: signature}, err
+}
+
+func (s *Server) verify(r *http.Request, context types.CryptoContext) (int, interface{}, error) {
+	parameter, err := identifier(r, context)
+	if err != nil {
+		return 0, nil, err
+	}
+	var request dataRequest
+	if err := decode(r, &request); err != nil {
+		return 0, nil, err
+	}
+	valid, err := s.provider.Verify(parameter, request.Data, request.Signature)
+	return http.StatusOK, verifyResponse{Valid: valid}, err
+}
+
+func (s *Server) encrypt(r *http.Request, context types.CryptoContext) (int, interface{}, error) {
+	parameter, err := identifier(r, context)
+	if err != nil {
+		return 0, nil, err
+	}
+	var request dataRequest
+	if err := decode(r, &request); err != nil {
+		return 0, nil, err
+	}
+	encrypted, err := s.provider.Encrypt(parameter, request.Data)
+	return http.StatusOK, dataResponse{Data: encrypted}, err
+}
+
+func (s *Server) decrypt(r *http.Request, context types.CryptoContext) (int, interface{}, error) {
+	parameter, err := identifier(r, context)
+	if err != nil {
+		return 0, nil, err
+	}
+	var request dataRequest
+	if err := decode(r, &request); err != nil {
+		return 0, nil, err
+	}
+	decrypted, err := s.provider.Decrypt(parameter, request.Data)
+	return http.StatusOK, dataResponse{Data: decrypted}, err
+}
+
+func (s *Server) random(r *http.Request, context types.CryptoContext) (int, interface{}, error) {
+	var request randomRequest
+	if err := decode(r, &request); err != nil {
+		return 0, nil, err
+	}
+	if request.Length <= 0 || request.Length > maxRequestSize {
+		return 0, nil, &httpError{status: http.StatusBadRequest, err: errors.New("invalid length")}
+	}
+	random, err := s.provider.GenerateRandom(context, request.Length)
+	return http.StatusOK, dataResponse{Data: random}, err
+}
+
+func (s *Server) hash(r *http.Request, context types.CryptoContext) (int, interface{}, error) {
+	var request hashRequest
+	if err := decode(r, &request); err != nil {
+		return 0, nil, err
+	}
+	if !types.ValidateHashFunction(request.Algorithm) {
+		return 0, nil, &httpError{status: http.StatusBadRequest, err: fmt.Errorf("unsupported algorithm %q", request.Algorithm)}
+	}
+	parameter := types.CryptoHashParameter{Identifier: types.CryptoIdentifier{CryptoContext: context}, HashAlgorithm: request.Algorithm}
+	hash, err := s.provider.Hash(parameter, request.Data)
+	return http.StatusOK, hashResponse{Hash: hash}, err
+}
+
+// ParseAllowedNamespaces parses "client-a=ns1,ns2;client-b=*" into Options.AllowedNamespaces.
+func ParseAllowedNamespaces(value string) (map[string][]string, error) {
+	allowed := map[string][]string{}
+	for _, entry := range strings.Split(value, ";") {
+		if strings.TrimSpace(entry) == "" {
+			continue
+		}
+		client, namespaces, ok := strings.Cut(entry, "=")
+		if !ok || strings.TrimSpace(client) == "" {
+			return nil, fmt.Errorf("invalid allowed namespaces entry %q", entry)
+		}
+		for _, namespace := range strings.Split(namespaces, ",") {
+			allowed[strings.TrimSpace(client)] = append(allowed[strings.TrimSpace(client)], strings.TrimSpace(namespace))
+		}
+	}
+	return allowed, nil
+}
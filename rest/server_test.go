@@ -0,0 +1,224 @@
+package rest
+
+import (
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/eclipse-xfsc/crypto-provider-core/types"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// providerStub signs by prefixing the data with the key id.
+type providerStub struct {
+	types.CryptoProvider
+	generated []types.CryptoKeyParameter
+	signed    types.CryptoContext
+}
+
+func (p *providerStub) GenerateKey(parameter types.CryptoKeyParameter) error {
+	if parameter.Identifier.CryptoContext.Namespace == "missing" {
+		return &types.CryptoContextError{Err: errors.New("context not initialized")}
+	}
+	p.generated = append(p.generated, parameter)
+	return nil
+}
+
+func (p *providerStub) GetKeys(parameter types.CryptoFilter) (*types.CryptoKeySet, error) {
+	set := &types.CryptoKeySet{}
+	for _, generated := range p.generated {
+		if parameter.Filter.MatchString(generated.Identifier.KeyId) {
+			set.Keys = append(set.Keys, types.CryptoKey{CryptoKeyParameter: generated, Key: []byte("pem")})
+		}
+	}
+	return set, nil
+}
+
+func (p *providerStub) GetKey(parameter types.CryptoIdentifier) (*types.CryptoKey, error) {
+	for _, generated := range p.generated {
+		if generated.Identifier.KeyId == parameter.KeyId {
+			return &types.CryptoKey{CryptoKeyParameter: generated, Key: []byte("pem")}, nil
+		}
+	}
+	return nil, fmt.Errorf("key %s: %w", parameter.KeyId, fs.ErrNotExist)
+}
+
+func (p *providerStub) Sign(parameter types.CryptoIdentifier, data []byte) ([]byte, error) {
+	p.signed = parameter.CryptoContext
+	return append([]byte(parameter.KeyId+":"), data...), nil
+}
+
+func (p *providerStub) Verify(parameter types.CryptoIdentifier, data []byte, signature []byte) (bool, error) {
+	switch parameter.KeyId {
+	case "ns/missing":
+		return false, fmt.Errorf("key missing: %w", fs.ErrNotExist)
+	case "ns/broken":
+		return false, errors.New("CKR_DEVICE_ERROR")
+	}
+	expected, _ := p.Sign(parameter, data)
+	if !bytes.Equal(expected, signature) {
+		return false, nil
+	}
+	return true, nil
+}
+
+func (p *providerStub) Encrypt(types.CryptoIdentifier, []byte) ([]byte, error) {
+	return nil, errors.ErrUnsupported
+}
+
+func do(t *testing.T, server http.Handler, method string, path string, body interface{}, header http.Header, response interface{}) int {
+	var payload bytes.Buffer
+	if body != nil {
+		assert.Nil(t, json.NewEncoder(&payload).Encode(body))
+	}
+	r := httptest.NewRequest(method, path, &payload)
+	for name := range header {
+		r.Header.Set(name, header.Get(name))
+	}
+	w := httptest.NewRecorder()
+	server.ServeHTTP(w, r)
+	if response != nil {
+		assert.Nil(t, json.Unmarshal(w.Body.Bytes(), response))
+	}
+	return w.Code
+}
+
+func TestServer_Keys(t *testing.T) {
+	provider := &providerStub{}
+	server := NewServer(provider, Options{})
+	header := http.Header{NamespaceHeader: {"ns"}, GroupHeader: {"group"}}
+
+	var key Key
+	status := do(t, server, http.MethodPost, "/v1/keys", Key{KeyId: "key1", KeyType: types.Ecdsap256}, header, &key)
+	assert.Equal(t, http.StatusCreated, status)
+	assert.Equal(t, "key1", key.KeyId)
+	assert.Equal(t, "ns/key1", provider.generated[0].Identifier.KeyId)
+	assert.Equal(t, "ns", provider.generated[0].Identifier.CryptoContext.Namespace)
+	assert.Equal(t, "group", provider.generated[0].Identifier.CryptoContext.Group)
+
+	var keys keysResponse
+	assert.Equal(t, http.StatusOK, do(t, server, http.MethodGet, "/v1/keys?filter=^key", nil, header, &keys))
+	assert.Len(t, keys.Keys, 1)
+	assert.Equal(t, "key1", keys.Keys[0].KeyId)
+	assert.Equal(t, "pem", keys.Keys[0].Key)
+	assert.Equal(t, http.StatusOK, do(t, server, http.MethodGet, "/v1/keys?filter=^other", nil, header, &keys))
+	assert.Empty(t, keys.Keys)
+
+	var failure errorResponse
+	assert.Equal(t, http.StatusBadRequest, do(t, server, http.MethodPost, "/v1/keys", Key{KeyId: "key2", KeyType: "dsa"}, header, &failure))
+	assert.Equal(t, "unsupported keyType \"dsa\"", failure.Error)
+	assert.Equal(t, http.StatusPreconditionFailed, do(t, server, http.MethodPost, "/v1/keys", Key{KeyId: "key2", KeyType: types.Rsa2048}, http.Header{NamespaceHeader: {"missing"}}, nil))
+}
+
+func TestServer_Operations(t *testing.T) {
+	server := NewServer(&providerStub{}, Options{})
+	header := http.Header{NamespaceHeader: {"ns"}}
+
+	var signature signatureResponse
+	assert.Equal(t, http.StatusOK, do(t, server, http.MethodPost, "/v1/keys/key1/sign", dataRequest{Data: []byte("data")}, header, &signature))
+	assert.Equal(t, []byte("ns/key1:data"), signature.Signature)
+	assert.Equal(t, http.StatusBadRequest, do(t, server, http.MethodPost, "/v1/keys/other%2Fkey1/sign", dataRequest{Data: []byte("data")}, header, nil))
+	assert.Equal(t, http.StatusBadRequest, do(t, server, http.MethodPost, "/v1/keys/key1/sign", dataRequest{Data: []byte("data")}, http.Header{NamespaceHeader: {"ns/other"}}, nil))
+
+	var verified verifyResponse
+	assert.Equal(t, http.StatusOK, do(t, server, http.MethodPost, "/v1/keys/key1/verify", dataRequest{Data: []byte("data"), Signature: signature.Signature}, header, &verified))
+	assert.True(t, verified.Valid)
+	assert.Equal(t, http.StatusOK, do(t, server, http.MethodPost, "/v1/keys/key1/verify", dataRequest{Data: []byte("other"), Signature: signature.Signature}, header, &verified))
+	assert.False(t, verified.Valid)
+	assert.Equal(t, http.StatusNotFound, do(t, server, http.MethodPost, "/v1/keys/missing/verify", dataRequest{Data: []byte("data"), Signature: signature.Signature}, header, nil))
+	assert.Equal(t, http.StatusInternalServerError, do(t, server, http.MethodPost, "/v1/keys/broken/verify", dataRequest{Data: []byte("data"), Signature: signature.Signature}, header, nil))
+
+	assert.Equal(t, http.StatusNotImplemented, do(t, server, http.MethodPost, "/v1/keys/key1/encrypt", dataRequest{Data: []byte("data")}, header, nil))
+	assert.Equal(t, http.StatusBadRequest, do(t, server, http.MethodPost, "/v1/random", randomRequest{Length: 0}, header, nil))
+	assert.Equal(t, http.StatusBadRequest, do(t, server, http.MethodPost, "/v1/hash", hashRequest{Algorithm: "md5"}, header, nil))
+
+	r := httptest.NewRequest(http.MethodGet, "/openapi.yaml", nil)
+	w := httptest.NewRecorder()
+	server.ServeHTTP(w, r)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "/v1/keys/{id}/sign")
+}
+
+func TestServer_TraceContext(t *testing.T) {
+	previous := otel.GetTextMapPropagator()
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	t.Cleanup(func() { otel.SetTextMapPropagator(previous) })
+
+	provider := &providerStub{}
+	header := http.Header{NamespaceHeader: {"ns"}, "Traceparent": {"00-0102030405060708090a0b0c0d0e0f10-0102030405060708-01"}}
+	assert.Equal(t, http.StatusOK, do(t, NewServer(provider, Options{}), http.MethodPost, "/v1/keys/key1/sign", dataRequest{Data: []byte("data")}, header, nil))
+
+	span := trace.SpanContextFromContext(provider.signed.Context)
+	assert.True(t, span.IsRemote())
+	assert.Equal(t, "0102030405060708090a0b0c0d0e0f10", span.TraceID().String())
+	assert.Equal(t, "0102030405060708", span.SpanID().String())
+}
+
+func TestServer_AllowedNamespaces(t *testing.T) {
+	allowed, err := ParseAllowedNamespaces("client-a=ns1, ns2;admin=*")
+	assert.Nil(t, err)
+	assert.Equal(t, map[string][]string{"client-a": {"ns1", "ns2"}, "admin": {"*"}}, allowed)
+	_, err = ParseAllowedNamespaces("client-a")
+	assert.NotNil(t, err)
+
+	server := NewServer(&providerStub{}, Options{AllowedNamespaces: allowed})
+	sign := func(commonName string, namespace string) int {
+		r := httptest.NewRequest(http.MethodPost, "/v1/keys/key1/sign", bytes.NewBufferString(`{"data":"ZGF0YQ=="}`))
+		r.Header.Set(NamespaceHeader, namespace)
+		if commonName != "" {
+			r.TLS = &tls.ConnectionState{PeerCertificates: []*x509.Certificate{{Subject: pkix.Name{CommonName: commonName}}}}
+		}
+		w := httptest.NewRecorder()
+		server.ServeHTTP(w, r)
+		return w.Code
+	}
+	assert.Equal(t, http.StatusOK, sign("client-a", "ns2"))
+	assert.Equal(t, http.StatusForbidden, sign("client-a", "ns3"))
+	assert.Equal(t, http.StatusOK, sign("admin", "ns3"))
+	assert.Equal(t, http.StatusForbidden, sign("client-b", "ns1"))
+	assert.Equal(t, http.StatusUnauthorized, sign("", "ns1"))
+}
+
+func TestServer_NamespaceIsolation(t *testing.T) {
+	server := NewServer(&providerStub{}, Options{AllowedNamespaces: map[string][]string{"client-a": {"a"}, "client-b": {"b"}}})
+	request := func(commonName string, namespace string, method string, path string, body interface{}, response interface{}) int {
+		var payload bytes.Buffer
+		if body != nil {
+			assert.Nil(t, json.NewEncoder(&payload).Encode(body))
+		}
+		r := httptest.NewRequest(method, path, &payload)
+		r.Header.Set(NamespaceHeader, namespace)
+		r.TLS = &tls.ConnectionState{PeerCertificates: []*x509.Certificate{{Subject: pkix.Name{CommonName: commonName}}}}
+		w := httptest.NewRecorder()
+		server.ServeHTTP(w, r)
+		if response != nil {
+			assert.Nil(t, json.Unmarshal(w.Body.Bytes(), response))
+		}
+		return w.Code
+	}
+	assert.Equal(t, http.StatusCreated, request("client-b", "b", http.MethodPost, "/v1/keys", Key{KeyId: "secret", KeyType: types.Ecdsap256}, nil))
+
+	var key Key
+	assert.Equal(t, http.StatusOK, request("client-b", "b", http.MethodGet, "/v1/keys/secret", nil, &key))
+	assert.Equal(t, "secret", key.KeyId)
+	assert.Equal(t, http.StatusNotFound, request("client-a", "a", http.MethodGet, "/v1/keys/secret", nil, nil))
+	assert.Equal(t, http.StatusBadRequest, request("client-a", "a", http.MethodGet, "/v1/keys/..%2Fb%2Fsecret", nil, nil))
+	assert.Equal(t, http.StatusForbidden, request("client-a", "b", http.MethodGet, "/v1/keys/secret", nil, nil))
+
+	var keys keysResponse
+	assert.Equal(t, http.StatusOK, request("client-a", "a", http.MethodGet, "/v1/keys", nil, &keys))
+	assert.Empty(t, keys.Keys)
+	assert.Equal(t, http.StatusOK, request("client-b", "b", http.MethodGet, "/v1/keys", nil, &keys))
+	assert.Len(t, keys.Keys, 1)
+}